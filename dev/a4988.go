@@ -11,55 +11,194 @@ Connect to Pi:
  - ms1 : any data pin
  - ms2 : any data pin
  - ms3 : any data pin
+ - en  : any data pin, optional
+ - slp : any data pin, optional
+ - rst : any data pin, optional
 
  NOTE:
- - This driver didn't implement reset, sleep and enable/disable.
- - You should typing [sleep] to [reset] pin to get the module always doesn't sleep.
+ - Tie [sleep] to [reset] if you don't connect them to the pi, to get the module always doesn't sleep.
  - And get [enable] tying nothing to get module always be enabled.
+ - Leave the ms pins nil if they are tied to fixed levels on the board.
+
+The same step/dir interface is used by many boards, they share this implementation with driver profiles:
+ - A4988Profile: full ~ 1/16 stepping
+ - DRV8825Profile: full ~ 1/32 stepping, pins mode0, mode1 and mode2 in place of ms1, ms2 and ms3
+ - TMC2208Profile: 1/2 ~ 1/16 stepping in standalone mode, pins ms1 and ms2, ms3 isn't used
+
+Usage:
+
+	drv := dev.NewA4988WithConfig(step, dir, m0, m1, m2, dev.A4988Config{
+		Profile:     dev.DRV8825Profile,
+		Mode:        dev.ThirtySecondMode,
+		StepsPerRev: 400, // a 0.9 degree motor
+		Enable:      dev.NewRpioPin(25),
+		Sleep:       dev.NewRpioPin(24),
+	})
+	drv.Roll(90)
+	drv.Sleep()
 */
 package dev
 
 import (
 	"errors"
+	"time"
+)
 
-	"github.com/stianeikeland/go-rpio/v4"
+const (
+	defaultNemaStepsPerRev = 200 // 1.8 degree per step
+	defaultStepDelay       = 500 * time.Microsecond
 )
 
-// the degree per step for nema stepper
-var degreePerStepForNema = map[StepperMode]float64{
-	FullMode:      1.8,
-	HalfMode:      0.9,
-	QuarterMode:   0.45,
-	EighthMode:    0.225,
-	SixteenthMode: 0.1125,
+// StepperDriverProfile describes a step/dir stepper driver board
+type StepperDriverProfile struct {
+	Name string
+	// Modes are the levels of ms1, ms2 and ms3 for the stepping modes
+	Modes map[StepperMode][3]LogicLevel
+	// WakeDelay is the time for the charge pump to stabilize after waking up or enabling
+	WakeDelay time.Duration
 }
 
-// A4988 ...
+// Driver profiles
+var (
+	A4988Profile = StepperDriverProfile{
+		Name: "A4988",
+		Modes: map[StepperMode][3]LogicLevel{
+			FullMode:      {Low, Low, Low},
+			HalfMode:      {High, Low, Low},
+			QuarterMode:   {Low, High, Low},
+			EighthMode:    {High, High, Low},
+			SixteenthMode: {High, High, High},
+		},
+		WakeDelay: time.Millisecond,
+	}
+	DRV8825Profile = StepperDriverProfile{
+		Name: "DRV8825",
+		Modes: map[StepperMode][3]LogicLevel{
+			FullMode:         {Low, Low, Low},
+			HalfMode:         {High, Low, Low},
+			QuarterMode:      {Low, High, Low},
+			EighthMode:       {High, High, Low},
+			SixteenthMode:    {Low, Low, High},
+			ThirtySecondMode: {High, Low, High},
+		},
+		WakeDelay: 1700 * time.Microsecond,
+	}
+	TMC2208Profile = StepperDriverProfile{
+		Name: "TMC2208",
+		Modes: map[StepperMode][3]LogicLevel{
+			HalfMode:      {High, Low, Low},
+			QuarterMode:   {Low, High, Low},
+			EighthMode:    {Low, Low, Low},
+			SixteenthMode: {High, High, Low},
+		},
+		WakeDelay: time.Millisecond,
+	}
+)
+
+// finest returns the stepping mode with the most microsteps
+func (p StepperDriverProfile) finest() StepperMode {
+	mode := FullMode
+	for m := range p.Modes {
+		if microsteps[m] > microsteps[mode] {
+			mode = m
+		}
+	}
+	return mode
+}
+
+// microsteps per full step
+var microsteps = map[StepperMode]int{
+	FullMode:         1,
+	HalfMode:         2,
+	QuarterMode:      4,
+	EighthMode:       8,
+	SixteenthMode:    16,
+	ThirtySecondMode: 32,
+}
+
+// A4988Config ...
+type A4988Config struct {
+	// Profile is the driver board, default A4988Profile
+	Profile StepperDriverProfile
+	// Mode is the stepping mode, default FullMode
+	Mode StepperMode
+	// StepsPerRev is the full steps per revolution of the motor, default 200 for 1.8 degree motors
+	StepsPerRev int
+	// StepDelay is the high and low time of a step pulse, default 500us
+	StepDelay time.Duration
+	// Enable, Sleep and Reset are optional pins, nil if not connected.
+	// Enable is active low, and so are Sleep and Reset.
+	Enable Pin
+	Sleep  Pin
+	Reset  Pin
+}
+
+// A4988 implements StepperMotor interface
 type A4988 struct {
-	step          rpio.Pin
-	dir           rpio.Pin
-	ms1, ms2, ms3 rpio.Pin
+	step          Pin
+	dir           Pin
+	ms1, ms2, ms3 Pin
+	en, slp, rst  Pin
+	profile       StepperDriverProfile
+	stepsPerRev   int
+	delay         time.Duration
 	mode          StepperMode
 }
 
 // NewA4988 ...
 func NewA4988(step, dir, ms1, ms2, ms3 uint8) *A4988 {
+	return NewA4988WithPins(newPin(step), newPin(dir), newPin(ms1), newPin(ms2), newPin(ms3))
+}
+
+// NewA4988WithPins ...
+func NewA4988WithPins(step, dir, ms1, ms2, ms3 Pin) *A4988 {
+	return NewA4988WithConfig(step, dir, ms1, ms2, ms3, A4988Config{Mode: HalfMode})
+}
+
+// NewA4988WithConfig ...
+func NewA4988WithConfig(step, dir, ms1, ms2, ms3 Pin, cfg A4988Config) *A4988 {
+	if cfg.Profile.Modes == nil {
+		cfg.Profile = A4988Profile
+	}
+	if cfg.StepsPerRev <= 0 {
+		cfg.StepsPerRev = defaultNemaStepsPerRev
+	}
+	if cfg.StepDelay <= 0 {
+		cfg.StepDelay = defaultStepDelay
+	}
 	a := &A4988{
-		step: rpio.Pin(step),
-		dir:  rpio.Pin(dir),
-		ms1:  rpio.Pin(ms1),
-		ms2:  rpio.Pin(ms2),
-		ms3:  rpio.Pin(ms3),
-		mode: HalfMode,
-	}
-	a.step.Output()
-	a.dir.Output()
-	a.ms1.Output()
-	a.ms2.Output()
-	a.ms3.Output()
+		step:        step,
+		dir:         dir,
+		ms1:         ms1,
+		ms2:         ms2,
+		ms3:         ms3,
+		en:          cfg.Enable,
+		slp:         cfg.Sleep,
+		rst:         cfg.Reset,
+		profile:     cfg.Profile,
+		stepsPerRev: cfg.StepsPerRev,
+		delay:       cfg.StepDelay,
+	}
+	for _, p := range []Pin{a.step, a.dir, a.ms1, a.ms2, a.ms3, a.en, a.slp, a.rst} {
+		if p != nil {
+			p.Output()
+		}
+	}
 	a.step.Low()
 	a.dir.Low()
-	_ = a.SetMode(HalfMode)
+	if a.en != nil {
+		a.en.Low()
+	}
+	if a.slp != nil {
+		a.slp.High()
+	}
+	if a.rst != nil {
+		a.rst.High()
+	}
+	if err := a.SetMode(cfg.Mode); err != nil {
+		// the profile doesn't support the mode, e.g. FullMode of TMC2208
+		_ = a.SetMode(cfg.Profile.finest())
+	}
 	return a
 }
 
@@ -79,9 +218,9 @@ func (a *A4988) Step(n int) {
 	}
 	for i := 0; i < n; i++ {
 		a.step.High()
-		delayUs(500)
+		time.Sleep(a.delay)
 		a.step.Low()
-		delayUs(500)
+		time.Sleep(a.delay)
 	}
 }
 
@@ -90,40 +229,79 @@ func (a *A4988) Step(n int) {
 // or roll in counter-clockwise direction if angle < 0,
 // or motionless if angle = 0.
 func (a *A4988) Roll(angle float64) {
-	degree, ok := degreePerStepForNema[a.mode]
-	if !ok {
-		return
-	}
-	n := int(angle / degree)
+	n := int(angle / a.StepAngle())
 	a.Step(n)
 }
 
-// SetMode sets the stepping mode
+// SetMode sets the stepping mode, the modes supported depend on the profile
 func (a *A4988) SetMode(mode StepperMode) error {
-	switch mode {
-	case FullMode:
-		a.ms1.Low()
-		a.ms2.Low()
-		a.ms3.Low()
-	case HalfMode:
-		a.ms1.High()
-		a.ms2.Low()
-		a.ms3.Low()
-	case QuarterMode:
-		a.ms1.Low()
-		a.ms2.High()
-		a.ms3.Low()
-	case EighthMode:
-		a.ms1.High()
-		a.ms2.High()
-		a.ms3.Low()
-	case SixteenthMode:
-		a.ms1.High()
-		a.ms2.High()
-		a.ms3.High()
-	default:
+	levels, ok := a.profile.Modes[mode]
+	if !ok {
 		return errors.New("invalid mode")
 	}
+	for i, p := range []Pin{a.ms1, a.ms2, a.ms3} {
+		if p != nil {
+			p.Write(levels[i])
+		}
+	}
 	a.mode = mode
 	return nil
 }
+
+// SetStepDelay sets the high and low time of step pulses
+func (a *A4988) SetStepDelay(d time.Duration) {
+	a.delay = d
+}
+
+// StepsPerRev returns the (micro)steps per revolution in the current mode
+func (a *A4988) StepsPerRev() int {
+	return a.stepsPerRev * microsteps[a.mode]
+}
+
+// StepAngle returns the degree per (micro)step in the current mode
+func (a *A4988) StepAngle() float64 {
+	return 360 / float64(a.StepsPerRev())
+}
+
+// Enable enables the outputs, it is a no-op without the enable pin
+func (a *A4988) Enable() {
+	if a.en == nil {
+		return
+	}
+	a.en.Low()
+	time.Sleep(a.profile.WakeDelay)
+}
+
+// Disable disables the outputs, the motor spins freely.
+// It is a no-op without the enable pin.
+func (a *A4988) Disable() {
+	if a.en != nil {
+		a.en.High()
+	}
+}
+
+// Sleep puts the driver into the low power mode, it is a no-op without the sleep pin
+func (a *A4988) Sleep() {
+	if a.slp != nil {
+		a.slp.Low()
+	}
+}
+
+// Wake wakes the driver up and waits for it to be ready, it is a no-op without the sleep pin
+func (a *A4988) Wake() {
+	if a.slp == nil {
+		return
+	}
+	a.slp.High()
+	time.Sleep(a.profile.WakeDelay)
+}
+
+// Reset resets the translator to the home state, it is a no-op without the reset pin
+func (a *A4988) Reset() {
+	if a.rst == nil {
+		return
+	}
+	a.rst.Low()
+	time.Sleep(a.delay)
+	a.rst.High()
+}
@@ -0,0 +1,114 @@
+package dev
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type a4988Pins struct {
+	step, dir, ms1, ms2, ms3, en, slp, rst *FakePin
+}
+
+func newTestA4988(cfg A4988Config) (*A4988, a4988Pins) {
+	pins := a4988Pins{
+		step: NewFakePin(),
+		dir:  NewFakePin(),
+		ms1:  NewFakePin(),
+		ms2:  NewFakePin(),
+		ms3:  NewFakePin(),
+		en:   NewFakePin(),
+		slp:  NewFakePin(),
+		rst:  NewFakePin(),
+	}
+	cfg.Enable, cfg.Sleep, cfg.Reset = pins.en, pins.slp, pins.rst
+	a := NewA4988WithConfig(pins.step, pins.dir, pins.ms1, pins.ms2, pins.ms3, cfg)
+	a.SetStepDelay(0)
+	return a, pins
+}
+
+func msLevels(pins a4988Pins) [3]LogicLevel {
+	return [3]LogicLevel{pins.ms1.Level(), pins.ms2.Level(), pins.ms3.Level()}
+}
+
+func Test_A4988Profiles(t *testing.T) {
+	testCases := []struct {
+		profile StepperDriverProfile
+		mode    StepperMode
+		levels  [3]LogicLevel
+		steps   int
+	}{
+		{A4988Profile, FullMode, [3]LogicLevel{Low, Low, Low}, 200},
+		{A4988Profile, HalfMode, [3]LogicLevel{High, Low, Low}, 400},
+		{A4988Profile, SixteenthMode, [3]LogicLevel{High, High, High}, 3200},
+		{DRV8825Profile, SixteenthMode, [3]LogicLevel{Low, Low, High}, 3200},
+		{DRV8825Profile, ThirtySecondMode, [3]LogicLevel{High, Low, High}, 6400},
+		{TMC2208Profile, HalfMode, [3]LogicLevel{High, Low, Low}, 400},
+		{TMC2208Profile, QuarterMode, [3]LogicLevel{Low, High, Low}, 800},
+		{TMC2208Profile, EighthMode, [3]LogicLevel{Low, Low, Low}, 1600},
+		{TMC2208Profile, SixteenthMode, [3]LogicLevel{High, High, Low}, 3200},
+	}
+	for _, tc := range testCases {
+		a, pins := newTestA4988(A4988Config{Profile: tc.profile, Mode: tc.mode})
+		assert.Equal(t, tc.levels, msLevels(pins), "%v %v", tc.profile.Name, tc.mode)
+		assert.Equal(t, tc.steps, a.StepsPerRev(), "%v %v", tc.profile.Name, tc.mode)
+	}
+
+	a, _ := newTestA4988(A4988Config{})
+	assert.Error(t, a.SetMode(ThirtySecondMode))
+	assert.Error(t, a.SetMode(WaveMode))
+	assert.Equal(t, 200, a.StepsPerRev())
+
+	// TMC2208 doesn't support full-step, the finest mode is used
+	a, pins := newTestA4988(A4988Config{Profile: TMC2208Profile})
+	assert.Equal(t, SixteenthMode, a.mode)
+	assert.Equal(t, [3]LogicLevel{High, High, Low}, msLevels(pins))
+}
+
+func Test_A4988(t *testing.T) {
+	a, pins := newTestA4988(A4988Config{Mode: QuarterMode, StepsPerRev: 400})
+	assert.Equal(t, 1600, a.StepsPerRev())
+	assert.Equal(t, 0.225, a.StepAngle())
+	assert.Equal(t, Low, pins.en.Level())
+	assert.Equal(t, High, pins.slp.Level())
+	assert.Equal(t, High, pins.rst.Level())
+
+	a.Step(3)
+	assert.Equal(t, High, pins.dir.Level())
+	assert.Equal(t, []LogicLevel{Low, High, Low, High, Low, High, Low}, pins.step.Writes())
+
+	a.Roll(-0.5)
+	assert.Equal(t, Low, pins.dir.Level())
+	assert.Len(t, pins.step.Writes(), 7+4)
+
+	a.Disable()
+	assert.Equal(t, High, pins.en.Level())
+	a.Enable()
+	assert.Equal(t, Low, pins.en.Level())
+	a.Sleep()
+	assert.Equal(t, Low, pins.slp.Level())
+	a.Wake()
+	assert.Equal(t, High, pins.slp.Level())
+	a.Reset()
+	assert.Equal(t, []LogicLevel{High, Low, High}, pins.rst.Writes())
+
+	// the optional pins can be left unconnected
+	step := NewFakePin()
+	a = NewA4988WithConfig(step, NewFakePin(), nil, nil, nil, A4988Config{
+		Mode:      EighthMode,
+		StepDelay: time.Microsecond,
+	})
+	a.Enable()
+	a.Disable()
+	a.Sleep()
+	a.Wake()
+	a.Reset()
+	a.Step(1)
+	assert.Equal(t, 1600, a.StepsPerRev())
+	assert.Equal(t, []LogicLevel{Low, High, Low}, step.Writes())
+
+	// the legacy constructor uses half-step on 1.8 degree motors
+	a = NewA4988WithPins(NewFakePin(), NewFakePin(), NewFakePin(), NewFakePin(), NewFakePin())
+	assert.Equal(t, 0.9, a.StepAngle())
+}
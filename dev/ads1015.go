@@ -35,12 +35,9 @@ package dev
 
 import (
 	"errors"
-
-	"golang.org/x/exp/io/i2c"
 )
 
 const (
-	ads1015Dev  = "/dev/i2c-1"
 	ads1015Addr = 0x48
 )
 
@@ -149,20 +146,26 @@ var (
 
 // ADS1015 is a 12-bit analog-digital converter. It implements ADC interface
 type ADS1015 struct {
-	dev    *i2c.Device
+	dev    I2CDevice
 	config uint16
 }
 
 // NewADS1015 create a driver for ADS1015 module
 func NewADS1015() (*ADS1015, error) {
-	dev, err := i2c.Open(&i2c.Devfs{Dev: ads1015Dev}, ads1015Addr)
+	dev, err := OpenI2C(DefaultI2CBus, ads1015Addr)
 	if err != nil {
 		return nil, err
 	}
+	return NewADS1015WithI2C(dev), nil
+}
+
+// NewADS1015WithI2C create a driver for ADS1015 module using the i2c device.
+// Use it for the module on other buses or addresses, e.g. OpenI2C("/dev/i2c-1", 0x49) if ADDR is tied to VDD.
+func NewADS1015WithI2C(dev I2CDevice) *ADS1015 {
 	return &ADS1015{
 		dev:    dev,
 		config: defaultConfig,
-	}, nil
+	}
 }
 
 // SetConfig ...
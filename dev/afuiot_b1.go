@@ -24,8 +24,6 @@ package dev
 
 import (
 	"fmt"
-
-	"github.com/tarm/serial"
 )
 
 var (
@@ -39,21 +37,25 @@ var (
 
 // AfuiotB1 implements Thermometer interface
 type AfuiotB1 struct {
-	port *serial.Port
+	port SerialPort
 	buf  [8]byte
 }
 
 // NewAfuiotB1 ...
 func NewAfuiotB1(dev string, baud int) (*AfuiotB1, error) {
-	cfg := &serial.Config{
-		Name: dev,
+	port, err := OpenSerial(SerialConfig{
+		Dev:  dev,
 		Baud: baud,
-	}
-	port, err := serial.OpenPort(cfg)
+	})
 	if err != nil {
 		return nil, err
 	}
-	return &AfuiotB1{port: port}, nil
+	return NewAfuiotB1WithPort(port), nil
+}
+
+// NewAfuiotB1WithPort ...
+func NewAfuiotB1WithPort(port SerialPort) *AfuiotB1 {
+	return &AfuiotB1{port: port}
 }
 
 // Temperature ...
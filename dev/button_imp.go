@@ -10,28 +10,76 @@ Connect to Raspberry Pi for a 2-pin buttom mobule:
  - port-1: any 3.3v pin
  - port-2: any data pin
 
+Or connect a 2-pin button to gnd, and use ButtonConfig{ActiveLow: true, Pull: PullUpMode}:
+ - port-1: any gnd pin
+ - port-2: any data pin
+
 */
 package dev
 
 import (
-	"github.com/stianeikeland/go-rpio/v4"
+	"context"
+	"time"
 )
 
+// ButtonConfig is the config of a button
+type ButtonConfig struct {
+	// ActiveLow is true if the level of the pin is low when the button is pressed
+	ActiveLow bool
+	// Pull is the internal pull resistor enabled on the pin
+	Pull Pull
+	// Debounce is the time the level must be stable before Watch() delivers an event,
+	// no debouncing if it is 0.
+	Debounce time.Duration
+}
+
 // ButtonImp implements Button interface
 type ButtonImp struct {
-	pin rpio.Pin
+	pin      Pin
+	active   LogicLevel
+	debounce time.Duration
 }
 
 // NewButtonImp ...
 func NewButtonImp(pin uint8) *ButtonImp {
+	return NewButtonImpWithPin(newPin(pin))
+}
+
+// NewButtonImpWithPin creates an active-high button without pull resistor and debouncing
+func NewButtonImpWithPin(pin Pin) *ButtonImp {
+	return NewButtonImpWithConfig(pin, ButtonConfig{})
+}
+
+// NewButtonImpWithConfig ...
+func NewButtonImpWithConfig(pin Pin, cfg ButtonConfig) *ButtonImp {
 	b := &ButtonImp{
-		pin: rpio.Pin(pin),
+		pin:      pin,
+		active:   High,
+		debounce: cfg.Debounce,
+	}
+	if cfg.ActiveLow {
+		b.active = Low
 	}
 	b.pin.Input()
+	switch cfg.Pull {
+	case PullUpMode:
+		b.pin.PullUp()
+	case PullDownMode:
+		b.pin.PullDown()
+	}
 	return b
 }
 
-// Pressed ...
+// Pressed returns the instantaneous state of the button without debouncing
 func (b *ButtonImp) Pressed() bool {
-	return b.pin.Read() == rpio.High
+	return b.pin.Read() == b.active
+}
+
+// Watch delivers debounced events when the button is pressed or released until ctx is done
+func (b *ButtonImp) Watch(ctx context.Context) <-chan InputEvent {
+	events := watchInput(ctx, b.pin, b.active)
+	if b.debounce <= 0 {
+		return events
+	}
+	return Debounce(ctx, events, b.debounce)
 }
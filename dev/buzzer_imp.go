@@ -15,20 +15,23 @@ package dev
 
 import (
 	"time"
-
-	"github.com/stianeikeland/go-rpio/v4"
 )
 
 // BuzzerImp implements Buzzer interface
 type BuzzerImp struct {
-	pin    rpio.Pin
+	pin    Pin
 	trigBy LogicLevel
 }
 
 // NewBuzzerImp ...
 func NewBuzzerImp(pin uint8, trigBy LogicLevel) *BuzzerImp {
+	return NewBuzzerImpWithPin(newPin(pin), trigBy)
+}
+
+// NewBuzzerImpWithPin ...
+func NewBuzzerImpWithPin(pin Pin, trigBy LogicLevel) *BuzzerImp {
 	b := &BuzzerImp{
-		pin:    rpio.Pin(pin),
+		pin:    pin,
 		trigBy: trigBy,
 	}
 	b.pin.Output()
@@ -9,79 +9,120 @@ Connect to Pi:
  - in3: any data pin
  - in4: any data pin
 
+The 28BYJ-48 has a gear ratio of about 1:64, it takes 4096 half steps or 2048 full steps per revolution.
+Please NOTE that Step(n) counts single steps in the current mode. It used to count 4-phase cycles,
+so Step(512) rolls a quarter revolution in FullMode now, use Step(2048) or Roll(360) for a whole one.
+
+Modes:
+ - WaveMode: one coil at a time, 2048 steps per rev
+ - FullMode: two coils at a time, 2048 steps per rev, the most torque (default)
+ - HalfMode: one and two coils alternately, 4096 steps per rev, the smoothest
+
+Usage:
+
+	byj := dev.NewBYJ2848WithConfig(in1, in2, in3, in4, dev.BYJ2848Config{
+		Mode:  dev.HalfMode,
+		Speed: 10,
+		Hold:  true,
+	})
+	byj.Roll(90)
+	byj.Release()
 */
 package dev
 
 import (
-	"github.com/stianeikeland/go-rpio/v4"
+	"errors"
+	"time"
 )
 
 const (
-	degreePerStepForBYJ2848 = float64(0.703125) // 360/512
+	byj2848HalfStepsPerRev = 4096
+	defaultBYJ2848Speed    = 12 // rpm
 )
 
-var (
-	clockwise = [4][4]uint8{
-		{1, 0, 0, 0},
-		{0, 1, 0, 0},
-		{0, 0, 1, 0},
-		{0, 0, 0, 1},
-	}
+// byj2848Sequence is the half-step sequence of the coils,
+// wave drive uses the even phases, and full-step uses the odd phases.
+var byj2848Sequence = [8][4]LogicLevel{
+	{High, Low, Low, Low},
+	{High, High, Low, Low},
+	{Low, High, Low, Low},
+	{Low, High, High, Low},
+	{Low, Low, High, Low},
+	{Low, Low, High, High},
+	{Low, Low, Low, High},
+	{High, Low, Low, High},
+}
 
-	cclockwise = [4][4]uint8{
-		{0, 0, 0, 1},
-		{0, 0, 1, 0},
-		{0, 1, 0, 0},
-		{1, 0, 0, 0},
-	}
-)
+// BYJ2848Config ...
+type BYJ2848Config struct {
+	// Mode is one of WaveMode, FullMode and HalfMode, default FullMode
+	Mode StepperMode
+	// Speed in rpm, default 12. The motor stalls above 15 rpm typically.
+	Speed float64
+	// Hold keeps the coils energized after stepping for holding torque,
+	// otherwise the coils are released to save power and keep the motor cool.
+	Hold bool
+}
 
 // BYJ2848 implements StepperMotor interface
 type BYJ2848 struct {
-	pins [4]rpio.Pin
+	pins  [4]Pin
+	mode  StepperMode
+	delay time.Duration
+	hold  bool
+	// phase is the index in byj2848Sequence
+	phase int
 }
 
 // NewBYJ2848 ...
 func NewBYJ2848(in1, in2, in3, in4 uint8) *BYJ2848 {
+	return NewBYJ2848WithPins(newPin(in1), newPin(in2), newPin(in3), newPin(in4))
+}
+
+// NewBYJ2848WithPins ...
+func NewBYJ2848WithPins(in1, in2, in3, in4 Pin) *BYJ2848 {
+	return NewBYJ2848WithConfig(in1, in2, in3, in4, BYJ2848Config{})
+}
+
+// NewBYJ2848WithConfig ...
+func NewBYJ2848WithConfig(in1, in2, in3, in4 Pin, cfg BYJ2848Config) *BYJ2848 {
 	byj := &BYJ2848{
-		pins: [4]rpio.Pin{
-			rpio.Pin(in1),
-			rpio.Pin(in2),
-			rpio.Pin(in3),
-			rpio.Pin(in4),
-		},
+		pins: [4]Pin{in1, in2, in3, in4},
+		mode: FullMode,
+		hold: cfg.Hold,
 	}
 	for i := 0; i < 4; i++ {
 		byj.pins[i].Output()
 		byj.pins[i].Low()
 	}
+	_ = byj.SetMode(cfg.Mode)
+	if cfg.Speed <= 0 {
+		cfg.Speed = defaultBYJ2848Speed
+	}
+	byj.SetSpeed(cfg.Speed)
 	return byj
 }
 
-// Step gets the motor rolls n steps.
+// Step gets the motor rolls n steps, a step is one phase of the sequence, not a whole 4-phase cycle,
+// so a revolution is 2048 steps in WaveMode and FullMode, and 4096 steps in HalfMode.
 // roll in clockwise direction if n > 0,
 // or roll in counter-clockwise direction if n < 0,
 // or motionless if n = 0.
 func (byj *BYJ2848) Step(n int) {
-	matrix := clockwise
+	dir := 1
 	if n < 0 {
-		matrix = cclockwise
+		dir = -1
 		n = 0 - n
 	}
 
 	for i := 0; i < n; i++ {
-		for j := 0; j < 4; j++ {
-			for k := 0; k < 4; k++ {
-				if matrix[j][k] == 1 {
-					byj.pins[k].High()
-				} else {
-					byj.pins[k].Low()
-				}
-			}
-			delayMs(2)
-		}
+		byj.phase = byj.nextPhase(dir)
+		byj.energize(byj.phase)
+		time.Sleep(byj.delay)
+	}
+	if !byj.hold {
+		byj.Release()
 	}
-	byj.reset()
 }
 
 // Roll gets the motor rolls angle degree.
@@ -89,18 +130,75 @@ func (byj *BYJ2848) Step(n int) {
 // or roll in counter-clockwise direction if angle < 0,
 // or motionless if angle = 0.
 func (byj *BYJ2848) Roll(angle float64) {
-	n := int(angle / degreePerStepForBYJ2848)
+	n := int(angle / byj.StepAngle())
 	byj.Step(n)
 }
 
-// SetMode sets the stepping mode.
-// Please NOTE only FullMode is supported currently, and FullMode is used by default.
+// SetMode sets the stepping mode, WaveMode, FullMode and HalfMode are supported.
+// The speed in rpm is kept.
 func (byj *BYJ2848) SetMode(mode StepperMode) error {
+	if mode != WaveMode && mode != FullMode && mode != HalfMode {
+		return errors.New("invalid mode")
+	}
+	old := byj.StepsPerRev()
+	byj.mode = mode
+	byj.delay = byj.delay * time.Duration(old) / time.Duration(byj.StepsPerRev())
 	return nil
 }
 
-func (byj *BYJ2848) reset() {
+// SetSpeed sets the speed in rpm
+func (byj *BYJ2848) SetSpeed(rpm float64) {
+	if rpm <= 0 {
+		return
+	}
+	byj.delay = time.Duration(float64(time.Minute) / (rpm * float64(byj.StepsPerRev())))
+}
+
+// SetStepDelay sets the delay between steps, it sets the speed too
+func (byj *BYJ2848) SetStepDelay(d time.Duration) {
+	byj.delay = d
+}
+
+// SetHold keeps the coils energized after stepping if hold is true
+func (byj *BYJ2848) SetHold(hold bool) {
+	byj.hold = hold
+}
+
+// StepsPerRev returns the steps per revolution in the current mode
+func (byj *BYJ2848) StepsPerRev() int {
+	if byj.mode == HalfMode {
+		return byj2848HalfStepsPerRev
+	}
+	return byj2848HalfStepsPerRev / 2
+}
+
+// StepAngle returns the degree per step in the current mode
+func (byj *BYJ2848) StepAngle() float64 {
+	return 360 / float64(byj.StepsPerRev())
+}
+
+// Release de-energizes all coils
+func (byj *BYJ2848) Release() {
 	for i := 0; i < 4; i++ {
 		byj.pins[i].Low()
 	}
 }
+
+// nextPhase returns the next phase in the direction
+func (byj *BYJ2848) nextPhase(dir int) int {
+	p := byj.phase + dir
+	if byj.mode != HalfMode {
+		// even phases for wave drive, and odd phases for full-step
+		odd := byj.mode == FullMode
+		if (p%2 != 0) != odd {
+			p += dir
+		}
+	}
+	return (p + 8) % 8
+}
+
+func (byj *BYJ2848) energize(phase int) {
+	for k, level := range byj2848Sequence[phase] {
+		byj.pins[k].Write(level)
+	}
+}
@@ -0,0 +1,118 @@
+package dev
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestBYJ2848(cfg BYJ2848Config) (*BYJ2848, [4]*FakePin) {
+	var pins [4]*FakePin
+	for i := range pins {
+		pins[i] = NewFakePin()
+	}
+	byj := NewBYJ2848WithConfig(pins[0], pins[1], pins[2], pins[3], cfg)
+	byj.SetStepDelay(0)
+	return byj, pins
+}
+
+func coils(pins [4]*FakePin) string {
+	s := ""
+	for _, p := range pins {
+		if p.Level() == High {
+			s += "1"
+		} else {
+			s += "0"
+		}
+	}
+	return s
+}
+
+func Test_BYJ2848Sequences(t *testing.T) {
+	testCases := []struct {
+		mode StepperMode
+		cw   []string
+		ccw  []string
+	}{
+		{
+			mode: WaveMode,
+			cw:   []string{"0100", "0010", "0001", "1000", "0100"},
+			ccw:  []string{"0001", "0010", "0100", "1000", "0001"},
+		},
+		{
+			mode: FullMode,
+			cw:   []string{"1100", "0110", "0011", "1001", "1100"},
+			ccw:  []string{"1001", "0011", "0110", "1100", "1001"},
+		},
+		{
+			mode: HalfMode,
+			cw:   []string{"1100", "0100", "0110", "0010", "0011", "0001", "1001", "1000", "1100"},
+			ccw:  []string{"1001", "0001", "0011", "0010", "0110", "0100", "1100", "1000", "1001"},
+		},
+	}
+	for _, tc := range testCases {
+		byj, pins := newTestBYJ2848(BYJ2848Config{Mode: tc.mode, Hold: true})
+		var seq []string
+		for range tc.cw {
+			byj.Step(1)
+			seq = append(seq, coils(pins))
+		}
+		assert.Equal(t, tc.cw, seq)
+
+		byj, pins = newTestBYJ2848(BYJ2848Config{Mode: tc.mode, Hold: true})
+		seq = nil
+		for range tc.ccw {
+			byj.Step(-1)
+			seq = append(seq, coils(pins))
+		}
+		assert.Equal(t, tc.ccw, seq)
+	}
+}
+
+func Test_BYJ2848(t *testing.T) {
+	byj, pins := newTestBYJ2848(BYJ2848Config{})
+	assert.Equal(t, 2048, byj.StepsPerRev())
+	assert.Equal(t, 0.17578125, byj.StepAngle())
+
+	// the coils are released after stepping without holding
+	byj.Step(3)
+	assert.Equal(t, "0000", coils(pins))
+	assert.Equal(t, []LogicLevel{Low, Low, High, High, Low}, pins[2].Writes())
+
+	byj.SetHold(true)
+	byj.Step(-1)
+	assert.Equal(t, "0110", coils(pins))
+	byj.Release()
+	assert.Equal(t, "0000", coils(pins))
+
+	// switching from full-step to half-step moves half a step
+	assert.NoError(t, byj.SetMode(HalfMode))
+	assert.Equal(t, 4096, byj.StepsPerRev())
+	assert.Equal(t, 0.087890625, byj.StepAngle())
+	byj.Step(1)
+	assert.Equal(t, "0010", coils(pins))
+
+	assert.Error(t, byj.SetMode(QuarterMode))
+	assert.Equal(t, 4096, byj.StepsPerRev())
+
+	// a full revolution goes back to the same phase
+	byj.Roll(360)
+	assert.Equal(t, "0010", coils(pins))
+	byj.Roll(-90)
+	assert.Equal(t, "0010", coils(pins))
+}
+
+func Test_BYJ2848Speed(t *testing.T) {
+	byj, _ := newTestBYJ2848(BYJ2848Config{Mode: HalfMode})
+	byj.SetSpeed(15)
+	assert.InDelta(t, float64(time.Minute)/(15*4096), float64(byj.delay), 1)
+	// the speed is kept when switching modes
+	assert.NoError(t, byj.SetMode(FullMode))
+	assert.InDelta(t, float64(time.Minute)/(15*2048), float64(byj.delay), 1)
+
+	pins := [4]*FakePin{NewFakePin(), NewFakePin(), NewFakePin(), NewFakePin()}
+	byj = NewBYJ2848WithPins(pins[0], pins[1], pins[2], pins[3])
+	assert.Equal(t, 2048, byj.StepsPerRev())
+	assert.InDelta(t, float64(time.Minute)/(12*2048), float64(byj.delay), 1)
+}
@@ -10,18 +10,23 @@ Connect to Raspberry Pi:
 package dev
 
 import (
-	"github.com/stianeikeland/go-rpio/v4"
+	"context"
 )
 
 // CollisionSwitch implements Detector interface
 type CollisionSwitch struct {
-	pin rpio.Pin
+	pin Pin
 }
 
 // NewCollisionSwitch ...
 func NewCollisionSwitch(pin uint8) *CollisionSwitch {
+	return NewCollisionSwitchWithPin(newPin(pin))
+}
+
+// NewCollisionSwitchWithPin ...
+func NewCollisionSwitchWithPin(pin Pin) *CollisionSwitch {
 	c := &CollisionSwitch{
-		pin: rpio.Pin(pin),
+		pin: pin,
 	}
 	c.pin.Input()
 	return c
@@ -29,5 +34,10 @@ func NewCollisionSwitch(pin uint8) *CollisionSwitch {
 
 // Deteched ...
 func (c *CollisionSwitch) Detected() bool {
-	return c.pin.Read() == rpio.Low
+	return c.pin.Read() == Low
+}
+
+// Watch delivers events when a collision happens or ends until ctx is done
+func (c *CollisionSwitch) Watch(ctx context.Context) <-chan InputEvent {
+	return watchInput(ctx, c.pin, Low)
 }
@@ -20,6 +20,9 @@ const (
 	QuarterMode
 	EighthMode
 	SixteenthMode
+	// WaveMode energizes one coil at a time, it uses less power than FullMode with less torque
+	WaveMode
+	ThirtySecondMode
 )
 
 const (
@@ -48,6 +51,7 @@ const (
 	rpi2       rpiModel = "Raspberry Pi 2 Model"
 	rpi3       rpiModel = "Raspberry Pi 3 Model"
 	rpi4       rpiModel = "Raspberry Pi 4 Model"
+	rpi5       rpiModel = "Raspberry Pi 5 Model"
 )
 
 func delayNs(d time.Duration) {
@@ -103,5 +107,8 @@ func getRpiModel() rpiModel {
 	if strings.Contains(s, string(rpi4)) {
 		return rpi4
 	}
+	if strings.Contains(s, string(rpi5)) {
+		return rpi5
+	}
 	return rpiUnknown
 }
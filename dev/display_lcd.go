@@ -34,12 +34,9 @@ package dev
 import (
 	"errors"
 	"image"
-
-	"golang.org/x/exp/io/i2c"
 )
 
 const (
-	lcdDev               = "/dev/i2c-1"
 	lcdAddr              = 0x27
 	lcdEnable       byte = 0x04
 	lcdBacklightOn  byte = 0x08
@@ -51,17 +48,23 @@ type LcdDisplay struct {
 	width  int
 	height int
 	blkOn  bool
-	dev    *i2c.Device
+	dev    I2CDevice
 }
 
 // NewLcdDisplay creates a driver for LCD display.
 // It is an implement of Display interface.
 // Please NOTE that I only test it on a 1602A lcd display module.
 func NewLcdDisplay(width, height int) (*LcdDisplay, error) {
-	dev, err := i2c.Open(&i2c.Devfs{Dev: lcdDev}, lcdAddr)
+	dev, err := OpenI2C(DefaultI2CBus, lcdAddr)
 	if err != nil {
 		return nil, err
 	}
+	return NewLcdDisplayWithI2C(dev, width, height), nil
+}
+
+// NewLcdDisplayWithI2C creates a driver for LCD display using the i2c device.
+// Use it for the module on other buses or addresses, e.g. OpenI2C("/dev/i2c-1", 0x3f).
+func NewLcdDisplayWithI2C(dev I2CDevice, width, height int) *LcdDisplay {
 	lcd := &LcdDisplay{
 		width:  width,
 		height: height,
@@ -85,7 +88,7 @@ func NewLcdDisplay(width, height int) (*LcdDisplay, error) {
 	lcd.sendCommand(0x01) // Clear display
 	delayMs(1)
 
-	return lcd, nil
+	return lcd
 }
 
 // Image displays an image on the screen.
@@ -22,8 +22,6 @@ package dev
 import (
 	"errors"
 	"image"
-
-	"github.com/stianeikeland/go-rpio/v4"
 )
 
 const (
@@ -88,12 +86,12 @@ var ledchars = map[byte]uint8{
 // TM1637Display is a dirvier for digital led display module drived by TM1637 chip.
 // It is an implement of Display interface.
 type TM1637Display struct {
-	dioPin  rpio.Pin
-	rclkPin rpio.Pin
-	sclkPin rpio.Pin
+	dioPin  Pin
+	rclkPin Pin
+	sclkPin Pin
 
 	// on    bool
-	state rpio.State
+	state LogicLevel
 	data  uint8
 
 	chText chan string
@@ -104,10 +102,15 @@ type TM1637Display struct {
 // NewTM1637Display creates a TM1637Display driver.
 // Please NOTE that I only test it on a 4-bit digital led module.
 func NewTM1637Display(dioPin, rclkPin, sclkPin uint8) *TM1637Display {
+	return NewTM1637DisplayWithPins(newPin(dioPin), newPin(rclkPin), newPin(sclkPin))
+}
+
+// NewTM1637DisplayWithPins ...
+func NewTM1637DisplayWithPins(dioPin, rclkPin, sclkPin Pin) *TM1637Display {
 	display := &TM1637Display{
-		dioPin:  rpio.Pin(dioPin),
-		rclkPin: rpio.Pin(rclkPin),
-		sclkPin: rpio.Pin(sclkPin),
+		dioPin:  dioPin,
+		rclkPin: rclkPin,
+		sclkPin: sclkPin,
 		chText:  make(chan string, 4),
 		chDone:  make(chan bool),
 		opened:  false,
@@ -187,7 +190,7 @@ func (display *TM1637Display) flushStcp() {
 }
 
 // setBit sets an individual bit
-func (display *TM1637Display) setBit(bit rpio.State) {
+func (display *TM1637Display) setBit(bit LogicLevel) {
 	display.dioPin.Write(bit)
 	display.flushShcp()
 }
@@ -196,7 +199,7 @@ func (display *TM1637Display) setBit(bit rpio.State) {
 func (display *TM1637Display) send(data uint8) {
 	display.data = data
 	for i := uint(0); i < 8; i++ {
-		display.setBit(rpio.State((display.data >> i) & 0x01))
+		display.setBit(LogicLevel((display.data >> i) & 0x01))
 	}
 	display.flushStcp()
 }
@@ -34,11 +34,9 @@ import (
 	"image"
 
 	"github.com/mdp/monochromeoled"
-	"golang.org/x/exp/io/i2c"
 )
 
 const (
-	oledDev  = "/dev/i2c-1"
 	oledAddr = 0x3c
 )
 
@@ -52,7 +50,17 @@ type SSD1306Display struct {
 
 // NewSSD1306Display creates a driver for the oled display module drived by SSD1306 chip
 func NewSSD1306Display(width, heigth int) (*SSD1306Display, error) {
-	oled, err := monochromeoled.Open(&i2c.Devfs{Dev: oledDev}, oledAddr, width, heigth)
+	dev, err := OpenI2C(DefaultI2CBus, oledAddr)
+	if err != nil {
+		return nil, err
+	}
+	return NewSSD1306DisplayWithI2C(dev, width, heigth)
+}
+
+// NewSSD1306DisplayWithI2C creates a driver for the oled display module using the i2c device.
+// Use it for the module on other buses or addresses, e.g. OpenI2C("/dev/i2c-3", 0x3d).
+func NewSSD1306DisplayWithI2C(dev I2CDevice, width, heigth int) (*SSD1306Display, error) {
+	oled, err := monochromeoled.Open(&i2cOpener{dev: dev}, oledAddr, width, heigth)
 	if err != nil {
 		return nil, err
 	}
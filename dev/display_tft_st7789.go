@@ -31,16 +31,24 @@ import (
 // ST7789Display is a driver for the tft lcd display module drived by ST7789 chip.
 // It is an implement of Display interface.
 type ST7789Display struct {
-	res    rpio.Pin
-	dc     rpio.Pin
-	blk    rpio.Pin
+	res    Pin
+	dc     Pin
+	blk    Pin
 	width  int
 	height int
+	spi    func(data ...byte)
 }
 
 // NewST7789Display create a driver for the tft lcd display module drived by ST7789 chip.
 // Note that you should disable SPI interface in raspi-config first!
 func NewST7789Display(res, dc, blk uint8, width, height int) (*ST7789Display, error) {
+	return NewST7789DisplayWithPins(newPin(res), newPin(dc), newPin(blk), width, height)
+}
+
+// NewST7789DisplayWithPins creates the driver with the given pins,
+// the data is still sent by SPI0 of rpio.
+func NewST7789DisplayWithPins(res, dc, blk Pin, width, height int) (*ST7789Display, error) {
+	openRpio()
 	if err := rpio.SpiBegin(rpio.Spi0); err != nil {
 		return nil, err
 	}
@@ -48,12 +56,18 @@ func NewST7789Display(res, dc, blk uint8, width, height int) (*ST7789Display, er
 	rpio.SpiMode(1, 0)
 	rpio.SpiSpeed(40000000)
 
-	display := &ST7789Display{}
-	display.res = rpio.Pin(res)
-	display.dc = rpio.Pin(dc)
-	display.blk = rpio.Pin(blk)
-	display.width = width
-	display.height = height
+	return newST7789Display(res, dc, blk, rpio.SpiTransmit, width, height), nil
+}
+
+func newST7789Display(res, dc, blk Pin, spi func(data ...byte), width, height int) *ST7789Display {
+	display := &ST7789Display{
+		res:    res,
+		dc:     dc,
+		blk:    blk,
+		width:  width,
+		height: height,
+		spi:    spi,
+	}
 
 	display.dc.Output()
 	display.res.Output()
@@ -63,7 +77,7 @@ func NewST7789Display(res, dc, blk uint8, width, height int) (*ST7789Display, er
 	display.reset()
 	display.init()
 
-	return display, nil
+	return display
 }
 
 // Image displays an image on the screen
@@ -227,12 +241,12 @@ func (display *ST7789Display) setwindow() {
 
 func (display *ST7789Display) command(data ...byte) {
 	display.dc.Low()
-	rpio.SpiTransmit(data...)
+	display.spi(data...)
 }
 
 func (display *ST7789Display) data(data ...byte) {
 	display.dc.High()
-	rpio.SpiTransmit(data...)
+	display.spi(data...)
 }
 
 func (display *ST7789Display) rgbaTo565(c color.RGBA) uint16 {
@@ -0,0 +1,35 @@
+package dev
+
+import (
+	"image"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ST7789DisplayWithPins(t *testing.T) {
+	res, dc, blk := NewFakePin(), NewFakePin(), NewFakePin()
+	var sent [][]byte
+	spi := func(data ...byte) {
+		sent = append(sent, append([]byte(nil), data...))
+	}
+	display := newST7789Display(res, dc, blk, spi, 4, 2)
+
+	assert.Equal(t, OutputMode, res.Mode())
+	assert.Equal(t, OutputMode, dc.Mode())
+	assert.Equal(t, High, blk.Level())
+	assert.Equal(t, []LogicLevel{High, Low, High}, res.Writes())
+	assert.Equal(t, []byte{0x11}, sent[0])
+	assert.Equal(t, []byte{0x29}, sent[len(sent)-1])
+
+	sent = nil
+	assert.NoError(t, display.Image(image.NewRGBA(image.Rect(0, 0, 4, 2))))
+	assert.Equal(t, []byte{0x2C}, sent[len(sent)-2])
+	assert.Len(t, sent[len(sent)-1], 4*2*2)
+	assert.Equal(t, High, dc.Level())
+
+	assert.NoError(t, display.Off())
+	assert.Equal(t, Low, blk.Level())
+	assert.NoError(t, display.On())
+	assert.Equal(t, High, blk.Level())
+}
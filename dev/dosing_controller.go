@@ -0,0 +1,222 @@
+/*
+DosingController dispenses a target volume of water with a Pump.
+
+With a VolumeMeter like WaterFlowMeter, it keeps the pump on until the meter reports the target volume.
+It stops with an error if no flow is measured for DryRunTime, which means the pump is running dry or the pipe is blocked.
+Without a meter, it falls back to running the pump for target/FlowRate, calibrate FlowRate by running the pump
+for a while and measuring the water:
+
+	FlowRate = measured ml / running seconds
+
+The pump never runs longer than MaxRuntime in both cases.
+
+Usage:
+
+	pump := dev.NewPumpImp(26)
+	meter := dev.NewWaterFlowMeter(17)
+	c := dev.NewDosingController(pump, meter, dev.DosingConfig{MaxRuntime: time.Minute})
+	ml, err := c.Dose(ctx, 250)
+*/
+package dev
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDosingMaxRuntime = 5 * time.Minute
+	defaultDosingDryRunTime = 5 * time.Second
+	defaultDosingInterval   = 50 * time.Millisecond
+)
+
+var (
+	errDosingBusy  = errors.New("dosing is in progress")
+	errDryRun      = errors.New("no flow detected, the pump may be running dry")
+	errMaxRuntime  = errors.New("max runtime exceeded")
+	errNoFlowRate  = errors.New("flow rate isn't calibrated")
+	errInvalidDose = errors.New("invalid volume")
+)
+
+// DosingConfig ...
+type DosingConfig struct {
+	// MaxRuntime is the max time of the pump running for a dose, default 5m
+	MaxRuntime time.Duration
+	// DryRunTime fails the dose if no flow is measured for it, default 5s
+	DryRunTime time.Duration
+	// FlowRate is the flow rate of the pump in ml/s for dosing by time when there is no meter
+	FlowRate float64
+	// Interval is the interval of reading the meter, default 50ms
+	Interval time.Duration
+}
+
+// DosingController ...
+type DosingController struct {
+	pump  Pump
+	meter VolumeMeter
+	cfg   DosingConfig
+
+	mu     sync.Mutex
+	dosing bool
+	dosed  float64
+}
+
+// NewDosingController creates a controller, meter can be nil for dosing by time with FlowRate
+func NewDosingController(pump Pump, meter VolumeMeter, cfg DosingConfig) *DosingController {
+	if cfg.MaxRuntime <= 0 {
+		cfg.MaxRuntime = defaultDosingMaxRuntime
+	}
+	if cfg.DryRunTime <= 0 {
+		cfg.DryRunTime = defaultDosingDryRunTime
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultDosingInterval
+	}
+	return &DosingController{
+		pump:  pump,
+		meter: meter,
+		cfg:   cfg,
+	}
+}
+
+// Dosing returns true if a dose is in progress
+func (c *DosingController) Dosing() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.dosing
+}
+
+// Dosed returns the volume in ml dispensed by the current or the last dose
+func (c *DosingController) Dosed() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.dosed
+}
+
+// FlowRate returns the flow rate in ml/s for dosing by time
+func (c *DosingController) FlowRate() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cfg.FlowRate
+}
+
+// SetFlowRate sets the flow rate in ml/s for dosing by time
+func (c *DosingController) SetFlowRate(mlps float64) {
+	if mlps <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cfg.FlowRate = mlps
+}
+
+// Dose runs the pump until ml milliliters are dispensed, and returns the volume dispensed.
+// It blocks until the dose is finished, and turns off the pump on MaxRuntime, dry run, or ctx is done.
+func (c *DosingController) Dose(ctx context.Context, ml float64) (float64, error) {
+	if ml <= 0 {
+		return 0, fmt.Errorf("%w: %v ml", errInvalidDose, ml)
+	}
+	c.mu.Lock()
+	if c.dosing {
+		c.mu.Unlock()
+		return 0, errDosingBusy
+	}
+	c.dosing, c.dosed = true, 0
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.dosing = false
+	}()
+
+	if c.meter == nil {
+		return c.doseByTime(ctx, ml)
+	}
+	return c.doseByMeter(ctx, ml)
+}
+
+func (c *DosingController) doseByMeter(ctx context.Context, ml float64) (float64, error) {
+	last := c.meter.Total()
+	c.pump.On()
+	defer c.pump.Off()
+
+	start := time.Now()
+	lastFlow := start
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return c.Dosed(), ctx.Err()
+		case now := <-ticker.C:
+			dosed, flowed := c.measure(&last)
+			if dosed >= ml {
+				return dosed, nil
+			}
+			if flowed {
+				lastFlow = now
+			}
+			if now.Sub(lastFlow) >= c.cfg.DryRunTime {
+				return dosed, fmt.Errorf("%w: %.1f of %.1f ml dispensed", errDryRun, dosed, ml)
+			}
+			if now.Sub(start) >= c.cfg.MaxRuntime {
+				return dosed, fmt.Errorf("%w: %.1f of %.1f ml dispensed", errMaxRuntime, dosed, ml)
+			}
+		}
+	}
+}
+
+// measure adds the volume since the last reading of the meter, and returns the volume dispensed.
+// The meter is read by increments, so resetting its total during the dose doesn't count.
+func (c *DosingController) measure(last *float64) (float64, bool) {
+	total := c.meter.Total()
+	delta := (total - *last) * 1000
+	*last = total
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if delta > 0 {
+		c.dosed += delta
+	}
+	return c.dosed, delta > 0
+}
+
+func (c *DosingController) doseByTime(ctx context.Context, ml float64) (float64, error) {
+	c.mu.Lock()
+	rate := c.cfg.FlowRate
+	c.mu.Unlock()
+	if rate <= 0 {
+		return 0, errNoFlowRate
+	}
+	d := time.Duration(ml / rate * float64(time.Second))
+	if d > c.cfg.MaxRuntime {
+		return 0, fmt.Errorf("%w: %.1f ml needs %v", errMaxRuntime, ml, d)
+	}
+
+	c.pump.On()
+	start := time.Now()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	var err error
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	c.pump.Off()
+
+	dosed := ml
+	if err != nil {
+		dosed = time.Since(start).Seconds() * rate
+		if dosed > ml {
+			dosed = ml
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dosed = dosed
+	return dosed, err
+}
@@ -0,0 +1,134 @@
+package dev
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakePump pours water to fakeMeter at the rate in ml/s while it is on
+type fakePump struct {
+	mu    sync.Mutex
+	rate  float64
+	on    bool
+	since time.Time
+	total float64 // liters
+	runs  int
+}
+
+func (p *fakePump) On() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.on {
+		p.on, p.since = true, time.Now()
+		p.runs++
+	}
+}
+
+func (p *fakePump) Off() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.on {
+		p.total += time.Since(p.since).Seconds() * p.rate / 1000
+		p.on = false
+	}
+}
+
+func (p *fakePump) Run(sec int) {}
+
+func (p *fakePump) isOn() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.on
+}
+
+// Total implements VolumeMeter
+func (p *fakePump) Total() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.on {
+		return p.total + time.Since(p.since).Seconds()*p.rate/1000
+	}
+	return p.total
+}
+
+func Test_DosingControllerMeter(t *testing.T) {
+	pump := &fakePump{rate: 1000}
+	c := NewDosingController(pump, pump, DosingConfig{Interval: time.Millisecond})
+
+	ml, err := c.Dose(context.Background(), 100)
+	assert.NoError(t, err)
+	assert.False(t, pump.isOn())
+	assert.GreaterOrEqual(t, ml, 100.0)
+	assert.Less(t, ml, 150.0)
+	assert.Equal(t, ml, c.Dosed())
+	assert.False(t, c.Dosing())
+
+	_, err = c.Dose(context.Background(), 0)
+	assert.ErrorIs(t, err, errInvalidDose)
+}
+
+func Test_DosingControllerSafety(t *testing.T) {
+	// running dry
+	pump := &fakePump{}
+	c := NewDosingController(pump, pump, DosingConfig{Interval: time.Millisecond, DryRunTime: 50 * time.Millisecond})
+	ml, err := c.Dose(context.Background(), 100)
+	assert.ErrorIs(t, err, errDryRun)
+	assert.Equal(t, 0.0, ml)
+	assert.False(t, pump.isOn())
+
+	// max runtime
+	pump = &fakePump{rate: 100}
+	c = NewDosingController(pump, pump, DosingConfig{Interval: time.Millisecond, MaxRuntime: 100 * time.Millisecond})
+	ml, err = c.Dose(context.Background(), 1000)
+	assert.ErrorIs(t, err, errMaxRuntime)
+	assert.Greater(t, ml, 5.0)
+	assert.Less(t, ml, 100.0)
+	assert.False(t, pump.isOn())
+
+	// canceled
+	pump = &fakePump{rate: 100}
+	c = NewDosingController(pump, pump, DosingConfig{Interval: time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		assert.Eventually(t, c.Dosing, time.Second, time.Millisecond)
+		_, err := c.Dose(ctx, 10)
+		assert.ErrorIs(t, err, errDosingBusy)
+		cancel()
+	}()
+	_, err = c.Dose(ctx, 1000)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, pump.isOn())
+}
+
+func Test_DosingControllerTime(t *testing.T) {
+	pump := &fakePump{rate: 500}
+	c := NewDosingController(pump, nil, DosingConfig{MaxRuntime: time.Second})
+
+	_, err := c.Dose(context.Background(), 50)
+	assert.ErrorIs(t, err, errNoFlowRate)
+	assert.Equal(t, 0, pump.runs)
+
+	c.SetFlowRate(500)
+	assert.Equal(t, 500.0, c.FlowRate())
+	ml, err := c.Dose(context.Background(), 50)
+	assert.NoError(t, err)
+	assert.Equal(t, 50.0, ml)
+	assert.InDelta(t, 50, pump.Total()*1000, 10)
+	assert.False(t, pump.isOn())
+
+	// 1000 ml needs 2s
+	_, err = c.Dose(context.Background(), 1000)
+	assert.ErrorIs(t, err, errMaxRuntime)
+	assert.Equal(t, 1, pump.runs)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	ml, err = c.Dose(ctx, 400)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.InDelta(t, 25, ml, 15)
+	assert.False(t, pump.isOn())
+}
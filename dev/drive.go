@@ -0,0 +1,245 @@
+/*
+DifferentialDrive drives a car with two motors on the left and right sides, e.g. the two motors of a L298N.
+
+Features:
+ - Drive(linear, angular), Spin in place, Arc with a turning radius, and Brake.
+ - speed ramping limited by Accel, so the motors never jump from full forward to full backward.
+ - per-side trim to calibrate mismatched motors, the car runs straight at the same speed of both sides.
+ - inverted wiring of each side, instead of swapping the wires of the motors.
+
+Usage:
+
+	l298n := dev.NewL298N(in1, in2, in3, in4, ena, enb)
+	drive := dev.NewDifferentialDriveWithConfig(l298n.MotorA, l298n.MotorB, dev.DifferentialDriveConfig{
+		Accel:       2,
+		RightTrim:   0.95,
+		InvertRight: true,
+	})
+	defer drive.Close()
+	drive.Drive(0.5, 0.2) // forward at half speed, turning right
+	drive.Arc(0.5, -1)    // forward at half speed, turning left on a circle of 1m radius
+	drive.Spin(0.3)       // spin clockwise in place
+	drive.Brake()
+*/
+package dev
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const defaultRampInterval = 20 * time.Millisecond
+
+// DifferentialDriveConfig ...
+type DifferentialDriveConfig struct {
+	// Accel limits how fast the speed of a side changes, in full speed per second.
+	// e.g. 2 takes 0.5s from stop to full speed. 0 disables ramping.
+	Accel float64
+	// Interval is the interval of ramping in the background, default 20ms
+	Interval time.Duration
+	// LeftTrim and RightTrim scale the speeds of the sides, 0 ~ 1, default 1.
+	// Trim down the faster side if the car doesn't run straight.
+	LeftTrim  float64
+	RightTrim float64
+	// InvertLeft and InvertRight reverse the directions of the sides
+	InvertLeft  bool
+	InvertRight bool
+	// TrackWidth is the distance between the left and right wheels in meters for Arc(), default 0.15
+	TrackWidth float64
+}
+
+// DifferentialDrive implements DriveTrain interface
+type DifferentialDrive struct {
+	left  MotorDriver
+	right MotorDriver
+	cfg   DifferentialDriveConfig
+
+	mu     sync.Mutex
+	target [2]float64 // target speeds of left and right
+	cur    [2]float64 // current speeds of left and right
+	done   chan struct{}
+	once   sync.Once
+}
+
+// motorBraker is a motor driver that can brake actively instead of coasting to stop
+type motorBraker interface {
+	Brake()
+}
+
+// NewDifferentialDrive creates a drive without ramping and trimming
+func NewDifferentialDrive(left, right MotorDriver) *DifferentialDrive {
+	return NewDifferentialDriveWithConfig(left, right, DifferentialDriveConfig{})
+}
+
+// NewDifferentialDriveWithConfig creates a drive, it ramps the speeds in the background if Accel > 0
+func NewDifferentialDriveWithConfig(left, right MotorDriver, cfg DifferentialDriveConfig) *DifferentialDrive {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultRampInterval
+	}
+	if cfg.LeftTrim <= 0 || cfg.LeftTrim > 1 {
+		cfg.LeftTrim = 1
+	}
+	if cfg.RightTrim <= 0 || cfg.RightTrim > 1 {
+		cfg.RightTrim = 1
+	}
+	if cfg.TrackWidth <= 0 {
+		cfg.TrackWidth = 0.15
+	}
+	d := &DifferentialDrive{
+		left:  left,
+		right: right,
+		cfg:   cfg,
+		done:  make(chan struct{}),
+	}
+	if cfg.Accel > 0 {
+		go d.ramp()
+	}
+	return d
+}
+
+// Drive drives at the linear speed while turning at the angular speed, both are -1 ~ 1.
+// Positive linear is forward, and positive angular turns clockwise.
+func (d *DifferentialDrive) Drive(linear, angular float64) {
+	d.set(mix(linear, angular))
+}
+
+// Spin spins in place at the speed -1 ~ 1, positive is clockwise
+func (d *DifferentialDrive) Spin(speed float64) {
+	d.Drive(0, speed)
+}
+
+// Arc drives at the speed -1 ~ 1 on a circle of the radius in meters.
+// Positive radius turns right, negative radius turns left, and 0 spins in place.
+func (d *DifferentialDrive) Arc(speed, radius float64) {
+	if radius == 0 {
+		d.Spin(speed)
+		return
+	}
+	// the outer side runs faster than the inner side by the ratio of their radiuses
+	k := d.cfg.TrackWidth / (2 * radius)
+	left, right := speed*(1+k), speed*(1-k)
+	if m := math.Max(math.Abs(left), math.Abs(right)); m > 1 {
+		left, right = left/m, right/m
+	}
+	d.set(left, right)
+}
+
+// Stop stops the car, it slows down following Accel
+func (d *DifferentialDrive) Stop() {
+	d.set(0, 0)
+}
+
+// Brake stops the car immediately without ramping.
+// The motors brake actively if their drivers support it, otherwise they coast to stop.
+func (d *DifferentialDrive) Brake() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.target = [2]float64{}
+	d.cur = [2]float64{}
+	brake(d.left)
+	brake(d.right)
+}
+
+// Speeds returns the current speeds of the left and right sides, -1 ~ 1 before trimming and inverting
+func (d *DifferentialDrive) Speeds() (left, right float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.cur[0], d.cur[1]
+}
+
+// Close stops ramping and brakes
+func (d *DifferentialDrive) Close() error {
+	d.once.Do(func() {
+		close(d.done)
+	})
+	d.Brake()
+	return nil
+}
+
+func (d *DifferentialDrive) set(left, right float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.target = [2]float64{left, right}
+	if d.cfg.Accel <= 0 {
+		d.cur = d.target
+		d.apply()
+	}
+}
+
+// ramp moves the current speeds toward the targets every interval
+func (d *DifferentialDrive) ramp() {
+	ticker := time.NewTicker(d.cfg.Interval)
+	defer ticker.Stop()
+	last := time.Now()
+	for {
+		select {
+		case <-d.done:
+			return
+		case now := <-ticker.C:
+			d.step(now.Sub(last).Seconds())
+			last = now
+		}
+	}
+}
+
+// step moves the current speeds toward the targets by at most Accel x dt
+func (d *DifferentialDrive) step(dt float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.cur == d.target {
+		return
+	}
+	maxDelta := d.cfg.Accel * dt
+	for i := range d.cur {
+		delta := d.target[i] - d.cur[i]
+		d.cur[i] += math.Max(-maxDelta, math.Min(maxDelta, delta))
+	}
+	d.apply()
+}
+
+// apply sets the motors to the current speeds
+func (d *DifferentialDrive) apply() {
+	left, right := d.cur[0]*d.cfg.LeftTrim, d.cur[1]*d.cfg.RightTrim
+	if d.cfg.InvertLeft {
+		left = -left
+	}
+	if d.cfg.InvertRight {
+		right = -right
+	}
+	setMotor(d.left, left)
+	setMotor(d.right, right)
+}
+
+// mix converts linear and angular speeds to the speeds of the left and right sides,
+// they are scaled down together if any of them exceeds 1.
+func mix(linear, angular float64) (left, right float64) {
+	left, right = linear+angular, linear-angular
+	if m := math.Max(math.Abs(left), math.Abs(right)); m > 1 {
+		left, right = left/m, right/m
+	}
+	return left, right
+}
+
+// setMotor runs the motor at the speed -1 ~ 1, negative is backward
+func setMotor(m MotorDriver, speed float64) {
+	switch {
+	case speed > 0:
+		m.Forward()
+	case speed < 0:
+		m.Backward()
+	default:
+		m.Stop()
+		return
+	}
+	m.SetSpeed(uint32(math.Round(math.Min(1, math.Abs(speed)) * 100)))
+}
+
+// brake brakes the motor if it supports braking, otherwise it stops the motor
+func brake(m MotorDriver) {
+	if b, ok := m.(motorBraker); ok {
+		b.Brake()
+		return
+	}
+	m.Stop()
+}
@@ -0,0 +1,152 @@
+package dev
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DifferentialDrive(t *testing.T) {
+	left, right := NewMotorSimulator(), NewMotorSimulator()
+	d := NewDifferentialDrive(left, right)
+
+	testCases := []struct {
+		desc    string
+		linear  float64
+		angular float64
+		left    float64
+		right   float64
+	}{
+		{desc: "forward", linear: 0.5, angular: 0, left: 0.5, right: 0.5},
+		{desc: "backward", linear: -0.3, angular: 0, left: -0.3, right: -0.3},
+		{desc: "turn right", linear: 0.5, angular: 0.2, left: 0.7, right: 0.3},
+		{desc: "turn left", linear: 0.5, angular: -0.2, left: 0.3, right: 0.7},
+		{desc: "scaled", linear: 1, angular: 1, left: 1, right: 0},
+		{desc: "spin", linear: 0, angular: -0.4, left: -0.4, right: 0.4},
+		{desc: "stop", linear: 0, angular: 0, left: 0, right: 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			d.Drive(tc.linear, tc.angular)
+			assert.InDelta(t, tc.left, left.Velocity(), 1e-9)
+			assert.InDelta(t, tc.right, right.Velocity(), 1e-9)
+		})
+	}
+
+	d.Spin(0.3)
+	assert.InDelta(t, 0.3, left.Velocity(), 1e-9)
+	assert.InDelta(t, -0.3, right.Velocity(), 1e-9)
+
+	d.Brake()
+	assert.True(t, left.Braked())
+	assert.True(t, right.Braked())
+	assert.Equal(t, 0.0, left.Velocity())
+}
+
+func Test_DifferentialDriveArc(t *testing.T) {
+	left, right := NewMotorSimulator(), NewMotorSimulator()
+	d := NewDifferentialDriveWithConfig(left, right, DifferentialDriveConfig{TrackWidth: 0.2})
+
+	// the inner wheel runs on a circle of 0.9m, and the outer one on 1.1m
+	d.Arc(0.5, 1)
+	assert.InDelta(t, 0.55, left.Velocity(), 1e-9)
+	assert.InDelta(t, 0.45, right.Velocity(), 1e-9)
+
+	d.Arc(0.5, -1)
+	assert.InDelta(t, 0.45, left.Velocity(), 1e-9)
+	assert.InDelta(t, 0.55, right.Velocity(), 1e-9)
+
+	// turning around the inner wheel
+	d.Arc(0.5, 0.1)
+	assert.InDelta(t, 1, left.Velocity(), 1e-9)
+	assert.InDelta(t, 0, right.Velocity(), 1e-9)
+
+	d.Arc(0.5, 0)
+	assert.InDelta(t, 0.5, left.Velocity(), 1e-9)
+	assert.InDelta(t, -0.5, right.Velocity(), 1e-9)
+}
+
+func Test_DifferentialDriveTrimAndInvert(t *testing.T) {
+	left, right := NewMotorSimulator(), NewMotorSimulator()
+	d := NewDifferentialDriveWithConfig(left, right, DifferentialDriveConfig{
+		RightTrim:   0.9,
+		InvertRight: true,
+	})
+	d.Drive(0.5, 0)
+	assert.InDelta(t, 0.5, left.Velocity(), 1e-9)
+	assert.InDelta(t, -0.45, right.Velocity(), 1e-9)
+
+	// speeds are reported before trimming and inverting
+	l, r := d.Speeds()
+	assert.Equal(t, 0.5, l)
+	assert.Equal(t, 0.5, r)
+
+	d.Drive(-1, 0)
+	assert.InDelta(t, -1, left.Velocity(), 1e-9)
+	assert.InDelta(t, 0.9, right.Velocity(), 1e-9)
+}
+
+func Test_DifferentialDriveRamp(t *testing.T) {
+	left, right := NewMotorSimulator(), NewMotorSimulator()
+	// ramp manually with step()
+	d := NewDifferentialDriveWithConfig(left, right, DifferentialDriveConfig{
+		Accel:    2,
+		Interval: time.Hour,
+	})
+	defer d.Close()
+
+	d.Drive(1, 0)
+	assert.Equal(t, 0.0, left.Velocity())
+	d.step(0.1)
+	assert.InDelta(t, 0.2, left.Velocity(), 1e-9)
+	assert.InDelta(t, 0.2, right.Velocity(), 1e-9)
+	for i := 0; i < 10; i++ {
+		d.step(0.1)
+	}
+	assert.InDelta(t, 1, left.Velocity(), 1e-9)
+
+	// reversing passes zero without jumping
+	d.Drive(-1, 0)
+	var speeds []float64
+	for i := 0; i < 10; i++ {
+		d.step(0.1)
+		speeds = append(speeds, left.Velocity())
+	}
+	assert.InDeltaSlice(t, []float64{0.8, 0.6, 0.4, 0.2, 0, -0.2, -0.4, -0.6, -0.8, -1}, speeds, 1e-9)
+
+	// spinning ramps the sides in opposite directions
+	d.Spin(-1)
+	d.step(0.25)
+	l, r := d.Speeds()
+	assert.InDelta(t, -1, l, 1e-9)
+	assert.InDelta(t, -0.5, r, 1e-9)
+
+	// brake doesn't ramp
+	d.Brake()
+	assert.Equal(t, 0.0, left.Velocity())
+	assert.Equal(t, 0.0, right.Velocity())
+	d.step(0.1)
+	assert.True(t, left.Braked())
+}
+
+func Test_DifferentialDriveRampInBackground(t *testing.T) {
+	left, right := NewMotorSimulator(), NewMotorSimulator()
+	d := NewDifferentialDriveWithConfig(left, right, DifferentialDriveConfig{
+		Accel:    10,
+		Interval: 5 * time.Millisecond,
+	})
+	defer d.Close()
+
+	d.Drive(0.6, 0)
+	assert.Less(t, left.Velocity(), 0.6)
+	assert.Eventually(t, func() bool {
+		return left.Velocity() == 0.6 && right.Velocity() == 0.6
+	}, time.Second, 5*time.Millisecond)
+
+	d.Stop()
+	assert.Eventually(t, func() bool {
+		return left.Velocity() == 0 && right.Velocity() == 0
+	}, time.Second, 5*time.Millisecond)
+	assert.False(t, left.Braked())
+}
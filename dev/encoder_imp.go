@@ -1,5 +1,6 @@
 /*
 Encoder is a sensor used to count number.
+Use QuadratureEncoder for the encoders with A/B channels, which tracks the direction and the speed.
 
 Connect to Raspberry Pi:
  - vcc: any 3.3v or v5 pin
@@ -10,22 +11,27 @@ Connect to Raspberry Pi:
 package dev
 
 import (
-	"github.com/stianeikeland/go-rpio/v4"
+	"context"
 )
 
 // EncoderImp implements Encoder interface
 type EncoderImp struct {
-	pin rpio.Pin
+	pin Pin
 }
 
 // NewEncoderImp ...
 func NewEncoderImp(pin uint8) *EncoderImp {
+	return NewEncoderImpWithPin(newPin(pin))
+}
+
+// NewEncoderImpWithPin ...
+func NewEncoderImpWithPin(pin Pin) *EncoderImp {
 	e := &EncoderImp{
-		pin: rpio.Pin(pin),
+		pin: pin,
 	}
 	e.pin.Input()
 	e.pin.PullDown()
-	e.pin.Detect(rpio.NoEdge)
+	e.pin.Detect(NoEdge)
 	return e
 }
 
@@ -44,10 +50,16 @@ func (e *EncoderImp) Count1() int {
 
 // Start ...
 func (e *EncoderImp) Start() {
-	e.pin.Detect(rpio.RiseEdge)
+	e.pin.Detect(RiseEdge)
 }
 
 // Stop ...
 func (e *EncoderImp) Stop() {
-	e.pin.Detect(rpio.NoEdge)
+	e.pin.Detect(NoEdge)
+}
+
+// Watch delivers an event on every pulse of the encoder until ctx is done.
+// It is unnecessary to call Start() for watching.
+func (e *EncoderImp) Watch(ctx context.Context) <-chan InputEvent {
+	return watchPulses(ctx, e.pin, pulsePollInterval)
 }
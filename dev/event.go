@@ -0,0 +1,195 @@
+package dev
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	eventBufSize = 64
+	// waitEdgeTimeout is the max time blocking in WaitEdge(), so that the cancellation can be checked
+	waitEdgeTimeout = 100 * time.Millisecond
+	// pulsePollInterval is the polling interval for pulse trains like encoders and flow meters,
+	// which may have more than one pulse in EdgePollInterval
+	pulsePollInterval = time.Millisecond
+)
+
+// EdgePollInterval is the default interval for sampling pins which can't wait for edges, like the rpio pins.
+// Pulses shorter than the interval are still caught by the latched edge detection of the pin,
+// use WatchEdgesInterval() for a shorter interval if more than one pulse may come in an interval.
+var EdgePollInterval = 10 * time.Millisecond
+
+// EdgeWaiter is implemented by pins which can wait for edges without polling, like ChipPin
+type EdgeWaiter interface {
+	WaitEdge(timeout time.Duration) (EdgeEvent, bool, error)
+}
+
+// InputEvent is a change of an input device,
+// e.g. a button being pressed or released, a detector detecting something or not.
+type InputEvent struct {
+	// Active is true if the device becomes active, e.g. pressed or detected
+	Active bool
+	// Time is the time when the change happened
+	Time time.Time
+}
+
+// Watcher is implemented by input devices delivering events
+type Watcher interface {
+	// Watch delivers events on the returned channel until ctx is done,
+	// the channel is closed after ctx is done.
+	Watch(ctx context.Context) <-chan InputEvent
+}
+
+// WatchEdges enables the edge detection of the pin, and delivers the detected edges on the returned channel.
+// The channel is closed after ctx is done.
+// Kernel events are used if the pin implements EdgeWaiter,
+// or the pin is sampled every EdgePollInterval.
+// Please NOTE that EdgeDetected() of the pin shouldn't be called by others while watching.
+func WatchEdges(ctx context.Context, pin Pin, edge Edge) <-chan EdgeEvent {
+	return WatchEdgesInterval(ctx, pin, edge, EdgePollInterval)
+}
+
+// WatchEdgesInterval is like WatchEdges, but samples the pin every interval if it can't wait for edges.
+// It is for fast signals like encoders, the interval is ignored for EdgeWaiter pins.
+func WatchEdgesInterval(ctx context.Context, pin Pin, edge Edge, interval time.Duration) <-chan EdgeEvent {
+	if interval <= 0 {
+		interval = EdgePollInterval
+	}
+	ch := make(chan EdgeEvent, eventBufSize)
+	pin.Detect(edge)
+	level := pin.Read()
+	go func() {
+		defer close(ch)
+		if w, ok := pin.(EdgeWaiter); ok {
+			waitEdges(ctx, w, edge, ch)
+			return
+		}
+		pollEdges(ctx, pin, edge, level, interval, ch)
+	}()
+	return ch
+}
+
+// OnEdge calls fn for every detected edge of the pin until ctx is done.
+// It blocks until ctx is done.
+func OnEdge(ctx context.Context, pin Pin, edge Edge, fn func(EdgeEvent)) {
+	for ev := range WatchEdges(ctx, pin, edge) {
+		fn(ev)
+	}
+}
+
+// OnInput calls fn for every event of the input device until ctx is done.
+// It blocks until ctx is done.
+func OnInput(ctx context.Context, w Watcher, fn func(InputEvent)) {
+	for ev := range w.Watch(ctx) {
+		fn(ev)
+	}
+}
+
+// watchInput watches the pin of an input device which is active at the level
+func watchInput(ctx context.Context, pin Pin, active LogicLevel) <-chan InputEvent {
+	ch := make(chan InputEvent, eventBufSize)
+	edges := WatchEdges(ctx, pin, AnyEdge)
+	go func() {
+		defer close(ch)
+		for ev := range edges {
+			level := Low
+			if ev.Edge == RiseEdge {
+				level = High
+			}
+			select {
+			case ch <- InputEvent{Active: level == active, Time: ev.Time}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// watchPulses delivers an active event on every rising edge of the pin sampled every interval
+func watchPulses(ctx context.Context, pin Pin, interval time.Duration) <-chan InputEvent {
+	ch := make(chan InputEvent, eventBufSize)
+	edges := WatchEdgesInterval(ctx, pin, RiseEdge, interval)
+	go func() {
+		defer close(ch)
+		for ev := range edges {
+			select {
+			case ch <- InputEvent{Active: true, Time: ev.Time}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+func waitEdges(ctx context.Context, w EdgeWaiter, edge Edge, ch chan<- EdgeEvent) {
+	for ctx.Err() == nil {
+		ev, ok, err := w.WaitEdge(waitEdgeTimeout)
+		if err != nil {
+			return
+		}
+		if !ok || !matchEdge(edge, ev.Edge) {
+			continue
+		}
+		if !sendEdge(ctx, ch, ev) {
+			return
+		}
+	}
+}
+
+func pollEdges(ctx context.Context, pin Pin, edge Edge, last LogicLevel, interval time.Duration, ch chan<- EdgeEvent) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		detected := pin.EdgeDetected()
+		level := pin.Read()
+		now := time.Now()
+
+		var edges []Edge
+		switch {
+		case level != last:
+			edges = []Edge{levelEdge(level)}
+		case detected:
+			// a pulse shorter than the interval, the level went and came back
+			edges = []Edge{levelEdge(1 - last), levelEdge(last)}
+		}
+		last = level
+
+		for _, e := range edges {
+			if !matchEdge(edge, e) {
+				continue
+			}
+			if !sendEdge(ctx, ch, EdgeEvent{Edge: e, Time: now}) {
+				return
+			}
+		}
+	}
+}
+
+func sendEdge(ctx context.Context, ch chan<- EdgeEvent, ev EdgeEvent) bool {
+	select {
+	case ch <- ev:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func levelEdge(level LogicLevel) Edge {
+	if level == High {
+		return RiseEdge
+	}
+	return FallEdge
+}
+
+func matchEdge(want, got Edge) bool {
+	return want == AnyEdge || want == got
+}
@@ -0,0 +1,87 @@
+package dev
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func recvEdge(t *testing.T, ch <-chan EdgeEvent) EdgeEvent {
+	select {
+	case ev := <-ch:
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("no edge received")
+	}
+	return EdgeEvent{}
+}
+
+func Test_WatchEdgesPolling(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := NewFakePin()
+	p.Input()
+	ch := WatchEdges(ctx, p, AnyEdge)
+
+	p.Set(High)
+	assert.Equal(t, RiseEdge, recvEdge(t, ch).Edge)
+	p.Set(Low)
+	assert.Equal(t, FallEdge, recvEdge(t, ch).Edge)
+
+	// a pulse shorter than the polling interval is caught by edge detection
+	p.Set(High)
+	p.Set(Low)
+	assert.Equal(t, RiseEdge, recvEdge(t, ch).Edge)
+	assert.Equal(t, FallEdge, recvEdge(t, ch).Edge)
+
+	cancel()
+	for range ch {
+	}
+}
+
+func Test_WatchEdgesWaiter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fake := newFakeGpioChipDevice()
+	chip := newGpioChip(fake)
+	defer chip.Close()
+
+	ch := WatchEdges(ctx, chip.Pin(6), FallEdge)
+	fake.lines[6].drive(High)
+	fake.lines[6].drive(Low)
+	ev := recvEdge(t, ch)
+	assert.Equal(t, FallEdge, ev.Edge)
+	assert.False(t, ev.Time.IsZero())
+}
+
+func Test_DetectorWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := NewFakePin()
+	p.Set(High)
+	ir := NewIRDetectorWithPin(p)
+
+	var events []InputEvent
+	done := make(chan bool)
+	go func() {
+		OnInput(ctx, ir, func(ev InputEvent) {
+			events = append(events, ev)
+			if len(events) == 2 {
+				done <- true
+			}
+		})
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	p.Set(Low)
+	time.Sleep(10 * time.Millisecond)
+	p.Set(High)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("no events received")
+	}
+	assert.True(t, events[0].Active)
+	assert.False(t, events[1].Active)
+}
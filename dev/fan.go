@@ -7,19 +7,20 @@ Connect to Raspberry Pi:
 */
 package dev
 
-import (
-	"github.com/stianeikeland/go-rpio/v4"
-)
-
 // Fan ...
 type Fan struct {
-	pin rpio.Pin
+	pin Pin
 }
 
 // NewFan ...
 func NewFan(pin uint8) *Fan {
+	return NewFanWithPin(newPin(pin))
+}
+
+// NewFanWithPin ...
+func NewFanWithPin(pin Pin) *Fan {
 	f := &Fan{
-		pin: rpio.Pin(pin),
+		pin: pin,
 	}
 	f.pin.Output()
 	f.pin.Low()
@@ -0,0 +1,309 @@
+package dev
+
+import (
+	"context"
+	"time"
+)
+
+// Gesture ...
+type Gesture int
+
+const (
+	// Click is a short press and release
+	Click Gesture = iota
+	// DoubleClick is two clicks in DoubleClickWindow
+	DoubleClick
+	// LongPress is a press held for LongPressTime
+	LongPress
+	// Repeat is delivered every RepeatInterval while holding after a LongPress
+	Repeat
+)
+
+// DetectorPollInterval is the interval for polling the detectors which don't implement Watcher
+var DetectorPollInterval = 10 * time.Millisecond
+
+// DefaultGestureConfig ...
+var DefaultGestureConfig = GestureConfig{
+	Debounce:          20 * time.Millisecond,
+	DoubleClickWindow: 300 * time.Millisecond,
+	LongPressTime:     800 * time.Millisecond,
+	RepeatInterval:    200 * time.Millisecond,
+}
+
+// GestureConfig is the timings for recognizing gestures,
+// a gesture is disabled if its timing is 0.
+type GestureConfig struct {
+	// Debounce is the time the input must be stable for
+	Debounce time.Duration
+	// DoubleClickWindow is the max time between releasing the first click and pressing the second one.
+	// Click is delivered without delay if double click is disabled.
+	DoubleClickWindow time.Duration
+	// LongPressTime is the min time holding for a long press
+	LongPressTime time.Duration
+	// RepeatInterval is the interval of Repeat while holding after a long press
+	RepeatInterval time.Duration
+}
+
+// GestureEvent ...
+type GestureEvent struct {
+	Gesture Gesture
+	// Count is the number of Repeat since the long press, it is 0 for other gestures
+	Count int
+	Time  time.Time
+}
+
+// DetectorFunc is an adapter to use a function as a Detector,
+// e.g. DetectorFunc(button.Pressed) or DetectorFunc(func() bool { return joystick.Z() == 1 })
+type DetectorFunc func() bool
+
+// Detected ...
+func (f DetectorFunc) Detected() bool {
+	return f()
+}
+
+// AsWatcher returns d itself if it implements Watcher,
+// or a Watcher polling d.Detected() every DetectorPollInterval.
+func AsWatcher(d Detector) Watcher {
+	if w, ok := d.(Watcher); ok {
+		return w
+	}
+	return &detectorPoller{d: d}
+}
+
+type detectorPoller struct {
+	d Detector
+}
+
+func (p *detectorPoller) Watch(ctx context.Context) <-chan InputEvent {
+	ch := make(chan InputEvent, eventBufSize)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(DetectorPollInterval)
+		defer ticker.Stop()
+		last := false
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			detected := p.d.Detected()
+			if detected == last {
+				continue
+			}
+			last = detected
+			select {
+			case ch <- InputEvent{Active: detected, Time: time.Now()}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// WatchGestures debounces the events of w and recognizes gestures from them until ctx is done
+func WatchGestures(ctx context.Context, w Watcher, cfg GestureConfig) <-chan GestureEvent {
+	events := w.Watch(ctx)
+	if cfg.Debounce > 0 {
+		events = Debounce(ctx, events, cfg.Debounce)
+	}
+	return RecognizeGestures(ctx, events, cfg)
+}
+
+// Debounce delivers an event only if the state stays for d after changing.
+// The input is considered as inactive at the beginning.
+func Debounce(ctx context.Context, in <-chan InputEvent, d time.Duration) <-chan InputEvent {
+	out := make(chan InputEvent, eventBufSize)
+	go func() {
+		defer close(out)
+		var (
+			stable  bool
+			pending InputEvent
+			timer   *time.Timer
+			timerC  <-chan time.Time
+		)
+		for {
+			select {
+			case ev, ok := <-in:
+				if !ok {
+					return
+				}
+				pending = ev
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.NewTimer(d)
+				timerC = timer.C
+			case <-timerC:
+				timerC = nil
+				if pending.Active == stable {
+					continue
+				}
+				stable = pending.Active
+				select {
+				case out <- pending:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// RecognizeGestures recognizes gestures from the debounced events until ctx is done
+func RecognizeGestures(ctx context.Context, in <-chan InputEvent, cfg GestureConfig) <-chan GestureEvent {
+	out := make(chan GestureEvent, eventBufSize)
+	go func() {
+		defer close(out)
+		g := &gestureRecognizer{cfg: cfg, out: out, ctx: ctx}
+		defer g.stopTimers()
+		g.run(in)
+	}()
+	return out
+}
+
+type gestureRecognizer struct {
+	cfg GestureConfig
+	out chan<- GestureEvent
+	ctx context.Context
+
+	pressed      bool
+	long         bool
+	pendingClick bool
+	repeats      int
+
+	longTimer   *time.Timer
+	clickTimer  *time.Timer
+	repeatTimer *time.Ticker
+	longC       <-chan time.Time
+	clickC      <-chan time.Time
+	repeatC     <-chan time.Time
+}
+
+func (g *gestureRecognizer) run(in <-chan InputEvent) {
+	for {
+		var ok bool
+		select {
+		case ev, more := <-in:
+			if !more {
+				return
+			}
+			if ev.Active {
+				ok = g.press()
+			} else {
+				ok = g.release(ev.Time)
+			}
+		case t := <-g.longC:
+			ok = g.longPress(t)
+		case t := <-g.repeatC:
+			g.repeats++
+			ok = g.emit(Repeat, g.repeats, t)
+		case t := <-g.clickC:
+			g.clickC = nil
+			ok = true
+			if g.pendingClick && !g.pressed {
+				g.pendingClick = false
+				ok = g.emit(Click, 0, t)
+			}
+		case <-g.ctx.Done():
+			return
+		}
+		if !ok {
+			return
+		}
+	}
+}
+
+func (g *gestureRecognizer) press() bool {
+	if g.pressed {
+		return true
+	}
+	g.pressed = true
+	g.long = false
+	if g.cfg.LongPressTime > 0 {
+		g.longTimer = time.NewTimer(g.cfg.LongPressTime)
+		g.longC = g.longTimer.C
+	}
+	if g.clickTimer != nil {
+		// the second press arrived in the double click window
+		g.clickTimer.Stop()
+		g.clickC = nil
+	}
+	return true
+}
+
+func (g *gestureRecognizer) release(t time.Time) bool {
+	if !g.pressed {
+		return true
+	}
+	g.pressed = false
+	g.stopHolding()
+
+	if g.long {
+		g.long = false
+		return true
+	}
+	if g.pendingClick {
+		g.pendingClick = false
+		return g.emit(DoubleClick, 0, t)
+	}
+	if g.cfg.DoubleClickWindow <= 0 {
+		return g.emit(Click, 0, t)
+	}
+	g.pendingClick = true
+	g.clickTimer = time.NewTimer(g.cfg.DoubleClickWindow)
+	g.clickC = g.clickTimer.C
+	return true
+}
+
+func (g *gestureRecognizer) longPress(t time.Time) bool {
+	g.longC = nil
+	g.long = true
+	if g.pendingClick {
+		// the second press became a long press
+		g.pendingClick = false
+		if !g.emit(Click, 0, t) {
+			return false
+		}
+	}
+	if g.cfg.RepeatInterval > 0 {
+		g.repeats = 0
+		g.repeatTimer = time.NewTicker(g.cfg.RepeatInterval)
+		g.repeatC = g.repeatTimer.C
+	}
+	return g.emit(LongPress, 0, t)
+}
+
+func (g *gestureRecognizer) stopHolding() {
+	if g.longTimer != nil {
+		g.longTimer.Stop()
+		g.longC = nil
+	}
+	if g.repeatTimer != nil {
+		g.repeatTimer.Stop()
+		g.repeatC = nil
+	}
+}
+
+func (g *gestureRecognizer) stopTimers() {
+	g.stopHolding()
+	if g.clickTimer != nil {
+		g.clickTimer.Stop()
+	}
+}
+
+func (g *gestureRecognizer) emit(gesture Gesture, count int, t time.Time) bool {
+	select {
+	case g.out <- GestureEvent{Gesture: gesture, Count: count, Time: t}:
+		return true
+	case <-g.ctx.Done():
+		return false
+	}
+}
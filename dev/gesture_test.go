@@ -0,0 +1,119 @@
+package dev
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testGestureConfig = GestureConfig{
+	DoubleClickWindow: 60 * time.Millisecond,
+	LongPressTime:     100 * time.Millisecond,
+	RepeatInterval:    30 * time.Millisecond,
+}
+
+func recvGesture(t *testing.T, ch <-chan GestureEvent) GestureEvent {
+	select {
+	case ev := <-ch:
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("no gesture received")
+	}
+	return GestureEvent{}
+}
+
+func pressFor(in chan<- InputEvent, d time.Duration) {
+	in <- InputEvent{Active: true, Time: time.Now()}
+	time.Sleep(d)
+	in <- InputEvent{Active: false, Time: time.Now()}
+}
+
+func Test_RecognizeGestures(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	in := make(chan InputEvent)
+	out := RecognizeGestures(ctx, in, testGestureConfig)
+
+	pressFor(in, 10*time.Millisecond)
+	assert.Equal(t, Click, recvGesture(t, out).Gesture)
+
+	pressFor(in, 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	pressFor(in, 10*time.Millisecond)
+	assert.Equal(t, DoubleClick, recvGesture(t, out).Gesture)
+
+	in <- InputEvent{Active: true, Time: time.Now()}
+	assert.Equal(t, LongPress, recvGesture(t, out).Gesture)
+	ev := recvGesture(t, out)
+	assert.Equal(t, Repeat, ev.Gesture)
+	assert.Equal(t, 1, ev.Count)
+	assert.Equal(t, 2, recvGesture(t, out).Count)
+	in <- InputEvent{Active: false, Time: time.Now()}
+
+	select {
+	case ev := <-out:
+		if ev.Gesture != Repeat {
+			t.Fatalf("unexpected gesture %v after releasing", ev.Gesture)
+		}
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func Test_Debounce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	in := make(chan InputEvent)
+	out := Debounce(ctx, in, 20*time.Millisecond)
+
+	// bouncing
+	for i := 0; i < 5; i++ {
+		in <- InputEvent{Active: true}
+		in <- InputEvent{Active: false}
+	}
+	in <- InputEvent{Active: true}
+	select {
+	case ev := <-out:
+		assert.True(t, ev.Active)
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+	}
+
+	// a glitch back to the stable state
+	in <- InputEvent{Active: false}
+	in <- InputEvent{Active: true}
+	select {
+	case ev := <-out:
+		t.Fatalf("unexpected event %v", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func Test_ButtonGesturesWithFakePin(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := NewFakePin()
+	b := NewButtonImpWithConfig(p, ButtonConfig{ActiveLow: true, Pull: PullUpMode})
+	assert.Equal(t, PullUpMode, p.PullMode())
+	assert.False(t, b.Pressed())
+
+	// the press is held for a few polling intervals of the pin
+	cfg := testGestureConfig
+	cfg.DoubleClickWindow = 0
+	cfg.LongPressTime = 500 * time.Millisecond
+	cfg.Debounce = 5 * time.Millisecond
+	out := WatchGestures(ctx, b, cfg)
+	p.Set(Low)
+	assert.True(t, b.Pressed())
+	time.Sleep(10 * EdgePollInterval)
+	p.Set(High)
+	assert.Equal(t, Click, recvGesture(t, out).Gesture)
+
+	stick := NewFakePin()
+	stick.Set(High)
+	z := DetectorFunc(func() bool { return stick.Read() == Low })
+	out = WatchGestures(ctx, AsWatcher(z), cfg)
+	stick.Set(Low)
+	assert.Equal(t, LongPress, recvGesture(t, out).Gesture)
+}
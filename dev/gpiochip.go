@@ -0,0 +1,351 @@
+/*
+GpioChip is a pin backend using the linux gpio character device (gpio uapi v2), e.g. /dev/gpiochip0.
+Unlike the default rpio backend, it doesn't map /dev/mem or /dev/gpiomem, so:
+  - it works for any user in the "gpio" group without root privileges
+  - it works on Raspberry Pi 5 whose gpios are driven by the RP1 chip
+  - edges are detected by the kernel with timestamps, and can be debounced by the kernel
+
+Please NOTE that hardware pwm isn't supported by the gpio character device, pwm falls back to SoftPWM.
+
+Use it by setting the pin factory before creating any driver:
+
+	chip, err := dev.OpenGpioChip("/dev/gpiochip0")
+	if err != nil {
+		...
+	}
+	defer chip.Close()
+	dev.SetPinFactory(chip.Pin)
+
+	led := dev.NewLedImp(26)
+
+Or just call dev.UseGpioChip("/dev/gpiochip0"), and close the returned chip when all drivers are done.
+
+The header gpios are on /dev/gpiochip0 on all Raspberry Pi models except
+Raspberry Pi 5 with old kernels (< 6.6.45), where they are on /dev/gpiochip4.
+*/
+package dev
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	gpioConsumer = "rpi-devices"
+)
+
+// line flags of gpio uapi v2
+const (
+	gpioLineFlagInput        uint64 = 1 << 2
+	gpioLineFlagOutput       uint64 = 1 << 3
+	gpioLineFlagEdgeRising   uint64 = 1 << 4
+	gpioLineFlagEdgeFalling  uint64 = 1 << 5
+	gpioLineFlagBiasPullUp   uint64 = 1 << 8
+	gpioLineFlagBiasPullDown uint64 = 1 << 9
+	gpioLineFlagBiasDisabled uint64 = 1 << 10
+)
+
+// gpioLineConfig is the config of a requested line
+type gpioLineConfig struct {
+	flags    uint64
+	debounce time.Duration
+	value    LogicLevel
+}
+
+// gpioChipDevice is the gpio character device,
+// it is the ioctl based device on linux, or an in-process stand-in in tests.
+type gpioChipDevice interface {
+	requestLine(offset uint32, cfg gpioLineConfig) (gpioLine, error)
+	close() error
+}
+
+// gpioLine is a line requested from gpioChipDevice
+type gpioLine interface {
+	setConfig(cfg gpioLineConfig) error
+	value() (LogicLevel, error)
+	setValue(v LogicLevel) error
+	// readEvent waits for an edge event in timeout,
+	// returns false if there is no event.
+	readEvent(timeout time.Duration) (EdgeEvent, bool, error)
+	close() error
+}
+
+// GpioChip is a gpio character device, it creates pins using its lines
+type GpioChip struct {
+	mu   sync.Mutex
+	dev  gpioChipDevice
+	pins map[uint8]*ChipPin
+}
+
+// OpenGpioChip opens the gpio character device, e.g. /dev/gpiochip0
+func OpenGpioChip(path string) (*GpioChip, error) {
+	dev, err := openGpioChipDevice(path)
+	if err != nil {
+		return nil, err
+	}
+	return newGpioChip(dev), nil
+}
+
+// UseGpioChip opens the gpio character device and uses it to create pins for all drivers.
+// The caller owns the returned chip and should close it when all drivers are done.
+func UseGpioChip(path string) (*GpioChip, error) {
+	chip, err := OpenGpioChip(path)
+	if err != nil {
+		return nil, err
+	}
+	SetPinFactory(chip.Pin)
+	return chip, nil
+}
+
+func newGpioChip(dev gpioChipDevice) *GpioChip {
+	return &GpioChip{
+		dev:  dev,
+		pins: make(map[uint8]*ChipPin),
+	}
+}
+
+// Pin requests the line n as an input pin.
+// The same pin is returned if the line has been requested.
+// Use Err() of the returned pin to check whether the line was requested successfully.
+func (c *GpioChip) Pin(n uint8) Pin {
+	return c.ChipPin(n)
+}
+
+// ChipPin is as same as Pin, but returns *ChipPin
+func (c *GpioChip) ChipPin(n uint8) *ChipPin {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok := c.pins[n]; ok {
+		return p
+	}
+	p := &ChipPin{mode: InputMode}
+	p.line, p.err = c.dev.requestLine(uint32(n), p.config())
+	c.pins[n] = p
+	return p
+}
+
+// Close releases all lines and closes the device
+func (c *GpioChip) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for n, p := range c.pins {
+		p.close()
+		delete(c.pins, n)
+	}
+	return c.dev.close()
+}
+
+// ChipPin implements Pin interface using a line of gpio character device.
+// Since the methods of Pin don't return errors, the last error can be got by Err().
+type ChipPin struct {
+	mu       sync.Mutex
+	line     gpioLine
+	mode     PinMode
+	bias     uint64
+	edge     Edge
+	debounce time.Duration
+	level    LogicLevel
+	err      error
+	soft     *softPWMChannel
+}
+
+// Err returns the last error
+func (p *ChipPin) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Input ...
+func (p *ChipPin) Input() {
+	p.stopSoftPWM()
+	p.reconfig(func() { p.mode = InputMode })
+}
+
+// Output ...
+func (p *ChipPin) Output() {
+	p.stopSoftPWM()
+	p.reconfig(func() { p.mode = OutputMode })
+}
+
+// Read ...
+func (p *ChipPin) Read() LogicLevel {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.line == nil {
+		return Low
+	}
+	v, err := p.line.value()
+	if err != nil {
+		p.err = err
+		return Low
+	}
+	return v
+}
+
+// Write ...
+func (p *ChipPin) Write(level LogicLevel) {
+	p.stopSoftPWM()
+	p.write(level)
+}
+
+func (p *ChipPin) write(level LogicLevel) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.level = level
+	if p.line == nil || p.mode != OutputMode {
+		// the level will be applied when switching to output mode
+		return
+	}
+	if err := p.line.setValue(level); err != nil {
+		p.err = err
+	}
+}
+
+// High ...
+func (p *ChipPin) High() {
+	p.Write(High)
+}
+
+// Low ...
+func (p *ChipPin) Low() {
+	p.Write(Low)
+}
+
+// PullUp ...
+func (p *ChipPin) PullUp() {
+	p.reconfig(func() { p.bias = gpioLineFlagBiasPullUp })
+}
+
+// PullDown ...
+func (p *ChipPin) PullDown() {
+	p.reconfig(func() { p.bias = gpioLineFlagBiasPullDown })
+}
+
+// PullOff ...
+func (p *ChipPin) PullOff() {
+	p.reconfig(func() { p.bias = gpioLineFlagBiasDisabled })
+}
+
+// Detect ...
+func (p *ChipPin) Detect(edge Edge) {
+	p.reconfig(func() { p.edge = edge })
+	// drop the events detected before
+	p.EdgeDetected()
+}
+
+// EdgeDetected returns true if any edge was detected since last call
+func (p *ChipPin) EdgeDetected() bool {
+	detected := false
+	for {
+		_, ok, err := p.WaitEdge(0)
+		if err != nil || !ok {
+			return detected
+		}
+		detected = true
+	}
+}
+
+// WaitEdge waits for an edge event detected by kernel in timeout,
+// returns false if no edge was detected.
+// Edges must be enabled by Detect() first.
+func (p *ChipPin) WaitEdge(timeout time.Duration) (EdgeEvent, bool, error) {
+	p.mu.Lock()
+	line := p.line
+	p.mu.Unlock()
+	if line == nil {
+		return EdgeEvent{}, false, p.Err()
+	}
+	ev, ok, err := line.readEvent(timeout)
+	if err != nil {
+		p.mu.Lock()
+		p.err = err
+		p.mu.Unlock()
+	}
+	return ev, ok, err
+}
+
+// SetDebounce sets the debounce period applied by kernel on the input pin
+func (p *ChipPin) SetDebounce(d time.Duration) {
+	p.reconfig(func() { p.debounce = d })
+}
+
+// Pwm sets the pin to output mode driven by SoftPWM
+func (p *ChipPin) Pwm() {
+	p.Output()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.soft = defaultSoftPWM().channel(p.write)
+}
+
+// Freq sets the pwm clock of SoftPWM, it is ignored if not in pwm mode
+func (p *ChipPin) Freq(freq int) {
+	if soft := p.softPWM(); soft != nil {
+		soft.freq(freq)
+	}
+}
+
+// DutyCycle sets the duty cycle of SoftPWM, it is ignored if not in pwm mode
+func (p *ChipPin) DutyCycle(dutyLen, cycleLen uint32) {
+	if soft := p.softPWM(); soft != nil {
+		soft.dutyCycle(dutyLen, cycleLen)
+	}
+}
+
+func (p *ChipPin) softPWM() *softPWMChannel {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.soft
+}
+
+func (p *ChipPin) stopSoftPWM() {
+	p.mu.Lock()
+	soft := p.soft
+	p.soft = nil
+	p.mu.Unlock()
+	if soft != nil {
+		soft.stop()
+	}
+}
+
+func (p *ChipPin) reconfig(change func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	change()
+	if p.line == nil {
+		return
+	}
+	if err := p.line.setConfig(p.config()); err != nil {
+		p.err = err
+	}
+}
+
+func (p *ChipPin) config() gpioLineConfig {
+	flags := p.bias
+	if p.mode == OutputMode {
+		flags |= gpioLineFlagOutput
+	} else {
+		flags |= gpioLineFlagInput
+		if p.edge == RiseEdge || p.edge == AnyEdge {
+			flags |= gpioLineFlagEdgeRising
+		}
+		if p.edge == FallEdge || p.edge == AnyEdge {
+			flags |= gpioLineFlagEdgeFalling
+		}
+	}
+	return gpioLineConfig{
+		flags:    flags,
+		debounce: p.debounce,
+		value:    p.level,
+	}
+}
+
+func (p *ChipPin) close() {
+	p.stopSoftPWM()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.line != nil {
+		_ = p.line.close()
+		p.line = nil
+	}
+}
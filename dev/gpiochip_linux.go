@@ -0,0 +1,206 @@
+//go:build linux
+// +build linux
+
+package dev
+
+import (
+	"os"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// structs and ioctls of gpio uapi v2, see include/uapi/linux/gpio.h in linux kernel
+const (
+	gpioV2LinesMax        = 64
+	gpioV2LineNumAttrsMax = 10
+	gpioMaxNameSize       = 32
+
+	gpioV2LineAttrIDOutputValues = 2
+	gpioV2LineAttrIDDebounce     = 3
+
+	gpioV2LineEventRisingEdge  = 1
+	gpioV2LineEventFallingEdge = 2
+)
+
+type gpioV2LineAttribute struct {
+	id      uint32
+	padding uint32
+	// flags, values or debounce_period_us
+	value uint64
+}
+
+type gpioV2LineConfigAttribute struct {
+	attr gpioV2LineAttribute
+	mask uint64
+}
+
+type gpioV2LineConfig struct {
+	flags    uint64
+	numAttrs uint32
+	padding  [5]uint32
+	attrs    [gpioV2LineNumAttrsMax]gpioV2LineConfigAttribute
+}
+
+type gpioV2LineRequest struct {
+	offsets         [gpioV2LinesMax]uint32
+	consumer        [gpioMaxNameSize]byte
+	config          gpioV2LineConfig
+	numLines        uint32
+	eventBufferSize uint32
+	padding         [5]uint32
+	fd              int32
+}
+
+type gpioV2LineValues struct {
+	bits uint64
+	mask uint64
+}
+
+type gpioV2LineEvent struct {
+	timestampNs uint64
+	id          uint32
+	offset      uint32
+	seqno       uint32
+	lineSeqno   uint32
+	padding     [6]uint32
+}
+
+func gpioIOWR(nr, size uintptr) uintptr {
+	return (3 << 30) | (size << 16) | (0xB4 << 8) | nr
+}
+
+var (
+	gpioV2GetLineIoctl       = gpioIOWR(0x07, unsafe.Sizeof(gpioV2LineRequest{}))
+	gpioV2LineSetConfigIoctl = gpioIOWR(0x0D, unsafe.Sizeof(gpioV2LineConfig{}))
+	gpioV2LineGetValuesIoctl = gpioIOWR(0x0E, unsafe.Sizeof(gpioV2LineValues{}))
+	gpioV2LineSetValuesIoctl = gpioIOWR(0x0F, unsafe.Sizeof(gpioV2LineValues{}))
+	gpioV2LineEventSize      = int(unsafe.Sizeof(gpioV2LineEvent{}))
+)
+
+func gpioIoctl(fd uintptr, req uintptr, arg unsafe.Pointer) error {
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, fd, req, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+type cdevGpioChip struct {
+	f *os.File
+}
+
+func openGpioChipDevice(path string) (gpioChipDevice, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &cdevGpioChip{f: f}, nil
+}
+
+func (c *cdevGpioChip) requestLine(offset uint32, cfg gpioLineConfig) (gpioLine, error) {
+	var req gpioV2LineRequest
+	req.offsets[0] = offset
+	req.numLines = 1
+	copy(req.consumer[:], gpioConsumer)
+	req.config = toGpioV2LineConfig(cfg)
+	if err := gpioIoctl(c.f.Fd(), gpioV2GetLineIoctl, unsafe.Pointer(&req)); err != nil {
+		return nil, err
+	}
+	return &cdevGpioLine{fd: int(req.fd)}, nil
+}
+
+func (c *cdevGpioChip) close() error {
+	return c.f.Close()
+}
+
+type cdevGpioLine struct {
+	fd int
+}
+
+func (l *cdevGpioLine) setConfig(cfg gpioLineConfig) error {
+	c := toGpioV2LineConfig(cfg)
+	return gpioIoctl(uintptr(l.fd), gpioV2LineSetConfigIoctl, unsafe.Pointer(&c))
+}
+
+func (l *cdevGpioLine) value() (LogicLevel, error) {
+	v := gpioV2LineValues{mask: 1}
+	if err := gpioIoctl(uintptr(l.fd), gpioV2LineGetValuesIoctl, unsafe.Pointer(&v)); err != nil {
+		return Low, err
+	}
+	return LogicLevel(v.bits & 1), nil
+}
+
+func (l *cdevGpioLine) setValue(level LogicLevel) error {
+	v := gpioV2LineValues{bits: uint64(level) & 1, mask: 1}
+	return gpioIoctl(uintptr(l.fd), gpioV2LineSetValuesIoctl, unsafe.Pointer(&v))
+}
+
+func (l *cdevGpioLine) readEvent(timeout time.Duration) (EdgeEvent, bool, error) {
+	fds := []unix.PollFd{{Fd: int32(l.fd), Events: unix.POLLIN}}
+	ms := int(timeout / time.Millisecond)
+	if timeout < 0 {
+		ms = -1
+	}
+	for {
+		n, err := unix.Poll(fds, ms)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return EdgeEvent{}, false, err
+		}
+		if n == 0 {
+			return EdgeEvent{}, false, nil
+		}
+		break
+	}
+
+	var ev gpioV2LineEvent
+	buf := (*[1 << 10]byte)(unsafe.Pointer(&ev))[:gpioV2LineEventSize:gpioV2LineEventSize]
+	if _, err := unix.Read(l.fd, buf); err != nil {
+		return EdgeEvent{}, false, err
+	}
+	edge := RiseEdge
+	if ev.id == gpioV2LineEventFallingEdge {
+		edge = FallEdge
+	}
+	return EdgeEvent{
+		Edge: edge,
+		Time: monotonicToTime(ev.timestampNs),
+	}, true, nil
+}
+
+func (l *cdevGpioLine) close() error {
+	return unix.Close(l.fd)
+}
+
+func toGpioV2LineConfig(cfg gpioLineConfig) gpioV2LineConfig {
+	c := gpioV2LineConfig{flags: cfg.flags}
+	if cfg.flags&gpioLineFlagOutput != 0 {
+		c.attrs[c.numAttrs] = gpioV2LineConfigAttribute{
+			attr: gpioV2LineAttribute{id: gpioV2LineAttrIDOutputValues, value: uint64(cfg.value) & 1},
+			mask: 1,
+		}
+		c.numAttrs++
+	}
+	if cfg.flags&gpioLineFlagInput != 0 && cfg.debounce > 0 {
+		c.attrs[c.numAttrs] = gpioV2LineConfigAttribute{
+			attr: gpioV2LineAttribute{id: gpioV2LineAttrIDDebounce, value: uint64(cfg.debounce / time.Microsecond)},
+			mask: 1,
+		}
+		c.numAttrs++
+	}
+	return c
+}
+
+// monotonicToTime converts the CLOCK_MONOTONIC timestamp of an event to time.Time
+func monotonicToTime(ns uint64) time.Time {
+	var ts unix.Timespec
+	now := time.Now()
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+		return now
+	}
+	return now.Add(-time.Duration(ts.Nano() - int64(ns)))
+}
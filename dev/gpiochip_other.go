@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package dev
+
+import (
+	"errors"
+)
+
+func openGpioChipDevice(path string) (gpioChipDevice, error) {
+	return nil, errors.New("gpio character device is only supported on linux")
+}
@@ -0,0 +1,178 @@
+package dev
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGpioChipDevice is an in-process stand-in of the gpio character device
+type fakeGpioChipDevice struct {
+	mu    sync.Mutex
+	lines map[uint32]*fakeGpioLine
+}
+
+func newFakeGpioChipDevice() *fakeGpioChipDevice {
+	return &fakeGpioChipDevice{lines: make(map[uint32]*fakeGpioLine)}
+}
+
+func (c *fakeGpioChipDevice) requestLine(offset uint32, cfg gpioLineConfig) (gpioLine, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.lines[offset]; ok {
+		return nil, errors.New("device or resource busy")
+	}
+	l := &fakeGpioLine{cfg: cfg, events: make(chan EdgeEvent, 16)}
+	c.lines[offset] = l
+	return l, nil
+}
+
+func (c *fakeGpioChipDevice) close() error {
+	return nil
+}
+
+type fakeGpioLine struct {
+	mu     sync.Mutex
+	cfg    gpioLineConfig
+	level  LogicLevel
+	events chan EdgeEvent
+	closed bool
+}
+
+// drive simulates an outside device driving the input line
+func (l *fakeGpioLine) drive(level LogicLevel) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if level == l.level {
+		return
+	}
+	l.level = level
+	if level == High && l.cfg.flags&gpioLineFlagEdgeRising != 0 {
+		l.events <- EdgeEvent{Edge: RiseEdge, Time: time.Now()}
+	}
+	if level == Low && l.cfg.flags&gpioLineFlagEdgeFalling != 0 {
+		l.events <- EdgeEvent{Edge: FallEdge, Time: time.Now()}
+	}
+}
+
+func (l *fakeGpioLine) config() gpioLineConfig {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.cfg
+}
+
+func (l *fakeGpioLine) setConfig(cfg gpioLineConfig) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cfg = cfg
+	if cfg.flags&gpioLineFlagOutput != 0 {
+		l.level = cfg.value
+	}
+	return nil
+}
+
+func (l *fakeGpioLine) value() (LogicLevel, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.level, nil
+}
+
+func (l *fakeGpioLine) setValue(v LogicLevel) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.cfg.flags&gpioLineFlagOutput == 0 {
+		return errors.New("operation not permitted")
+	}
+	l.level = v
+	return nil
+}
+
+func (l *fakeGpioLine) readEvent(timeout time.Duration) (EdgeEvent, bool, error) {
+	select {
+	case ev := <-l.events:
+		return ev, true, nil
+	default:
+	}
+	select {
+	case ev := <-l.events:
+		return ev, true, nil
+	case <-time.After(timeout):
+		return EdgeEvent{}, false, nil
+	}
+}
+
+func (l *fakeGpioLine) close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	return nil
+}
+
+func Test_GpioChipPinConfig(t *testing.T) {
+	fake := newFakeGpioChipDevice()
+	chip := newGpioChip(fake)
+
+	p := chip.ChipPin(17)
+	assert.NoError(t, p.Err())
+	assert.Equal(t, p, chip.Pin(17))
+	line := fake.lines[17]
+	assert.Equal(t, gpioLineFlagInput, line.config().flags)
+
+	p.PullUp()
+	p.Detect(FallEdge)
+	p.SetDebounce(5 * time.Millisecond)
+	cfg := line.config()
+	assert.Equal(t, gpioLineFlagInput|gpioLineFlagBiasPullUp|gpioLineFlagEdgeFalling, cfg.flags)
+	assert.Equal(t, 5*time.Millisecond, cfg.debounce)
+
+	p.High()
+	p.Output()
+	assert.Equal(t, gpioLineFlagOutput|gpioLineFlagBiasPullUp, line.config().flags)
+	assert.Equal(t, High, p.Read())
+	p.Low()
+	assert.Equal(t, Low, p.Read())
+	assert.NoError(t, p.Err())
+
+	// pwm falls back to SoftPWM, full and zero duty are constant levels
+	p.Pwm()
+	p.Freq(1000)
+	p.DutyCycle(10, 10)
+	assert.Equal(t, High, p.Read())
+	p.DutyCycle(0, 10)
+	assert.Equal(t, Low, p.Read())
+	assert.NoError(t, p.Err())
+
+	assert.NoError(t, chip.Close())
+	assert.True(t, line.closed)
+}
+
+func Test_GpioChipPinEdges(t *testing.T) {
+	fake := newFakeGpioChipDevice()
+	chip := newGpioChip(fake)
+	defer chip.Close()
+
+	b := NewButtonImpWithPin(chip.Pin(5))
+	p := chip.ChipPin(5)
+	line := fake.lines[5]
+
+	p.Detect(RiseEdge)
+	assert.False(t, p.EdgeDetected())
+	line.drive(High)
+	assert.True(t, b.Pressed())
+	assert.True(t, p.EdgeDetected())
+	assert.False(t, p.EdgeDetected())
+
+	p.Detect(AnyEdge)
+	line.drive(Low)
+	ev, ok, err := p.WaitEdge(time.Second)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, FallEdge, ev.Edge)
+
+	_, ok, err = p.WaitEdge(time.Millisecond)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
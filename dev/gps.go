@@ -0,0 +1,58 @@
+package dev
+
+import (
+	"time"
+
+	"github.com/shanghuiyang/rpi-devices/geo"
+)
+
+// FixType is the type of a GPS fix
+type FixType int
+
+// Fix types
+const (
+	FixNone FixType = iota
+	Fix2D
+	Fix3D
+)
+
+// String ...
+func (t FixType) String() string {
+	switch t {
+	case Fix2D:
+		return "2D"
+	case Fix3D:
+		return "3D"
+	}
+	return "none"
+}
+
+// Fix is a position reported by a GPS
+type Fix struct {
+	// Time is the UTC time of the fix, it is zero if the GPS hasn't reported the date yet
+	Time time.Time
+	// Lat and Lon are in degrees, negative for south and west
+	Lat float64
+	Lon float64
+	// Alt is the altitude above mean sea level in meters
+	Alt float64
+	// Speed is the ground speed in m/s
+	Speed float64
+	// Course is the course over ground in degrees from true north
+	Course float64
+	// Satellites is the number of satellites used for the fix
+	Satellites int
+	// HDOP is the horizontal dilution of precision, lower is better
+	HDOP float64
+	Type FixType
+}
+
+// Valid returns true if the fix has a position
+func (f Fix) Valid() bool {
+	return f.Type != FixNone
+}
+
+// Point ...
+func (f Fix) Point() geo.Point {
+	return geo.Point{Lat: f.Lat, Lon: f.Lon}
+}
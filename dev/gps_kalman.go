@@ -0,0 +1,253 @@
+/*
+KalmanGPS smooths the fixes of a GPS using a constant velocity Kalman filter.
+
+The position is filtered on a local plane around the first fix, east and north are two
+independent axes with state [position, velocity]. HDOP x UERE is used as the standard
+deviation of the measured position, and fixes too far from the prediction are rejected as outliers.
+The velocity can be measured by the speed over ground of the fixes, or by wheel encoders using UpdateVelocity().
+
+Usage:
+
+	gps, _ := dev.NewNeo6mGPS("/dev/ttyAMA0", 9600)
+	kf := dev.NewKalmanGPS(gps, dev.KalmanConfig{})
+	lat, lon, err := kf.Loc()
+*/
+package dev
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// KalmanConfig ...
+type KalmanConfig struct {
+	// UERE is the user equivalent range error in meters, the position error is HDOP x UERE, default 5
+	UERE float64
+	// Accel is the standard deviation of the acceleration in m/s^2, how fast the velocity changes, default 1
+	Accel float64
+	// OutlierSigma rejects fixes whose distance to the prediction is larger than this many standard deviations, default 5.
+	// After MaxRejects rejections in a row, the filter restarts at the fix, default 5.
+	OutlierSigma float64
+	MaxRejects   int
+	// UseFixVelocity uses the speed and course of the fixes as velocity measurements
+	UseFixVelocity bool
+	// VelocityNoise is the standard deviation of velocity measurements in m/s, default 0.5
+	VelocityNoise float64
+}
+
+// KalmanGPS implements FixGPS interface
+type KalmanGPS struct {
+	gps FixGPS
+	cfg KalmanConfig
+
+	mu      sync.Mutex
+	started bool
+	origin  Fix
+	last    time.Time
+	x, y    kalmanAxis // east and north
+	rejects int
+}
+
+// kalmanAxis is a constant velocity Kalman filter on one axis
+type kalmanAxis struct {
+	pos, vel float64
+	// covariance [[ppp, ppv], [ppv, pvv]]
+	ppp, ppv, pvv float64
+}
+
+// NewKalmanGPS ...
+func NewKalmanGPS(gps FixGPS, cfg KalmanConfig) *KalmanGPS {
+	if cfg.UERE <= 0 {
+		cfg.UERE = 5
+	}
+	if cfg.Accel <= 0 {
+		cfg.Accel = 1
+	}
+	if cfg.OutlierSigma <= 0 {
+		cfg.OutlierSigma = 5
+	}
+	if cfg.MaxRejects <= 0 {
+		cfg.MaxRejects = 5
+	}
+	if cfg.VelocityNoise <= 0 {
+		cfg.VelocityNoise = 0.5
+	}
+	return &KalmanGPS{
+		gps: gps,
+		cfg: cfg,
+	}
+}
+
+// Loc ...
+func (k *KalmanGPS) Loc() (lat, lon float64, err error) {
+	fix, err := k.Fix()
+	if err != nil {
+		return 0, 0, err
+	}
+	if !fix.Valid() {
+		return 0, 0, errInvalidFix
+	}
+	return fix.Lat, fix.Lon, nil
+}
+
+// Fix returns the filtered fix. Invalid fixes are returned as is.
+func (k *KalmanGPS) Fix() (Fix, error) {
+	fix, err := k.gps.Fix()
+	if err != nil || !fix.Valid() {
+		return fix, err
+	}
+	return k.Update(fix), nil
+}
+
+// Update filters a fix. It is used by Fix(), and can be used to filter fixes from other sources.
+// The prediction is returned if the fix is rejected as an outlier.
+func (k *KalmanGPS) Update(fix Fix) Fix {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	t := fix.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	if !k.started {
+		k.reset(fix, t)
+		return k.output(fix)
+	}
+
+	if dt := t.Sub(k.last).Seconds(); dt > 0 {
+		k.x.predict(dt, k.cfg.Accel)
+		k.y.predict(dt, k.cfg.Accel)
+		k.last = t
+	}
+
+	r := k.variance(fix)
+	ex, ny := k.project(fix)
+	dx, sx := ex-k.x.pos, k.x.ppp+r
+	dy, sy := ny-k.y.pos, k.y.ppp+r
+	// mahalanobis distance of the measurement to the prediction
+	if d := math.Sqrt(dx*dx/sx + dy*dy/sy); d > k.cfg.OutlierSigma {
+		k.rejects++
+		if k.rejects < k.cfg.MaxRejects {
+			return k.output(fix)
+		}
+		// the prediction went wrong, restart at the fix
+		k.reset(fix, t)
+		return k.output(fix)
+	}
+	k.rejects = 0
+
+	k.x.updatePos(ex, r)
+	k.y.updatePos(ny, r)
+	if k.cfg.UseFixVelocity {
+		k.updateVelocity(fix.Speed, fix.Course)
+	}
+	return k.output(fix)
+}
+
+// UpdateVelocity feeds a velocity measurement, e.g. the speed from wheel encoders and the course from a compass.
+// speed is in m/s, and course is in degrees from true north.
+func (k *KalmanGPS) UpdateVelocity(speed, course float64) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if !k.started {
+		return
+	}
+	k.updateVelocity(speed, course)
+}
+
+// Reset restarts the filter from the next fix
+func (k *KalmanGPS) Reset() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.started = false
+}
+
+// Close closes the GPS
+func (k *KalmanGPS) Close() error {
+	return k.gps.Close()
+}
+
+func (k *KalmanGPS) reset(fix Fix, t time.Time) {
+	r := k.variance(fix)
+	// unknown velocity
+	v := 10.0 * 10.0
+	k.origin = fix
+	k.last = t
+	k.x = kalmanAxis{ppp: r, pvv: v}
+	k.y = kalmanAxis{ppp: r, pvv: v}
+	k.rejects = 0
+	k.started = true
+	if k.cfg.UseFixVelocity {
+		k.updateVelocity(fix.Speed, fix.Course)
+	}
+}
+
+// variance returns the variance of the position of the fix
+func (k *KalmanGPS) variance(fix Fix) float64 {
+	hdop := fix.HDOP
+	if hdop <= 0 {
+		hdop = 1
+	}
+	return (hdop * k.cfg.UERE) * (hdop * k.cfg.UERE)
+}
+
+func (k *KalmanGPS) updateVelocity(speed, course float64) {
+	rad := course * math.Pi / 180
+	q := k.cfg.VelocityNoise * k.cfg.VelocityNoise
+	k.x.updateVel(speed*math.Sin(rad), q)
+	k.y.updateVel(speed*math.Cos(rad), q)
+}
+
+// project returns the position of the fix on the local plane, east and north in meters from the origin
+func (k *KalmanGPS) project(fix Fix) (east, north float64) {
+	east = (fix.Lon - k.origin.Lon) * metersPerDegree * math.Cos(k.origin.Lat*math.Pi/180)
+	north = (fix.Lat - k.origin.Lat) * metersPerDegree
+	return east, north
+}
+
+// output returns the fix with the filtered position and velocity
+func (k *KalmanGPS) output(fix Fix) Fix {
+	fix.Lat = k.origin.Lat + k.y.pos/metersPerDegree
+	fix.Lon = k.origin.Lon + k.x.pos/(metersPerDegree*math.Cos(k.origin.Lat*math.Pi/180))
+	fix.Speed = math.Hypot(k.x.vel, k.y.vel)
+	fix.Course = math.Mod(math.Atan2(k.x.vel, k.y.vel)*180/math.Pi+360, 360)
+	return fix
+}
+
+// predict moves the state forward dt seconds, with the process noise of white acceleration
+func (a *kalmanAxis) predict(dt, accel float64) {
+	q := accel * accel
+	a.pos += a.vel * dt
+	ppp := a.ppp + 2*dt*a.ppv + dt*dt*a.pvv
+	ppv := a.ppv + dt*a.pvv
+	a.ppp = ppp + q*dt*dt*dt*dt/4
+	a.ppv = ppv + q*dt*dt*dt/2
+	a.pvv += q * dt * dt
+}
+
+// updatePos updates the state with a position measurement of variance r
+func (a *kalmanAxis) updatePos(z, r float64) {
+	s := a.ppp + r
+	kp, kv := a.ppp/s, a.ppv/s
+	y := z - a.pos
+	a.pos += kp * y
+	a.vel += kv * y
+	ppp, ppv, pvv := a.ppp, a.ppv, a.pvv
+	a.ppp = (1 - kp) * ppp
+	a.ppv = (1 - kp) * ppv
+	a.pvv = pvv - kv*ppv
+}
+
+// updateVel updates the state with a velocity measurement of variance r
+func (a *kalmanAxis) updateVel(z, r float64) {
+	s := a.pvv + r
+	kp, kv := a.ppv/s, a.pvv/s
+	y := z - a.vel
+	a.pos += kp * y
+	a.vel += kv * y
+	ppp, ppv, pvv := a.ppp, a.ppv, a.pvv
+	a.ppp = ppp - kp*ppv
+	a.ppv = ppv - kp*pvv
+	a.pvv = (1 - kv) * pvv
+}
@@ -0,0 +1,120 @@
+package dev
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/shanghuiyang/rpi-devices/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingGPS keeps the last raw fix
+type recordingGPS struct {
+	FixGPS
+	last Fix
+}
+
+func (r *recordingGPS) Fix() (Fix, error) {
+	fix, err := r.FixGPS.Fix()
+	r.last = fix
+	return fix, err
+}
+
+// rmsErrors replays the track with noise, and returns the rms errors of the raw and the filtered fixes
+func rmsErrors(t *testing.T, points []TrackPoint, cfg KalmanConfig) (raw, filtered float64) {
+	noisy, err := NewGPSSimulatorWithConfig(points, GPSSimulatorConfig{Interpolate: true, Noise: 5, Seed: 1})
+	assert.NoError(t, err)
+	truth, err := NewGPSSimulatorWithConfig(points, GPSSimulatorConfig{Interpolate: true})
+	assert.NoError(t, err)
+	rec := &recordingGPS{FixGPS: noisy}
+	kf := NewKalmanGPS(rec, cfg)
+
+	n := 0
+	for i := 0; ; i++ {
+		want, err := truth.Fix()
+		if err == ErrEndOfTrack {
+			break
+		}
+		fix, err := kf.Fix()
+		assert.NoError(t, err)
+		assert.Equal(t, want.Time, fix.Time)
+
+		// skip the warm up
+		if i < 10 {
+			continue
+		}
+		d1 := geo.Distance(want.Point(), rec.last.Point())
+		d2 := geo.Distance(want.Point(), fix.Point())
+		raw += d1 * d1
+		filtered += d2 * d2
+		n++
+	}
+	return math.Sqrt(raw / float64(n)), math.Sqrt(filtered / float64(n))
+}
+
+func Test_KalmanGPSStationary(t *testing.T) {
+	start := time.Date(2019, 11, 4, 8, 32, 45, 0, time.UTC)
+	points := []TrackPoint{
+		{Time: start, Lat: 39.958134, Lon: 116.436234},
+		{Time: start.Add(5 * time.Minute), Lat: 39.958134, Lon: 116.436234},
+	}
+	raw, filtered := rmsErrors(t, points, KalmanConfig{})
+	assert.InDelta(t, 7, raw, 1)
+	assert.Less(t, filtered, raw*0.7)
+
+	// less acceleration, smoother
+	raw, filtered = rmsErrors(t, points, KalmanConfig{Accel: 0.1})
+	assert.Less(t, filtered, raw/2)
+}
+
+func Test_KalmanGPSMoving(t *testing.T) {
+	points, err := LoadCSV(testCSV)
+	assert.NoError(t, err)
+	raw, filtered := rmsErrors(t, points[:100], KalmanConfig{})
+	assert.Less(t, filtered, raw*0.7)
+
+	raw, filtered = rmsErrors(t, points[:100], KalmanConfig{UseFixVelocity: true})
+	assert.Less(t, filtered, raw*0.7)
+}
+
+func Test_KalmanGPSOutlier(t *testing.T) {
+	kf := NewKalmanGPS(nil, KalmanConfig{MaxRejects: 3})
+	home := geo.Point{Lat: 39.958134, Lon: 116.436234}
+	far := geo.Destination(home, 90, 500)
+	start := time.Now()
+	fix := func(i int, p geo.Point) Fix {
+		return Fix{Time: start.Add(time.Duration(i) * time.Second), Lat: p.Lat, Lon: p.Lon, HDOP: 1, Type: Fix3D}
+	}
+
+	for i := 0; i < 10; i++ {
+		kf.Update(fix(i, home))
+	}
+	// a jump is rejected
+	got := kf.Update(fix(10, far))
+	assert.Less(t, geo.Distance(home, got.Point()), 1.0)
+	got = kf.Update(fix(11, home))
+	assert.Less(t, geo.Distance(home, got.Point()), 1.0)
+
+	// but not if it lasts
+	kf.Update(fix(12, far))
+	kf.Update(fix(13, far))
+	got = kf.Update(fix(14, far))
+	assert.Less(t, geo.Distance(far, got.Point()), 1.0)
+}
+
+func Test_KalmanGPSVelocity(t *testing.T) {
+	kf := NewKalmanGPS(nil, KalmanConfig{})
+	home := geo.Point{Lat: 39.958134, Lon: 116.436234}
+	start := time.Now()
+	// moving north at 2m/s
+	for i := 0; i < 30; i++ {
+		p := geo.Destination(home, 0, 2*float64(i))
+		kf.UpdateVelocity(2, 0)
+		got := kf.Update(Fix{Time: start.Add(time.Duration(i) * time.Second), Lat: p.Lat, Lon: p.Lon, Type: Fix3D})
+		if i > 5 {
+			assert.InDelta(t, 2, got.Speed, 0.2)
+			assert.InDelta(t, 0, math.Mod(got.Course+180, 360)-180, 5)
+		}
+	}
+}
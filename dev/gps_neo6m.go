@@ -22,16 +22,6 @@ Connect to Raspberry Pi:
 */
 package dev
 
-import (
-	"bufio"
-	"bytes"
-	"fmt"
-	"io"
-	"strings"
-
-	"github.com/tarm/serial"
-)
-
 const (
 	// Recommended Minimum Specific Data from GPS
 	GPRMC = "$GPRMC,"
@@ -39,103 +29,25 @@ const (
 	GNRMC = "$GNRMC,"
 )
 
-var (
-	buf = make([]byte, 1024)
-)
-
-// Neo6mGPS implements GPS interface
+// Neo6mGPS implements FixGPS interface
 type Neo6mGPS struct {
-	port *serial.Port
+	*NMEAGPS
 }
 
 // NewNeo6mGPS ...
 func NewNeo6mGPS(dev string, baud int) (*Neo6mGPS, error) {
-	cfg := &serial.Config{
-		Name: dev,
-		Baud: baud,
-	}
-	port, err := serial.OpenPort(cfg)
+	port, err := OpenSerial(SerialConfig{
+		Dev:         dev,
+		Baud:        baud,
+		ReadTimeout: gpsReadTimeout,
+	})
 	if err != nil {
 		return nil, err
 	}
-	return &Neo6mGPS{port}, nil
-}
-
-// Loc ...
-func (gps *Neo6mGPS) Loc() (lat, lon float64, err error) {
-	if err := gps.port.Flush(); err != nil {
-		return 0, 0, fmt.Errorf("flush port error: %w", err)
-	}
-	a := 0
-	for a < 512 {
-		n, err := gps.port.Read(buf[a:])
-		if err != nil {
-			return 0, 0, fmt.Errorf("read port error: %w", err)
-		}
-		a += n
-	}
-	r := bufio.NewReader(bytes.NewReader(buf[:a]))
-	loc := ""
-	for {
-		line, err := r.ReadString('\n')
-		if err == io.EOF {
-			break
-		}
-		line = strings.Trim(line, " \t\n")
-		if strings.Contains(line, GPRMC) || strings.Contains(line, GNRMC) {
-			loc = line
-			break
-		}
-	}
-
-	if loc == "" {
-		return 0, 0, fmt.Errorf("%v and %v not found", GPRMC, GNRMC)
-	}
-	items := strings.Split(loc, ",")
-	if len(items) < 7 {
-		return 0, 0, fmt.Errorf("invalid data format")
-	}
-
-	var available string
-	if _, err := fmt.Sscanf(items[2], "%s", &available); err != nil {
-		return 0, 0, fmt.Errorf("parse [available] error: %w", err)
-	}
-	if available != "A" {
-		return 0, 0, fmt.Errorf("invalid data")
-	}
-
-	if _, err := fmt.Sscanf(items[3], "%f", &lat); err != nil {
-		return 0, 0, fmt.Errorf("parse [lat] error: %w", err)
-	}
-	northOrSouth := ""
-	if _, err := fmt.Sscanf(items[4], "%s", &northOrSouth); err != nil {
-		return 0, 0, fmt.Errorf("parse [north/south] error: %w", err)
-	}
-	if _, err := fmt.Sscanf(items[5], "%f", &lon); err != nil {
-		return 0, 0, fmt.Errorf("parse [lon] error: %w", err)
-	}
-	eastOrWest := ""
-	if _, err := fmt.Sscanf(items[6], "%s", &eastOrWest); err != nil {
-		return 0, 0, fmt.Errorf("parse [east/west] error: %w", err)
-	}
-	if northOrSouth == "S" {
-		lat = lat * (-1)
-	}
-	if eastOrWest == "W" {
-		lon = lon * (-1)
-	}
-	dd := int(lat / 100)
-	mm := lat - float64(dd*100)
-	lat = float64(dd) + mm/60
-
-	dd = int(lon / 100)
-	mm = lon - float64(dd*100)
-	lon = float64(dd) + mm/60
-
-	return lat, lon, nil
+	return NewNeo6mGPSWithPort(port), nil
 }
 
-// Close ...
-func (gps *Neo6mGPS) Close() error {
-	return gps.port.Close()
+// NewNeo6mGPSWithPort ...
+func NewNeo6mGPSWithPort(port SerialPort) *Neo6mGPS {
+	return &Neo6mGPS{NewNMEAGPS(port)}
 }
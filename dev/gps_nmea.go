@@ -0,0 +1,462 @@
+/*
+NMEAGPS reads fixes from a GPS module outputting NMEA 0183 sentences over a serial port.
+It is shared by GPS drivers like Neo6mGPS and HT1818GPS.
+
+NMEAGPS works in two modes:
+ - one-shot: Loc() and Fix() flush the port and read sentences until a fix is found.
+ - continuous: after Start() or Subscribe(), a goroutine reads the serial stream and
+   Loc() and Fix() return the latest fix immediately. They are safe to call from
+   multiple goroutines.
+
+Usage:
+
+	gps, _ := dev.NewNeo6mGPS("/dev/ttyAMA0", 9600)
+	defer gps.Close()
+	for fix := range gps.Subscribe(ctx) {
+		log.Printf("%v, %v, %v satellites", fix.Lat, fix.Lon, fix.Satellites)
+	}
+*/
+package dev
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/shanghuiyang/rpi-devices/nmea"
+)
+
+const (
+	knotToMps       = 0.514444
+	fixBufSize      = 16
+	maxOneShotBytes = 4096
+	maxSentenceSize = 256
+	gpsReadTimeout  = 2 * time.Second
+	gpsRetryDelay   = 100 * time.Millisecond
+)
+
+// FixTimeout is the max age of the latest fix in continuous mode,
+// Loc() and Fix() return an error if no sentences arrive within it.
+var FixTimeout = 3 * time.Second
+
+var (
+	errNoFix      = errors.New("no fix found")
+	errInvalidFix = errors.New("invalid data")
+	errStaleFix   = errors.New("no data from gps")
+)
+
+// NMEAGPS implements FixGPS interface
+type NMEAGPS struct {
+	port SerialPort
+
+	mu      sync.Mutex
+	running bool
+	fix     Fix
+	updated time.Time
+	err     error
+	subs    map[chan Fix]struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewNMEAGPS ...
+func NewNMEAGPS(port SerialPort) *NMEAGPS {
+	return &NMEAGPS{
+		port: port,
+		subs: map[chan Fix]struct{}{},
+	}
+}
+
+// Loc ...
+func (gps *NMEAGPS) Loc() (lat, lon float64, err error) {
+	if gps.isRunning() {
+		fix, err := gps.latest()
+		if err != nil {
+			return 0, 0, err
+		}
+		if !fix.Valid() {
+			return 0, 0, errInvalidFix
+		}
+		return fix.Lat, fix.Lon, nil
+	}
+
+	r, err := gps.oneShotReader()
+	if err != nil {
+		return 0, 0, err
+	}
+	for {
+		line, err := r.next()
+		if err == io.EOF {
+			return 0, 0, errStaleFix
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		s, err := nmea.Parse(line)
+		if err != nil {
+			continue
+		}
+		rmc, ok := s.(*nmea.RMC)
+		if !ok {
+			continue
+		}
+		if !rmc.Valid() {
+			return 0, 0, fmt.Errorf("%v: %w", rmc.Prefix(), errInvalidFix)
+		}
+		return rmc.Lat, rmc.Lon, nil
+	}
+}
+
+// Fix returns the latest fix in continuous mode, or reads a fix in one-shot mode.
+// An invalid fix is returned without error if the GPS has no position, check it using Fix.Valid().
+func (gps *NMEAGPS) Fix() (Fix, error) {
+	if gps.isRunning() {
+		return gps.latest()
+	}
+
+	r, err := gps.oneShotReader()
+	if err != nil {
+		return Fix{}, err
+	}
+	var a fixAssembler
+	for {
+		line, err := r.next()
+		if err == io.EOF {
+			return Fix{}, errStaleFix
+		}
+		if err != nil {
+			return Fix{}, err
+		}
+		s, err := nmea.Parse(line)
+		if err != nil {
+			continue
+		}
+		if fix, ok := a.add(s); ok {
+			return fix, nil
+		}
+	}
+}
+
+// Start starts reading the serial stream continuously in a goroutine
+func (gps *NMEAGPS) Start() {
+	gps.mu.Lock()
+	defer gps.mu.Unlock()
+	if gps.running {
+		return
+	}
+	gps.running = true
+	gps.fix = Fix{}
+	gps.updated = time.Time{}
+	gps.err = nil
+	gps.stop = make(chan struct{})
+	gps.done = make(chan struct{})
+	go gps.run(gps.stop, gps.done)
+}
+
+// Stop stops the continuous reading, and closes the channels returned by Subscribe()
+func (gps *NMEAGPS) Stop() {
+	gps.mu.Lock()
+	if !gps.running {
+		gps.mu.Unlock()
+		return
+	}
+	done := gps.stopLocked()
+	gps.mu.Unlock()
+	<-done
+}
+
+// Subscribe returns a channel receiving every new fix. It starts the continuous reading if needed.
+// The channel is closed when ctx is done or the GPS is stopped.
+// Fixes are dropped if the receiver falls behind.
+func (gps *NMEAGPS) Subscribe(ctx context.Context) <-chan Fix {
+	ch := make(chan Fix, fixBufSize)
+	gps.Start()
+	gps.mu.Lock()
+	gps.subs[ch] = struct{}{}
+	done := gps.done
+	gps.mu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		gps.unsubscribe(ch)
+	}()
+	return ch
+}
+
+// Close ...
+func (gps *NMEAGPS) Close() error {
+	gps.mu.Lock()
+	if !gps.running {
+		gps.mu.Unlock()
+		return gps.port.Close()
+	}
+	done := gps.stopLocked()
+	gps.mu.Unlock()
+
+	// closing the port unblocks the pending read
+	err := gps.port.Close()
+	<-done
+	return err
+}
+
+// stopLocked signals the reading goroutine to stop only once, and returns the channel closed when it exits.
+// The caller must hold gps.mu.
+func (gps *NMEAGPS) stopLocked() chan struct{} {
+	if gps.stop != nil {
+		close(gps.stop)
+		gps.stop = nil
+	}
+	return gps.done
+}
+
+func (gps *NMEAGPS) isRunning() bool {
+	gps.mu.Lock()
+	defer gps.mu.Unlock()
+	return gps.running
+}
+
+func (gps *NMEAGPS) latest() (Fix, error) {
+	gps.mu.Lock()
+	defer gps.mu.Unlock()
+	if gps.err != nil {
+		return Fix{}, gps.err
+	}
+	if gps.updated.IsZero() {
+		return Fix{}, errNoFix
+	}
+	if time.Since(gps.updated) > FixTimeout {
+		return Fix{}, errStaleFix
+	}
+	return gps.fix, nil
+}
+
+func (gps *NMEAGPS) oneShotReader() (*nmeaReader, error) {
+	if err := gps.port.Flush(); err != nil {
+		return nil, fmt.Errorf("flush port error: %w", err)
+	}
+	return &nmeaReader{port: gps.port, limit: maxOneShotBytes}, nil
+}
+
+func (gps *NMEAGPS) run(stop, done chan struct{}) {
+	defer func() {
+		gps.mu.Lock()
+		gps.running = false
+		for ch := range gps.subs {
+			delete(gps.subs, ch)
+			close(ch)
+		}
+		gps.mu.Unlock()
+		close(done)
+	}()
+
+	r := &nmeaReader{port: gps.port}
+	var a fixAssembler
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		line, err := r.next()
+		if err == io.EOF {
+			// read timeout
+			continue
+		}
+		if err != nil {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			gps.mu.Lock()
+			gps.err = err
+			gps.mu.Unlock()
+			time.Sleep(gpsRetryDelay)
+			continue
+		}
+
+		s, err := nmea.Parse(line)
+		if err != nil {
+			continue
+		}
+		fix, ok := a.add(s)
+
+		gps.mu.Lock()
+		gps.err = nil
+		gps.updated = time.Now()
+		if ok {
+			gps.fix = fix
+			for ch := range gps.subs {
+				select {
+				case ch <- fix:
+				default:
+				}
+			}
+		}
+		gps.mu.Unlock()
+	}
+}
+
+func (gps *NMEAGPS) unsubscribe(ch chan Fix) {
+	gps.mu.Lock()
+	defer gps.mu.Unlock()
+	if _, ok := gps.subs[ch]; ok {
+		delete(gps.subs, ch)
+		close(ch)
+	}
+}
+
+// nmeaReader splits the serial stream into sentences
+type nmeaReader struct {
+	port  SerialPort
+	buf   [256]byte
+	data  []byte
+	read  int
+	limit int // max bytes to read, no limit if 0
+}
+
+// next returns the next line, it returns io.EOF on read timeout
+func (r *nmeaReader) next() (string, error) {
+	for {
+		if i := bytes.IndexByte(r.data, '\n'); i >= 0 {
+			line := string(bytes.TrimSpace(r.data[:i]))
+			r.data = r.data[i+1:]
+			return line, nil
+		}
+		if len(r.data) > maxSentenceSize {
+			// garbage without line breaks
+			r.data = r.data[:0]
+		}
+		if r.limit > 0 && r.read >= r.limit {
+			return "", errNoFix
+		}
+		n, err := r.port.Read(r.buf[:])
+		if err != nil {
+			if err == io.EOF {
+				return "", err
+			}
+			return "", fmt.Errorf("read port error: %w", err)
+		}
+		r.read += n
+		r.data = append(r.data, r.buf[:n]...)
+	}
+}
+
+// fixAssembler merges the sentences of an epoch, which share the same UTC time, into a fix.
+// The fix of an epoch is complete when both RMC and GGA arrive, or when the next epoch begins.
+type fixAssembler struct {
+	fix       Fix
+	tod       nmea.Time
+	date      nmea.Date
+	seen      map[string]bool
+	hasPos    bool
+	valid     bool
+	published bool
+	gsaType   int
+}
+
+func (a *fixAssembler) add(s nmea.Sentence) (fix Fix, ok bool) {
+	if a.seen == nil {
+		a.reset(nmea.Time{})
+	}
+
+	switch v := s.(type) {
+	case *nmea.RMC:
+		fix, ok = a.begin(v.DataType(), v.Time)
+		if v.Date.Valid {
+			a.date = v.Date
+		}
+		a.position(v.Lat, v.Lon, v.Valid())
+		a.fix.Speed = v.Speed * knotToMps
+		a.fix.Course = v.Course
+	case *nmea.GGA:
+		fix, ok = a.begin(v.DataType(), v.Time)
+		a.position(v.Lat, v.Lon, v.Valid())
+		a.fix.Alt = v.Altitude
+		a.fix.Satellites = v.NumSatellites
+		a.fix.HDOP = v.HDOP
+	case *nmea.GLL:
+		fix, ok = a.begin(v.DataType(), v.Time)
+		a.position(v.Lat, v.Lon, v.Valid())
+	case *nmea.GSA:
+		a.gsaType = v.FixType
+		if !a.seen[nmea.TypeGGA] {
+			a.fix.HDOP = v.HDOP
+		}
+	case *nmea.VTG:
+		if !a.seen[nmea.TypeRMC] {
+			a.fix.Speed = v.SpeedKnots * knotToMps
+			a.fix.Course = v.TrueCourse
+		}
+	case *nmea.ZDA:
+		if v.Date.Valid {
+			a.date = v.Date
+		}
+	}
+	if ok {
+		return fix, true
+	}
+
+	if a.seen[nmea.TypeRMC] && a.seen[nmea.TypeGGA] && !a.published {
+		a.published = true
+		return a.build(), true
+	}
+	return Fix{}, false
+}
+
+// begin starts a new epoch if the time changes or the sentence type repeats,
+// and returns the fix of the previous epoch if it hasn't been published.
+func (a *fixAssembler) begin(typ string, tod nmea.Time) (fix Fix, ok bool) {
+	if tod != a.tod || a.seen[typ] {
+		if a.hasPos && !a.published {
+			fix, ok = a.build(), true
+		}
+		a.reset(tod)
+	}
+	a.seen[typ] = true
+	return fix, ok
+}
+
+func (a *fixAssembler) reset(tod nmea.Time) {
+	a.fix = Fix{}
+	a.tod = tod
+	a.seen = map[string]bool{}
+	a.hasPos = false
+	a.valid = true
+	a.published = false
+}
+
+func (a *fixAssembler) position(lat, lon float64, valid bool) {
+	a.valid = a.valid && valid
+	a.hasPos = true
+	if valid {
+		a.fix.Lat = lat
+		a.fix.Lon = lon
+	}
+}
+
+func (a *fixAssembler) build() Fix {
+	fix := a.fix
+	fix.Time = nmea.DateTime(a.date, a.tod)
+	switch {
+	case !a.hasPos || !a.valid:
+		fix.Type = FixNone
+	case a.gsaType == nmea.FixType3D:
+		fix.Type = Fix3D
+	case a.gsaType == nmea.FixType2D:
+		fix.Type = Fix2D
+	case fix.Satellites >= 4:
+		fix.Type = Fix3D
+	default:
+		fix.Type = Fix2D
+	}
+	return fix
+}
@@ -0,0 +1,153 @@
+/*
+TrackRecorder records the track of a GPS into GPX and/or CSV files.
+
+Usage:
+
+	gps, _ := dev.NewHT1818GPS("/dev/ttyAMA0", 9600)
+	r, err := dev.NewTrackRecorder(gps, dev.TrackRecorderConfig{
+		Interval:    5 * time.Second,
+		MinDistance: 10,
+		GPXFile:     "trip.gpx",
+		CSVFile:     "trip.csv",
+	})
+	...
+	r.Run(ctx)
+*/
+package dev
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/shanghuiyang/rpi-devices/geo"
+)
+
+// TrackRecorderConfig ...
+type TrackRecorderConfig struct {
+	// Interval is the sampling interval, default 1s
+	Interval time.Duration
+	// MinDistance in meters skips the points closer than it to the last recorded point, 0 records all points
+	MinDistance float64
+	// GPXFile and CSVFile are the output files, at least one of them is required
+	GPXFile string
+	CSVFile string
+	// Name is the name of the GPX track
+	Name string
+}
+
+// TrackRecorder ...
+type TrackRecorder struct {
+	gps  FixGPS
+	cfg  TrackRecorderConfig
+	gpx  *GPXWriter
+	csv  *CSVWriter
+	last *TrackPoint
+	lost bool
+}
+
+// NewTrackRecorder creates the output files
+func NewTrackRecorder(gps FixGPS, cfg TrackRecorderConfig) (*TrackRecorder, error) {
+	if cfg.GPXFile == "" && cfg.CSVFile == "" {
+		return nil, errors.New("no output file")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	r := &TrackRecorder{
+		gps: gps,
+		cfg: cfg,
+	}
+	if cfg.GPXFile != "" {
+		gpx, err := CreateGPX(cfg.GPXFile, cfg.Name)
+		if err != nil {
+			return nil, err
+		}
+		r.gpx = gpx
+	}
+	if cfg.CSVFile != "" {
+		csv, err := CreateCSV(cfg.CSVFile)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		r.csv = csv
+	}
+	return r, nil
+}
+
+// Run samples the GPS every interval until ctx is done, it returns the first write error
+func (r *TrackRecorder) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		fix, err := r.gps.Fix()
+		if err != nil {
+			fix = Fix{}
+		}
+		if err := r.Record(fix); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Record records a fix. An invalid fix ends the current segment, and the next valid fix starts a new one.
+func (r *TrackRecorder) Record(fix Fix) error {
+	if !fix.Valid() {
+		r.lost = true
+		return nil
+	}
+
+	p := TrackPoint{
+		Time: fix.Time,
+		Lat:  fix.Lat,
+		Lon:  fix.Lon,
+		Ele:  fix.Alt,
+	}
+	if p.Time.IsZero() {
+		p.Time = time.Now().UTC()
+	}
+	if r.last != nil && !r.lost && r.cfg.MinDistance > 0 &&
+		geo.Distance(r.last.Point(), p.Point()) < r.cfg.MinDistance {
+		return nil
+	}
+
+	if r.gpx != nil {
+		if r.lost {
+			r.gpx.NewSegment()
+		}
+		if err := r.gpx.Write(p); err != nil {
+			return err
+		}
+	}
+	if r.csv != nil {
+		if err := r.csv.Write(p); err != nil {
+			return err
+		}
+	}
+	r.last = &p
+	r.lost = false
+	return nil
+}
+
+// Close closes the output files, but not the GPS
+func (r *TrackRecorder) Close() error {
+	var errs []error
+	if r.gpx != nil {
+		errs = append(errs, r.gpx.Close())
+	}
+	if r.csv != nil {
+		errs = append(errs, r.csv.Close())
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
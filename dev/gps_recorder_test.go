@@ -0,0 +1,121 @@
+package dev
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shanghuiyang/rpi-devices/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TrackRecorder(t *testing.T) {
+	dir := t.TempDir()
+	gpxFile := filepath.Join(dir, "track.gpx")
+	csvFile := filepath.Join(dir, "track.csv")
+	points, err := LoadCSV(testCSV)
+	assert.NoError(t, err)
+	points = points[:20]
+
+	r, err := NewTrackRecorder(nil, TrackRecorderConfig{
+		GPXFile: gpxFile,
+		CSVFile: csvFile,
+		Name:    "trip <1>",
+	})
+	assert.NoError(t, err)
+	for i, p := range points {
+		if i == 10 {
+			// fix lost
+			assert.NoError(t, r.Record(Fix{}))
+		}
+		assert.NoError(t, r.Record(Fix{Time: p.Time, Lat: p.Lat, Lon: p.Lon, Type: Fix3D}))
+
+		// the files are readable after every point, as if the power was cut here
+		got, err := LoadGPX(gpxFile)
+		assert.NoError(t, err)
+		assert.Len(t, got, i+1)
+	}
+	assert.NoError(t, r.Close())
+
+	got, err := LoadCSV(csvFile)
+	assert.NoError(t, err)
+	assert.Equal(t, points, got)
+	got, err = LoadGPX(gpxFile)
+	assert.NoError(t, err)
+	assert.Equal(t, points, got)
+
+	data, err := os.ReadFile(gpxFile)
+	assert.NoError(t, err)
+	var gpx struct {
+		Name     string     `xml:"trk>name"`
+		Segments []struct{} `xml:"trk>trkseg"`
+	}
+	assert.NoError(t, xml.Unmarshal(data, &gpx))
+	assert.Equal(t, "trip <1>", gpx.Name)
+	assert.Len(t, gpx.Segments, 2)
+}
+
+func Test_TrackRecorderRun(t *testing.T) {
+	gps, err := NewGPSSimulatorFromCSV(testCSV, GPSSimulatorConfig{})
+	assert.NoError(t, err)
+	csvFile := filepath.Join(t.TempDir(), "track.csv")
+	r, err := NewTrackRecorder(gps, TrackRecorderConfig{
+		Interval:    time.Millisecond,
+		MinDistance: 50,
+		CSVFile:     csvFile,
+	})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.NoError(t, r.Run(ctx))
+	assert.NoError(t, r.Close())
+
+	got, err := LoadCSV(csvFile)
+	assert.NoError(t, err)
+	assert.Greater(t, len(got), 2)
+	for i := 1; i < len(got); i++ {
+		assert.GreaterOrEqual(t, geo.Distance(got[i-1].Point(), got[i].Point()), 50.0)
+	}
+}
+
+func Test_ReadTruncatedGPX(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "track.gpx")
+	points, err := LoadCSV(testCSV)
+	assert.NoError(t, err)
+	points = points[:5]
+
+	w, err := CreateGPX(file, "trip")
+	assert.NoError(t, err)
+	for i, p := range points {
+		if i == 3 {
+			w.NewSegment()
+		}
+		assert.NoError(t, w.Write(p))
+	}
+	assert.NoError(t, w.Close())
+	data, err := os.ReadFile(file)
+	assert.NoError(t, err)
+
+	// cut the file at every byte after the root element, as if the power was cut there
+	root := bytes.Index(data, []byte("<gpx"))
+	root += bytes.IndexByte(data[root:], '>') + 1
+	for n := root; n <= len(data); n++ {
+		got, err := ReadGPX(bytes.NewReader(data[:n]))
+		if !assert.NoError(t, err, "cut at %v", n) {
+			break
+		}
+		complete := strings.Count(string(data[:n]), "</trkpt>")
+		assert.Equal(t, points[:complete], append([]TrackPoint{}, got...), "cut at %v", n)
+	}
+
+	_, err = ReadGPX(strings.NewReader(""))
+	assert.Error(t, err)
+	_, err = ReadGPX(strings.NewReader("<gpx><trk><trkseg></trk></gpx>"))
+	assert.Error(t, err)
+}
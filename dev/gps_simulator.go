@@ -1,44 +1,311 @@
 /*
-GPSSimulator simulates GPS module.
+GPSSimulator simulates GPS module by replaying a track, which can be loaded from GPX or CSV files.
+
+Replay modes:
+ - ReplayStep: every Loc()/Fix() returns the next point of the track.
+ - ReplayRealTime: the track is replayed following the timestamps of its points from the first
+   Loc()/Fix(), and Speed > 1 replays it faster.
+
+Usage:
+
+	gps, err := dev.NewGPSSimulatorFromCSV("dev/test/gps.csv", dev.GPSSimulatorConfig{
+		Mode:        dev.ReplayRealTime,
+		Speed:       10,
+		Interpolate: true,
+		Noise:       3,
+		Dropout:     0.05,
+	})
 */
 
 package dev
 
 import (
 	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shanghuiyang/rpi-devices/geo"
 )
 
-// GPSSimulator implements GPS interface
+// ReplayMode is how GPSSimulator replays a track
+type ReplayMode int
+
+// Replay modes
+const (
+	ReplayStep ReplayMode = iota
+	ReplayRealTime
+)
+
+const (
+	metersPerDegree  = geo.EarthRadius * math.Pi / 180
+	defaultSimHDOP   = 1.0
+	defaultSimSats   = 8
+	untimedPointStep = time.Second
+)
+
+// ErrEndOfTrack is returned by GPSSimulator when the track is over and Loop is false
+var ErrEndOfTrack = errors.New("end of track")
+
+// GPSSimulatorConfig ...
+type GPSSimulatorConfig struct {
+	Mode ReplayMode
+	// Speed is the replay speed in ReplayRealTime mode, e.g. 10 replays 10 times faster, default 1
+	Speed float64
+	// Delay imitates the reading time of a real GPS in every Loc()/Fix() in ReplayStep mode
+	Delay time.Duration
+	// Interpolate interpolates positions between the points of the track.
+	// In ReplayStep mode every step advances the track time by Interval, default 1s.
+	Interpolate bool
+	Interval    time.Duration
+	// Noise is the standard deviation of the gaussian noise added to positions, in meters
+	Noise float64
+	// Dropout is the probability of losing the fix in a reading, 0 ~ 1
+	Dropout float64
+	// Loop restarts the track when it is over, otherwise ErrEndOfTrack is returned
+	Loop bool
+	// Seed seeds the noise and dropouts so the replay is repeatable
+	Seed int64
+}
+
+// GPSSimulator implements FixGPS interface
 type GPSSimulator struct {
-	index   int
-	latlons [][]float64
+	cfg    GPSSimulatorConfig
+	points []TrackPoint
+
+	mu    sync.Mutex
+	rand  *rand.Rand
+	index int
+	step  time.Duration // elapsed track time in ReplayStep mode with interpolation
+	start time.Time     // the first reading in ReplayRealTime mode
 }
 
-// NewGPSSimulator ...
+// NewGPSSimulator creates a simulator stepping through the latlons in loop, one point per second
 func NewGPSSimulator(latlons [][]float64) (*GPSSimulator, error) {
+	points := make([]TrackPoint, 0, len(latlons))
+	for _, latlon := range latlons {
+		if len(latlon) < 2 {
+			return nil, errors.New("invalid lat/lon")
+		}
+		points = append(points, TrackPoint{Lat: latlon[0], Lon: latlon[1]})
+	}
+	return newGPSSimulator(points, GPSSimulatorConfig{
+		Mode:  ReplayStep,
+		Delay: 1000 * time.Millisecond,
+		Loop:  true,
+	}), nil
+}
+
+// NewGPSSimulatorWithConfig ...
+func NewGPSSimulatorWithConfig(points []TrackPoint, cfg GPSSimulatorConfig) (*GPSSimulator, error) {
+	if len(points) == 0 {
+		return nil, errors.New("without data")
+	}
+	return newGPSSimulator(points, cfg), nil
+}
+
+// NewGPSSimulatorFromGPX ...
+func NewGPSSimulatorFromGPX(file string, cfg GPSSimulatorConfig) (*GPSSimulator, error) {
+	points, err := LoadGPX(file)
+	if err != nil {
+		return nil, err
+	}
+	return NewGPSSimulatorWithConfig(points, cfg)
+}
+
+// NewGPSSimulatorFromCSV ...
+func NewGPSSimulatorFromCSV(file string, cfg GPSSimulatorConfig) (*GPSSimulator, error) {
+	points, err := LoadCSV(file)
+	if err != nil {
+		return nil, err
+	}
+	return NewGPSSimulatorWithConfig(points, cfg)
+}
+
+func newGPSSimulator(points []TrackPoint, cfg GPSSimulatorConfig) *GPSSimulator {
+	if cfg.Speed <= 0 {
+		cfg.Speed = 1
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
 	return &GPSSimulator{
-		index:   0,
-		latlons: latlons,
-	}, nil
+		cfg:    cfg,
+		points: normalizeTrack(points),
+		rand:   rand.New(rand.NewSource(cfg.Seed)),
+	}
+}
+
+// normalizeTrack copies the points, and gives untimed points a timestamp one second after the previous one
+func normalizeTrack(points []TrackPoint) []TrackPoint {
+	pts := make([]TrackPoint, len(points))
+	copy(pts, points)
+	untimed := false
+	for _, p := range pts {
+		if p.Time.IsZero() {
+			untimed = true
+			break
+		}
+	}
+	if untimed {
+		t := time.Now().UTC().Truncate(time.Second)
+		for i := range pts {
+			pts[i].Time = t.Add(time.Duration(i) * untimedPointStep)
+		}
+		return pts
+	}
+	sort.SliceStable(pts, func(i, j int) bool {
+		return pts[i].Time.Before(pts[j].Time)
+	})
+	return pts
 }
 
 // Loc ...
 func (gps *GPSSimulator) Loc() (lat, lon float64, err error) {
-	delayMs(1000)
-	n := len(gps.latlons)
-	if n == 0 {
-		return 0, 0, errors.New("without data")
+	fix, err := gps.Fix()
+	if err != nil {
+		return 0, 0, err
 	}
-	if gps.index >= len(gps.latlons) {
-		gps.index = 0
+	if !fix.Valid() {
+		return 0, 0, errInvalidFix
 	}
-	lat = gps.latlons[gps.index][0]
-	lon = gps.latlons[gps.index][1]
-	gps.index++
-	return lat, lon, nil
+	return fix.Lat, fix.Lon, nil
+}
+
+// Fix returns the next fix of the track. A fix with FixNone is returned on dropouts.
+func (gps *GPSSimulator) Fix() (Fix, error) {
+	if gps.cfg.Mode == ReplayStep && gps.cfg.Delay > 0 {
+		time.Sleep(gps.cfg.Delay)
+	}
+
+	gps.mu.Lock()
+	defer gps.mu.Unlock()
+	if len(gps.points) == 0 {
+		return Fix{}, errors.New("without data")
+	}
+
+	t, err := gps.trackTime()
+	if err != nil {
+		return Fix{}, err
+	}
+	fix := gps.fixAt(t)
+	if gps.cfg.Dropout > 0 && gps.rand.Float64() < gps.cfg.Dropout {
+		return Fix{Time: fix.Time}, nil
+	}
+	if gps.cfg.Noise > 0 {
+		fix.Lat += gps.rand.NormFloat64() * gps.cfg.Noise / metersPerDegree
+		fix.Lon += gps.rand.NormFloat64() * gps.cfg.Noise / (metersPerDegree * math.Cos(fix.Lat*math.Pi/180))
+	}
+	return fix, nil
+}
+
+// Reset restarts the replay from the beginning of the track
+func (gps *GPSSimulator) Reset() {
+	gps.mu.Lock()
+	defer gps.mu.Unlock()
+	gps.index = 0
+	gps.step = 0
+	gps.start = time.Time{}
 }
 
 // Close ...
 func (gps *GPSSimulator) Close() error {
 	return nil
 }
+
+// trackTime returns the time on the track of the current reading and advances the replay
+func (gps *GPSSimulator) trackTime() (time.Time, error) {
+	first, last := gps.points[0].Time, gps.points[len(gps.points)-1].Time
+	duration := last.Sub(first)
+
+	if gps.cfg.Mode == ReplayStep && !gps.cfg.Interpolate {
+		if gps.index >= len(gps.points) {
+			if !gps.cfg.Loop {
+				return time.Time{}, ErrEndOfTrack
+			}
+			gps.index = 0
+		}
+		t := gps.points[gps.index].Time
+		gps.index++
+		return t, nil
+	}
+
+	var elapsed time.Duration
+	if gps.cfg.Mode == ReplayStep {
+		elapsed = gps.step
+		gps.step += gps.cfg.Interval
+	} else {
+		now := time.Now()
+		if gps.start.IsZero() {
+			gps.start = now
+		}
+		elapsed = time.Duration(float64(now.Sub(gps.start)) * gps.cfg.Speed)
+	}
+	if elapsed > duration {
+		if !gps.cfg.Loop {
+			return time.Time{}, ErrEndOfTrack
+		}
+		// the track restarts after the last point
+		elapsed %= duration + gps.cfg.Interval
+		if elapsed > duration {
+			elapsed = duration
+		}
+	}
+	return first.Add(elapsed), nil
+}
+
+// fixAt returns the fix on the track at t
+func (gps *GPSSimulator) fixAt(t time.Time) Fix {
+	pts := gps.points
+	// the index of the first point after t
+	i := sort.Search(len(pts), func(i int) bool {
+		return pts[i].Time.After(t)
+	})
+
+	var p, from, to TrackPoint
+	switch {
+	case i == 0:
+		p = pts[0]
+	case i == len(pts):
+		p = pts[i-1]
+	case gps.cfg.Interpolate:
+		a, b := pts[i-1], pts[i]
+		r := float64(t.Sub(a.Time)) / float64(b.Time.Sub(a.Time))
+		p = TrackPoint{
+			Time: t,
+			Lat:  a.Lat + (b.Lat-a.Lat)*r,
+			Lon:  a.Lon + (b.Lon-a.Lon)*r,
+			Ele:  a.Ele + (b.Ele-a.Ele)*r,
+		}
+	default:
+		p = pts[i-1]
+	}
+
+	// speed and course of the segment the point is on
+	switch {
+	case len(pts) < 2:
+		from, to = p, p
+	case i == 0:
+		from, to = pts[0], pts[1]
+	case i == len(pts):
+		from, to = pts[i-2], pts[i-1]
+	default:
+		from, to = pts[i-1], pts[i]
+	}
+	fix := Fix{
+		Time:       p.Time,
+		Lat:        p.Lat,
+		Lon:        p.Lon,
+		Alt:        p.Ele,
+		Satellites: defaultSimSats,
+		HDOP:       defaultSimHDOP,
+		Type:       Fix3D,
+	}
+	if dt := to.Time.Sub(from.Time).Seconds(); dt > 0 {
+		fix.Speed = geo.Distance(from.Point(), to.Point()) / dt
+		fix.Course = geo.Bearing(from.Point(), to.Point())
+	}
+	return fix
+}
@@ -0,0 +1,164 @@
+package dev
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shanghuiyang/rpi-devices/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testCSV = "test/gps.csv"
+	testGPX = "test/gps.gpx"
+)
+
+func Test_LoadTracks(t *testing.T) {
+	points, err := LoadCSV(testCSV)
+	assert.NoError(t, err)
+	assert.Len(t, points, 397)
+	assert.Equal(t, TrackPoint{Time: time.Date(2019, 11, 4, 8, 32, 45, 0, time.UTC), Lat: 39.958134, Lon: 116.436234}, points[0])
+
+	points, err = LoadGPX(testGPX)
+	assert.NoError(t, err)
+	assert.Len(t, points, 10)
+	assert.InDelta(t, 40.386697556823492, points[0].Lat, 1e-12)
+	assert.InDelta(t, 116.6647821944207, points[0].Lon, 1e-12)
+	assert.InDelta(t, 82.8, points[0].Ele, 1e-5)
+	assert.Equal(t, time.Date(2017, 8, 26, 9, 30, 32, 0, time.UTC), points[0].Time)
+
+	_, err = ReadCSV(strings.NewReader("2019-11-04T08:32:45,39.958134\n"))
+	assert.Error(t, err)
+}
+
+func Test_GPSSimulatorStep(t *testing.T) {
+	gps, err := NewGPSSimulatorFromGPX(testGPX, GPSSimulatorConfig{})
+	assert.NoError(t, err)
+	points, _ := LoadGPX(testGPX)
+	for _, p := range points {
+		fix, err := gps.Fix()
+		assert.NoError(t, err)
+		assert.Equal(t, p.Time, fix.Time)
+		assert.Equal(t, p.Lat, fix.Lat)
+		assert.Equal(t, p.Lon, fix.Lon)
+		assert.Equal(t, Fix3D, fix.Type)
+		assert.Greater(t, fix.Speed, 0.0)
+	}
+	_, _, err = gps.Loc()
+	assert.ErrorIs(t, err, ErrEndOfTrack)
+
+	gps.Reset()
+	lat, lon, err := gps.Loc()
+	assert.NoError(t, err)
+	assert.Equal(t, points[0].Lat, lat)
+	assert.Equal(t, points[0].Lon, lon)
+
+	// the legacy simulator loops
+	gps, err = NewGPSSimulator([][]float64{{39.958134, 116.436234}, {39.958195, 116.436165}})
+	assert.NoError(t, err)
+	gps.cfg.Delay = 0
+	for i := 0; i < 3; i++ {
+		_, _, err = gps.Loc()
+		assert.NoError(t, err)
+	}
+	lat, _, _ = gps.Loc()
+	assert.Equal(t, 39.958195, lat)
+}
+
+func Test_GPSSimulatorInterpolate(t *testing.T) {
+	gps, err := NewGPSSimulatorFromCSV(testCSV, GPSSimulatorConfig{
+		Interpolate: true,
+		Interval:    3 * time.Second,
+	})
+	assert.NoError(t, err)
+
+	fix, err := gps.Fix()
+	assert.NoError(t, err)
+	assert.Equal(t, 39.958134, fix.Lat)
+
+	// halfway between the first two points which are 6s apart
+	fix, err = gps.Fix()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2019, 11, 4, 8, 32, 48, 0, time.UTC), fix.Time)
+	assert.InDelta(t, (39.958134+39.958195)/2, fix.Lat, 1e-9)
+	assert.InDelta(t, (116.436234+116.436165)/2, fix.Lon, 1e-9)
+	assert.InDelta(t, 8.9/6, fix.Speed, 0.1)
+	assert.InDelta(t, 319, fix.Course, 1)
+}
+
+func Test_GPSSimulatorRealTime(t *testing.T) {
+	gps, err := NewGPSSimulatorFromCSV(testCSV, GPSSimulatorConfig{
+		Mode:  ReplayRealTime,
+		Speed: 1000,
+	})
+	assert.NoError(t, err)
+
+	fix, err := gps.Fix()
+	assert.NoError(t, err)
+	start := fix.Time
+	time.Sleep(30 * time.Millisecond)
+	fix, err = gps.Fix()
+	assert.NoError(t, err)
+	// 30ms x 1000 = 30s of the track, the points are 6s apart
+	elapsed := fix.Time.Sub(start)
+	assert.GreaterOrEqual(t, elapsed, 24*time.Second)
+	assert.Less(t, elapsed, 60*time.Second)
+
+	// the track lasts about 46 minutes
+	gps, err = NewGPSSimulatorFromCSV(testCSV, GPSSimulatorConfig{
+		Mode:  ReplayRealTime,
+		Speed: 1000000,
+	})
+	assert.NoError(t, err)
+	_, err = gps.Fix()
+	assert.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+	_, err = gps.Fix()
+	assert.ErrorIs(t, err, ErrEndOfTrack)
+}
+
+func Test_GPSSimulatorNoiseAndDropout(t *testing.T) {
+	points, _ := LoadCSV(testCSV)
+	gps, err := NewGPSSimulatorWithConfig(points, GPSSimulatorConfig{
+		Noise:   5,
+		Dropout: 0.2,
+		Seed:    1,
+	})
+	assert.NoError(t, err)
+
+	dropouts, sum := 0, 0.0
+	for _, p := range points {
+		fix, err := gps.Fix()
+		assert.NoError(t, err)
+		if !fix.Valid() {
+			dropouts++
+			continue
+		}
+		sum += geo.Distance(p.Point(), fix.Point())
+	}
+	// mean of the 2D gaussian error is 1.25 sigma
+	mean := sum / float64(len(points)-dropouts)
+	assert.InDelta(t, 6.3, mean, 1.5)
+	assert.InDelta(t, 0.2, float64(dropouts)/float64(len(points)), 0.06)
+}
+
+func Test_WatchFencesWithGPSSimulator(t *testing.T) {
+	gps, err := NewGPSSimulatorFromCSV(testCSV, GPSSimulatorConfig{})
+	assert.NoError(t, err)
+	points, _ := LoadCSV(testCSV)
+	start := geo.Fence{Name: "start", Area: geo.Circle{Center: points[0].Point(), Radius: 30}}
+	end := geo.Fence{Name: "end", Area: geo.Circle{Center: points[len(points)-1].Point(), Radius: 30}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var got []string
+	for ev := range geo.WatchFences(ctx, gps, time.Microsecond, start, end) {
+		got = append(got, ev.Type.String()+" "+ev.Fence)
+		if len(got) == 3 {
+			cancel()
+		}
+	}
+	assert.Equal(t, []string{"enter start", "exit start", "enter end"}, got)
+}
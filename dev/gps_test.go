@@ -0,0 +1,147 @@
+package dev
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shanghuiyang/rpi-devices/nmea"
+	"github.com/stretchr/testify/assert"
+)
+
+// epoch returns the sentences of an epoch in the order NEO-6M outputs them
+func epoch(tod string, lat, lon string) string {
+	lines := []string{
+		nmea.Sentencef("GPRMC", tod, "A", lat, "N", lon, "E", "1.944", "87.5", "041119", "", "", "A"),
+		nmea.Sentencef("GPVTG", "87.5", "T", "", "M", "1.944", "N", "3.600", "K", "A"),
+		nmea.Sentencef("GPGGA", tod, lat, "N", lon, "E", "1", "07", "1.25", "48.6", "M", "-8.1", "M", "", ""),
+		nmea.Sentencef("GPGSA", "A", "3", "10", "07", "05", "02", "29", "04", "08", "", "", "", "", "", "2.10", "1.25", "1.69"),
+		nmea.Sentencef("GPGSV", "1", "1", "01", "10", "63", "137", "17"),
+	}
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+func Test_NMEAGPSOneShotFix(t *testing.T) {
+	port := NewFakeSerialPort()
+	port.Feed([]byte("GSV,3,3,11*00\r\n" + epoch("083245.00", "3957.48804", "11626.17404")))
+	gps := NewNeo6mGPSWithPort(port)
+
+	fix, err := gps.Fix()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2019, 11, 4, 8, 32, 45, 0, time.UTC), fix.Time)
+	assert.InDelta(t, 39.958134, fix.Lat, 1e-6)
+	assert.InDelta(t, 116.436234, fix.Lon, 1e-6)
+	assert.Equal(t, 48.6, fix.Alt)
+	assert.InDelta(t, 1.0, fix.Speed, 1e-3)
+	assert.Equal(t, 87.5, fix.Course)
+	assert.Equal(t, 7, fix.Satellites)
+	assert.Equal(t, 1.25, fix.HDOP)
+	assert.Equal(t, Fix3D, fix.Type)
+	assert.True(t, fix.Valid())
+
+	// no data
+	port.SetReadTimeout(10 * time.Millisecond)
+	_, err = gps.Fix()
+	assert.ErrorIs(t, err, errStaleFix)
+}
+
+func Test_FixAssembler(t *testing.T) {
+	var a fixAssembler
+	add := func(s string) (Fix, bool) {
+		sentence, err := nmea.Parse(s)
+		assert.NoError(t, err)
+		return a.add(sentence)
+	}
+
+	// RMC only, the fix is complete when the next epoch begins
+	_, ok := add(nmea.Sentencef("GNRMC", "083245.00", "A", "3957.48804", "N", "11626.17404", "E", "0", "", "041119", "", "", "A"))
+	assert.False(t, ok)
+	fix, ok := add(nmea.Sentencef("GNRMC", "083246.00", "V", "", "", "", "", "", "", "041119", "", "", "N"))
+	assert.True(t, ok)
+	assert.Equal(t, Fix2D, fix.Type)
+	assert.Equal(t, time.Date(2019, 11, 4, 8, 32, 45, 0, time.UTC), fix.Time)
+
+	// fix lost
+	fix, ok = add(nmea.Sentencef("GNGGA", "083246.00", "", "", "", "", "0", "00", "99.99", "", "", "", "", "", ""))
+	assert.True(t, ok)
+	assert.Equal(t, FixNone, fix.Type)
+	assert.False(t, fix.Valid())
+}
+
+func Test_NMEAGPSSubscribe(t *testing.T) {
+	port := NewFakeSerialPort()
+	port.SetReadTimeout(10 * time.Millisecond)
+	gps := NewHT1818GPSWithPort(port)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fixes := gps.Subscribe(ctx)
+
+	// not fixed yet
+	_, _, err := gps.Loc()
+	assert.ErrorIs(t, err, errNoFix)
+
+	port.Feed([]byte(epoch("083245.00", "3957.48804", "11626.17404")))
+	fix := <-fixes
+	assert.InDelta(t, 39.958134, fix.Lat, 1e-6)
+	port.Feed([]byte(epoch("083246.00", "3957.49170", "11626.16990")))
+	fix = <-fixes
+	assert.InDelta(t, 39.958195, fix.Lat, 1e-6)
+	assert.Equal(t, 46, fix.Time.Second())
+
+	// safe to call from multiple goroutines
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lat, lon, err := gps.Loc()
+			assert.NoError(t, err)
+			assert.InDelta(t, 39.958195, lat, 1e-6)
+			assert.InDelta(t, 116.436165, lon, 1e-6)
+		}()
+	}
+	wg.Wait()
+
+	cancel()
+	_, ok := <-fixes
+	assert.False(t, ok)
+
+	// stale
+	saved := FixTimeout
+	FixTimeout = 20 * time.Millisecond
+	defer func() { FixTimeout = saved }()
+	time.Sleep(50 * time.Millisecond)
+	_, err = gps.Fix()
+	assert.ErrorIs(t, err, errStaleFix)
+
+	assert.NoError(t, gps.Close())
+	assert.True(t, port.Closed())
+}
+
+func Test_NMEAGPSStopAndClose(t *testing.T) {
+	port := NewFakeSerialPort()
+	port.SetReadTimeout(10 * time.Millisecond)
+	gps := NewHT1818GPSWithPort(port)
+	fixes := gps.Subscribe(context.Background())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			gps.Stop()
+		}()
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, gps.Close())
+		}()
+	}
+	wg.Wait()
+
+	_, ok := <-fixes
+	assert.False(t, ok)
+	assert.False(t, gps.isRunning())
+	assert.True(t, port.Closed())
+}
@@ -28,8 +28,6 @@ package dev
 import (
 	"errors"
 	"time"
-
-	"github.com/tarm/serial"
 )
 
 const (
@@ -57,22 +55,26 @@ var (
 
 // GY25 implements Accelerometer interface
 type GY25 struct {
-	port *serial.Port
+	port SerialPort
 	buf  [bufsize]byte
 }
 
 // NewGY25 ...
 func NewGY25(dev string, baud int) (*GY25, error) {
-	cfg := &serial.Config{
-		Name:        dev,
+	port, err := OpenSerial(SerialConfig{
+		Dev:         dev,
 		Baud:        baud,
 		ReadTimeout: 3 * time.Second,
-	}
-	port, err := serial.OpenPort(cfg)
+	})
 	if err != nil {
 		return nil, err
 	}
-	return &GY25{port: port}, nil
+	return NewGY25WithPort(port), nil
+}
+
+// NewGY25WithPort ...
+func NewGY25WithPort(port SerialPort) *GY25 {
+	return &GY25{port: port}
 }
 
 // SetMode ...
@@ -26,8 +26,6 @@ package dev
 
 import (
 	"time"
-
-	"github.com/stianeikeland/go-rpio/v4"
 )
 
 const (
@@ -37,15 +35,20 @@ const (
 
 // HCSR04 implements DistanceMeter interface
 type HCSR04 struct {
-	trig rpio.Pin
-	echo rpio.Pin
+	trig Pin
+	echo Pin
 }
 
 // NewHCSR04 ...
 func NewHCSR04(trig int8, echo int8) *HCSR04 {
+	return NewHCSR04WithPins(newPin(uint8(trig)), newPin(uint8(echo)))
+}
+
+// NewHCSR04WithPins ...
+func NewHCSR04WithPins(trig, echo Pin) *HCSR04 {
 	hc := &HCSR04{
-		trig: rpio.Pin(trig),
-		echo: rpio.Pin(echo),
+		trig: trig,
+		echo: echo,
 	}
 	hc.trig.Output()
 	hc.trig.Low()
@@ -60,7 +63,7 @@ func (hc *HCSR04) Dist() (float64, error) {
 	hc.trig.High()
 	delayUs(1)
 
-	for i := 0; hc.echo.Read() != rpio.High; i++ {
+	for i := 0; hc.echo.Read() != High; i++ {
 		if i >= hcsr04Timeout {
 			return hcsr04MaxDist, nil
 		}
@@ -68,7 +71,7 @@ func (hc *HCSR04) Dist() (float64, error) {
 	}
 
 	start := time.Now()
-	for i := 0; hc.echo.Read() != rpio.Low; i++ {
+	for i := 0; hc.echo.Read() != Low; i++ {
 		if i >= hcsr04Timeout {
 			return hcsr04MaxDist, nil
 		}
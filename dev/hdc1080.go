@@ -32,12 +32,9 @@ package dev
 
 import (
 	"errors"
-
-	"golang.org/x/exp/io/i2c"
 )
 
 const (
-	hdc080Dev   = "/dev/i2c-1"
 	hdc1080Addr = 0x40
 	maxRetry    = 10
 )
@@ -48,16 +45,21 @@ var (
 
 // HDC1080 ...
 type HDC1080 struct {
-	dev *i2c.Device
+	dev I2CDevice
 }
 
 // NewHDC1080 implement Thermohygrometer interface
 func NewHDC1080() (*HDC1080, error) {
-	dev, err := i2c.Open(&i2c.Devfs{Dev: hdc080Dev}, hdc1080Addr)
+	dev, err := OpenI2C(DefaultI2CBus, hdc1080Addr)
 	if err != nil {
 		return nil, err
 	}
-	return &HDC1080{dev: dev}, nil
+	return NewHDC1080WithI2C(dev), nil
+}
+
+// NewHDC1080WithI2C ...
+func NewHDC1080WithI2C(dev I2CDevice) *HDC1080 {
+	return &HDC1080{dev: dev}
 }
 
 // TempHumidity ...
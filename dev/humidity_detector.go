@@ -11,18 +11,23 @@ Connect to Raspberry Pi:
 package dev
 
 import (
-	"github.com/stianeikeland/go-rpio/v4"
+	"context"
 )
 
 // HumidityDetector implements Detector interface
 type HumidityDetector struct {
-	pin rpio.Pin
+	pin Pin
 }
 
 // NewHumidityDetector ...
 func NewHumidityDetector(pin uint8) *HumidityDetector {
+	return NewHumidityDetectorWithPin(newPin(pin))
+}
+
+// NewHumidityDetectorWithPin ...
+func NewHumidityDetectorWithPin(pin Pin) *HumidityDetector {
 	h := &HumidityDetector{
-		pin: rpio.Pin(pin),
+		pin: pin,
 	}
 	h.pin.Input()
 	return h
@@ -30,5 +35,10 @@ func NewHumidityDetector(pin uint8) *HumidityDetector {
 
 // Detected ...
 func (h *HumidityDetector) Detected() bool {
-	return h.pin.Read() == rpio.Low
+	return h.pin.Read() == Low
+}
+
+// Watch delivers events when humidity is detected or gone until ctx is done
+func (h *HumidityDetector) Watch(ctx context.Context) <-chan InputEvent {
+	return watchInput(ctx, h.pin, Low)
 }
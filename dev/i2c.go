@@ -0,0 +1,197 @@
+package dev
+
+import (
+	"errors"
+	"sync"
+
+	"golang.org/x/exp/io/i2c"
+	"golang.org/x/exp/io/i2c/driver"
+)
+
+const (
+	// DefaultI2CBus is the i2c bus exposed on gpio 2/3 of raspberry pi
+	DefaultI2CBus = "/dev/i2c-1"
+)
+
+// I2CDevice is a device with an address on an i2c bus.
+// All i2c drivers in this package talk to the hardware through I2CDevice.
+type I2CDevice interface {
+	// Read reads len(buf) bytes from the device
+	Read(buf []byte) error
+	// ReadReg reads len(buf) bytes from the register
+	ReadReg(reg byte, buf []byte) error
+	// Write writes buf to the device
+	Write(buf []byte) error
+	// WriteReg writes buf to the register
+	WriteReg(reg byte, buf []byte) error
+	Close() error
+}
+
+// OpenI2C opens the device with the address on the i2c bus,
+// e.g. OpenI2C("/dev/i2c-1", 0x48)
+func OpenI2C(bus string, addr int) (I2CDevice, error) {
+	dev, err := i2c.Open(&i2c.Devfs{Dev: bus}, addr)
+	if err != nil {
+		return nil, err
+	}
+	return dev, nil
+}
+
+// i2cOpener adapts an I2CDevice to driver.Opener used by the third-party libraries like monochromeoled
+type i2cOpener struct {
+	dev I2CDevice
+}
+
+func (o *i2cOpener) Open(addr int, tenbit bool) (driver.Conn, error) {
+	return &i2cConn{dev: o.dev}, nil
+}
+
+type i2cConn struct {
+	dev I2CDevice
+}
+
+func (c *i2cConn) Tx(w, r []byte) error {
+	if len(w) == 1 && len(r) > 0 {
+		return c.dev.ReadReg(w[0], r)
+	}
+	if len(w) > 0 {
+		if err := c.dev.Write(w); err != nil {
+			return err
+		}
+	}
+	if len(r) > 0 {
+		return c.dev.Read(r)
+	}
+	return nil
+}
+
+func (c *i2cConn) Close() error {
+	return c.dev.Close()
+}
+
+// FakeI2CDevice implements I2CDevice in memory for testing.
+// Script the data returned by reads using SetReg() and QueueRead(),
+// and check the data written by drivers using RegWrites() and Writes().
+type FakeI2CDevice struct {
+	mu        sync.Mutex
+	regs      map[byte][][]byte
+	reads     [][]byte
+	regWrites map[byte][][]byte
+	writes    [][]byte
+	err       error
+	closed    bool
+}
+
+// NewFakeI2CDevice ...
+func NewFakeI2CDevice() *FakeI2CDevice {
+	return &FakeI2CDevice{
+		regs:      make(map[byte][][]byte),
+		regWrites: make(map[byte][][]byte),
+	}
+}
+
+// SetReg scripts the data returned by reading the register.
+// Each call queues a response, and the last one is repeated once the queue is drained.
+func (d *FakeI2CDevice) SetReg(reg byte, data ...byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.regs[reg] = append(d.regs[reg], data)
+}
+
+// QueueRead scripts the data returned by the next Read()
+func (d *FakeI2CDevice) QueueRead(data ...byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.reads = append(d.reads, data)
+}
+
+// SetErr makes all following operations fail with err, use nil to recover
+func (d *FakeI2CDevice) SetErr(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.err = err
+}
+
+// RegWrites returns all data written to the register
+func (d *FakeI2CDevice) RegWrites(reg byte) [][]byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([][]byte{}, d.regWrites[reg]...)
+}
+
+// Writes returns all data written without a register
+func (d *FakeI2CDevice) Writes() [][]byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([][]byte{}, d.writes...)
+}
+
+// Closed returns true if the device has been closed
+func (d *FakeI2CDevice) Closed() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.closed
+}
+
+// Read ...
+func (d *FakeI2CDevice) Read(buf []byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.err != nil {
+		return d.err
+	}
+	if len(d.reads) == 0 {
+		return errors.New("no data scripted for read")
+	}
+	copy(buf, d.reads[0])
+	d.reads = d.reads[1:]
+	return nil
+}
+
+// ReadReg ...
+func (d *FakeI2CDevice) ReadReg(reg byte, buf []byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.err != nil {
+		return d.err
+	}
+	q := d.regs[reg]
+	if len(q) == 0 {
+		return errors.New("no data scripted for register")
+	}
+	copy(buf, q[0])
+	if len(q) > 1 {
+		d.regs[reg] = q[1:]
+	}
+	return nil
+}
+
+// Write ...
+func (d *FakeI2CDevice) Write(buf []byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.err != nil {
+		return d.err
+	}
+	d.writes = append(d.writes, append([]byte{}, buf...))
+	return nil
+}
+
+// WriteReg ...
+func (d *FakeI2CDevice) WriteReg(reg byte, buf []byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.err != nil {
+		return d.err
+	}
+	d.regWrites[reg] = append(d.regWrites[reg], append([]byte{}, buf...))
+	return nil
+}
+
+// Close ...
+func (d *FakeI2CDevice) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closed = true
+	return nil
+}
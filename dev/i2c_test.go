@@ -0,0 +1,52 @@
+package dev
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ADS1015WithFakeI2C(t *testing.T) {
+	i2c := NewFakeI2CDevice()
+	i2c.SetReg(ConversionRegiserPointer, 0x40, 0x00)
+	ads := NewADS1015WithI2C(i2c)
+
+	v, err := ads.Read(1)
+	assert.NoError(t, err)
+	assert.InDelta(t, 3.072, v, 0.001)
+
+	conf := defaultConfig | MultiplexerConfigurationAIN1
+	assert.Equal(t, [][]byte{{byte(conf >> 8), byte(conf & 0xFF)}}, i2c.RegWrites(ConfigRegiserPointer))
+
+	_, err = ads.Read(4)
+	assert.Error(t, err)
+
+	i2c.SetErr(errors.New("bus error"))
+	_, err = ads.Read(0)
+	assert.Error(t, err)
+
+	assert.NoError(t, ads.Close())
+	assert.True(t, i2c.Closed())
+}
+
+func Test_HDC1080WithFakeI2C(t *testing.T) {
+	i2c := NewFakeI2CDevice()
+	i2c.QueueRead(0x66, 0x66, 0x80, 0x00)
+	hdc := NewHDC1080WithI2C(i2c)
+
+	temp, humi, err := hdc.TempHumidity()
+	assert.NoError(t, err)
+	assert.InDelta(t, 26.0, temp, 0.01)
+	assert.InDelta(t, 50.0, humi, 0.01)
+	assert.Equal(t, [][]byte{hdc1080Cmd}, i2c.Writes())
+}
+
+func Test_SSD1306DisplayWithFakeI2C(t *testing.T) {
+	i2c := NewFakeI2CDevice()
+	display, err := NewSSD1306DisplayWithI2C(i2c, 128, 32)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, i2c.Writes())
+	assert.NoError(t, display.Close())
+	assert.True(t, i2c.Closed())
+}
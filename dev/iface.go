@@ -58,6 +58,13 @@ type DistanceMeter interface {
 	Close() error
 }
 
+// DriveTrain is a differential drive, linear and angular are -1 ~ 1,
+// and positive angular turns clockwise.
+type DriveTrain interface {
+	Drive(linear, angular float64)
+	Stop()
+}
+
 // Encoder ...
 type Encoder interface {
 	Count1() int
@@ -66,12 +73,23 @@ type Encoder interface {
 	Stop()
 }
 
+// PositionEncoder is an encoder reporting its signed position in counts, e.g. QuadratureEncoder
+type PositionEncoder interface {
+	Position() int64
+}
+
 // GPS ...
 type GPS interface {
 	Loc() (lat, lon float64, err error)
 	Close() error
 }
 
+// FixGPS is a GPS reporting the full fix, e.g. NMEAGPS
+type FixGPS interface {
+	GPS
+	Fix() (Fix, error)
+}
+
 // Hygrometer ...
 type Hygrometer interface {
 	Humidity() (float32, error)
@@ -111,6 +129,11 @@ type Pump interface {
 	Run(sec int)
 }
 
+// VolumeMeter is a meter reporting the total volume in liters, e.g. WaterFlowMeter
+type VolumeMeter interface {
+	Total() float64
+}
+
 // RFReciver is the interface of radio-frequency receiver
 type RFReceiver interface {
 	Received(ch int) bool
@@ -28,29 +28,31 @@ package dev
 import (
 	"fmt"
 	"time"
-
-	"github.com/tarm/serial"
 )
 
 var irCodeBuf = make([]byte, 32)
 
 // IRCoder ...
 type IRCoder struct {
-	port *serial.Port
+	port SerialPort
 }
 
 // NewIRCoder ...
 func NewIRCoder(dev string, baud int) (*IRCoder, error) {
-	c := &serial.Config{
-		Name:        dev,
+	port, err := OpenSerial(SerialConfig{
+		Dev:         dev,
 		Baud:        baud,
 		ReadTimeout: 3 * time.Second,
-	}
-	port, err := serial.OpenPort(c)
+	})
 	if err != nil {
 		return nil, err
 	}
-	return &IRCoder{port}, nil
+	return NewIRCoderWithPort(port), nil
+}
+
+// NewIRCoderWithPort ...
+func NewIRCoderWithPort(port SerialPort) *IRCoder {
+	return &IRCoder{port}
 }
 
 func (ir *IRCoder) Send(data []byte) error {
@@ -10,18 +10,23 @@ Connect to Pi:
 package dev
 
 import (
-	"github.com/stianeikeland/go-rpio/v4"
+	"context"
 )
 
 // IRDetector implements Detector interface
 type IRDetector struct {
-	out rpio.Pin
+	out Pin
 }
 
 // NewIRDetector ...
 func NewIRDetector(out uint8) *IRDetector {
+	return NewIRDetectorWithPin(newPin(out))
+}
+
+// NewIRDetectorWithPin ...
+func NewIRDetectorWithPin(out Pin) *IRDetector {
 	ir := &IRDetector{
-		out: rpio.Pin(out),
+		out: out,
 	}
 	ir.out.Input()
 	return ir
@@ -29,5 +34,10 @@ func NewIRDetector(out uint8) *IRDetector {
 
 // Detected ...
 func (ir *IRDetector) Detected() bool {
-	return ir.out.Read() == rpio.Low
+	return ir.out.Read() == Low
+}
+
+// Watch delivers events when infrared ray is detected or gone until ctx is done
+func (ir *IRDetector) Watch(ctx context.Context) <-chan InputEvent {
+	return watchInput(ctx, ir.out, Low)
 }
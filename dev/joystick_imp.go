@@ -11,13 +11,13 @@ Connect to Pi:
 package dev
 
 import (
-	"github.com/stianeikeland/go-rpio/v4"
+	"context"
 )
 
 // JoystickImp ...
 type JoystickImp struct {
-	swPin rpio.Pin
-	ads   *ADS1015
+	swPin Pin
+	ads   ADC
 }
 
 // NewJoystickImp ...
@@ -26,12 +26,17 @@ func NewJoystickImp(sw uint8) (*JoystickImp, error) {
 	if err != nil {
 		return nil, err
 	}
+	return NewJoystickImpWithPin(newPin(sw), ads), nil
+}
+
+// NewJoystickImpWithPin creates a joystick using the sw pin and an ADC reading x on channel 0 and y on channel 1
+func NewJoystickImpWithPin(sw Pin, ads ADC) *JoystickImp {
 	j := &JoystickImp{
-		swPin: rpio.Pin(sw),
+		swPin: sw,
 		ads:   ads,
 	}
 	j.swPin.Input()
-	return j, nil
+	return j
 }
 
 // X ...
@@ -56,8 +61,13 @@ func (j *JoystickImp) Y() float64 {
 // z = 1: pressed
 // z = 0: home
 func (j *JoystickImp) Z() int {
-	if j.swPin.Read() == rpio.Low {
+	if j.swPin.Read() == Low {
 		return 1 // pressed
 	}
 	return 0 // home
 }
+
+// Watch delivers events when the joystick is pressed down or released until ctx is done
+func (j *JoystickImp) Watch(ctx context.Context) <-chan InputEvent {
+	return watchInput(ctx, j.swPin, Low)
+}
@@ -24,72 +24,335 @@ Pins:
  - IN2: any data pin
  - IN3: any data pin
  - IN4: any data pin
- - EN1: must be one of GPIO 12, 13, 18 or 19 (pwn pins)
- - EN2: must be one of GPIO 12, 13, 18 or 19 (pwn pins)
+ - EN1: any data pin, GPIO 12, 13, 18 or 19 (pwm pins) are preferred, others fall back to software pwm
+ - EN2: any data pin, GPIO 12, 13, 18 or 19 (pwm pins) are preferred, others fall back to software pwm
 
+Stop() and Coast() cut the power and let the motor spin down freely, and Brake() stops it quickly by shorting
+the motor with both inputs high. Speed changes follow the slew rate in the background until the target is reached,
+and changing the direction slows the motor down to zero before reversing, so the driver never
+sees the current spike of an abrupt reversal.
+
+Usage:
+
+	l298n := dev.NewL298N(17, 23, 27, 22, 13, 19)
+	defer l298n.Close()
+	l298n.MotorA.SetSpeed(50)
+	l298n.MotorA.Forward()
+	l298n.MotorA.Backward() // slows down to zero, then speeds up backward
+	l298n.MotorA.Brake()
+	state := l298n.MotorA.State()
 */
 package dev
 
 import (
-	"github.com/stianeikeland/go-rpio/v4"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	defaultL298NFreq     = 50 * 100
+	defaultL298NSlewRate = 200
+	defaultL298NInterval = 20 * time.Millisecond
 )
 
-// L298N implements MotorDriver interface
+// MotorMode is the mode of a motor driver
+type MotorMode int
+
+// Motor modes
+const (
+	MotorCoast MotorMode = iota
+	MotorForward
+	MotorBackward
+	MotorBrake
+)
+
+// String ...
+func (m MotorMode) String() string {
+	switch m {
+	case MotorForward:
+		return "forward"
+	case MotorBackward:
+		return "backward"
+	case MotorBrake:
+		return "brake"
+	default:
+		return "coast"
+	}
+}
+
+// MotorState is the commanded state of a motor
+type MotorState struct {
+	// Mode and Speed are what the motor is driven with now, Speed is the duty cycle 0 ~ 100
+	Mode  MotorMode
+	Speed float64
+	// TargetMode and TargetSpeed are the latest commands the motor is ramping to
+	TargetMode  MotorMode
+	TargetSpeed uint32
+}
+
+// L298NConfig ...
+type L298NConfig struct {
+	// SlewRate limits how fast the speed changes, in percent per second, default 200.
+	// e.g. 200 takes 0.5s from stop to full speed, and 1s from full forward to full backward.
+	SlewRate float64
+	// Interval is the interval of ramping in the background, default 20ms
+	Interval time.Duration
+	// Freq is the pwm frequency of ENA and ENB, default 5000
+	Freq int
+}
+
+// L298N drives two DC motors, MotorA and MotorB implement MotorDriver interface
 type L298N struct {
-	MotorA MotorDriver
-	MotorB MotorDriver
+	MotorA *L298NMotor
+	MotorB *L298NMotor
 }
 
-type l298nMotorDriver struct {
-	in1 rpio.Pin
-	in2 rpio.Pin
-	en  rpio.Pin
+// L298NMotor implements MotorDriver interface
+type L298NMotor struct {
+	in1 Pin
+	in2 Pin
+	en  Pin
+	cfg L298NConfig
+
+	mu          sync.Mutex
+	mode        MotorMode
+	speed       float64
+	targetMode  MotorMode
+	targetSpeed uint32
+	ramping     bool
+	done        chan struct{}
+	once        sync.Once
 }
 
 // NewL298N ...
 func NewL298N(in1, in2, in3, in4, ena, enb uint8) *L298N {
+	return NewL298NWithPins(newPin(in1), newPin(in2), newPin(in3), newPin(in4), newPin(ena), newPin(enb))
+}
+
+// NewL298NWithPins ...
+func NewL298NWithPins(in1, in2, in3, in4, ena, enb Pin) *L298N {
+	return NewL298NWithConfig(in1, in2, in3, in4, ena, enb, L298NConfig{})
+}
+
+// NewL298NWithConfig ...
+func NewL298NWithConfig(in1, in2, in3, in4, ena, enb Pin, cfg L298NConfig) *L298N {
+	if cfg.SlewRate <= 0 {
+		cfg.SlewRate = defaultL298NSlewRate
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultL298NInterval
+	}
+	if cfg.Freq <= 0 {
+		cfg.Freq = defaultL298NFreq
+	}
 	l := &L298N{
-		MotorA: newL298NMotorDriver(in1, in2, ena),
-		MotorB: newL298NMotorDriver(in3, in4, enb),
+		MotorA: newL298NMotor(in1, in2, ena, cfg),
+		MotorB: newL298NMotor(in3, in4, enb, cfg),
 	}
 	return l
 }
 
-func newL298NMotorDriver(in1, in2, en uint8) *l298nMotorDriver {
-	m := &l298nMotorDriver{
-		in1: rpio.Pin(in1),
-		in2: rpio.Pin(in2),
-		en:  rpio.Pin(en),
+// Close coasts the motors and stops ramping
+func (l *L298N) Close() error {
+	l.MotorA.Close()
+	l.MotorB.Close()
+	return nil
+}
+
+func newL298NMotor(in1, in2, en Pin, cfg L298NConfig) *L298NMotor {
+	m := &L298NMotor{
+		in1:  in1,
+		in2:  in2,
+		en:   en,
+		cfg:  cfg,
+		done: make(chan struct{}),
 	}
 	m.in1.Output()
 	m.in2.Output()
 	m.in1.Low()
 	m.in2.Low()
 	m.en.Pwm()
-	m.en.Freq(50 * 100)
+	m.en.Freq(cfg.Freq)
+	m.en.DutyCycle(0, 100)
 	return m
 }
 
-// Forward ...
-func (m *l298nMotorDriver) Forward() {
-	m.in1.High()
-	m.in2.Low()
+// Forward runs forward, it slows down to zero first if the motor is running backward
+func (m *L298NMotor) Forward() {
+	m.setTarget(MotorForward)
 }
 
-// Backward ...
-func (m *l298nMotorDriver) Backward() {
-	m.in1.Low()
-	m.in2.High()
+// Backward runs backward, it slows down to zero first if the motor is running forward
+func (m *L298NMotor) Backward() {
+	m.setTarget(MotorBackward)
 }
 
-// Stop ...
-func (m *l298nMotorDriver) Stop() {
-	m.in1.Low()
-	m.in2.Low()
+// Stop coasts the motor
+func (m *L298NMotor) Stop() {
+	m.Coast()
 }
 
-// Speed ...
-func (m *l298nMotorDriver) SetSpeed(s uint32) {
-	m.en.DutyCycle(0, 100)
-	m.en.DutyCycle(s, 100)
+// Coast cuts the power immediately and lets the motor spin down freely
+func (m *L298NMotor) Coast() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.targetMode = MotorCoast
+	m.speed = 0
+	m.setMode(MotorCoast)
+}
+
+// Brake stops the motor quickly by driving both inputs high with the enable pin fully on
+func (m *L298NMotor) Brake() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.targetMode = MotorBrake
+	m.speed = 0
+	m.setMode(MotorBrake)
+}
+
+// SetSpeed sets the target speed in percent 0 ~ 100, the motor ramps to it following the slew rate
+func (m *L298NMotor) SetSpeed(percent uint32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if percent > 100 {
+		percent = 100
+	}
+	m.targetSpeed = percent
+	m.startRamp()
+}
+
+// State returns the commanded state
+func (m *L298NMotor) State() MotorState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return MotorState{
+		Mode:        m.mode,
+		Speed:       m.speed,
+		TargetMode:  m.targetMode,
+		TargetSpeed: m.targetSpeed,
+	}
+}
+
+// Close coasts the motor and stops ramping, the motor can't ramp after closed
+func (m *L298NMotor) Close() error {
+	m.once.Do(func() {
+		close(m.done)
+	})
+	m.Coast()
+	return nil
+}
+
+func (m *L298NMotor) setTarget(mode MotorMode) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.targetMode = mode
+	// change the direction right now if the motor isn't running
+	m.update(0)
+	m.startRamp()
+}
+
+// startRamp starts ramping in the background if the motor isn't at the target,
+// the ramping goroutine exits once the target is reached
+func (m *L298NMotor) startRamp() {
+	if m.ramping || m.settled() {
+		return
+	}
+	select {
+	case <-m.done:
+		return
+	default:
+	}
+	m.ramping = true
+	go m.ramp()
+}
+
+// settled returns true if the motor is at the target
+func (m *L298NMotor) settled() bool {
+	if m.mode != m.targetMode {
+		return false
+	}
+	if m.mode != MotorForward && m.mode != MotorBackward {
+		return true
+	}
+	return m.speed == float64(m.targetSpeed)
+}
+
+// ramp updates the motor every interval until the target is reached
+func (m *L298NMotor) ramp() {
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+	last := time.Now()
+	for {
+		select {
+		case <-m.done:
+			m.mu.Lock()
+			m.ramping = false
+			m.mu.Unlock()
+			return
+		case now := <-ticker.C:
+			m.mu.Lock()
+			m.update(now.Sub(last).Seconds())
+			if m.settled() {
+				m.ramping = false
+				m.mu.Unlock()
+				return
+			}
+			m.mu.Unlock()
+			last = now
+		}
+	}
+}
+
+// update moves the motor toward the target by at most SlewRate x dt
+func (m *L298NMotor) update(dt float64) {
+	maxDelta := m.cfg.SlewRate * dt
+	if m.mode != m.targetMode {
+		if m.speed > 0 {
+			// slow down to zero before changing the direction
+			m.setSpeed(math.Max(0, m.speed-maxDelta))
+			if m.speed > 0 {
+				return
+			}
+			maxDelta = 0
+		}
+		m.setMode(m.targetMode)
+	}
+	if m.mode != MotorForward && m.mode != MotorBackward {
+		return
+	}
+	delta := float64(m.targetSpeed) - m.speed
+	m.setSpeed(m.speed + math.Max(-maxDelta, math.Min(maxDelta, delta)))
+}
+
+// setMode drives the input pins for the mode
+func (m *L298NMotor) setMode(mode MotorMode) {
+	m.mode = mode
+	switch mode {
+	case MotorForward:
+		m.in1.High()
+		m.in2.Low()
+		m.en.DutyCycle(uint32(math.Round(m.speed)), 100)
+	case MotorBackward:
+		m.in1.Low()
+		m.in2.High()
+		m.en.DutyCycle(uint32(math.Round(m.speed)), 100)
+	case MotorBrake:
+		m.in1.High()
+		m.in2.High()
+		m.en.DutyCycle(100, 100)
+	default:
+		m.in1.Low()
+		m.in2.Low()
+		m.en.DutyCycle(0, 100)
+	}
+}
+
+// setSpeed sets the duty cycle of the enable pin
+func (m *L298NMotor) setSpeed(speed float64) {
+	if speed == m.speed {
+		return
+	}
+	m.speed = speed
+	m.en.DutyCycle(uint32(math.Round(speed)), 100)
 }
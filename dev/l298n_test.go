@@ -0,0 +1,159 @@
+package dev
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestL298N(cfg L298NConfig) (*L298N, []*FakePin) {
+	pins := make([]*FakePin, 6)
+	for i := range pins {
+		pins[i] = NewFakePin()
+	}
+	l := NewL298NWithConfig(pins[0], pins[1], pins[2], pins[3], pins[4], pins[5], cfg)
+	return l, pins
+}
+
+func Test_L298N(t *testing.T) {
+	// ramp manually with update()
+	l, pins := newTestL298N(L298NConfig{Interval: time.Hour})
+	defer l.Close()
+	in1, in2, en := pins[0], pins[1], pins[4]
+	m := l.MotorA
+
+	freq, duty, cycle := en.PwmState()
+	assert.Equal(t, PwmMode, en.Mode())
+	assert.Equal(t, 5000, freq)
+	assert.Equal(t, uint32(0), duty)
+	assert.Equal(t, uint32(100), cycle)
+	assert.Equal(t, Low, in1.Level())
+	assert.Equal(t, Low, in2.Level())
+
+	m.SetSpeed(100)
+	m.Forward()
+	assert.Equal(t, High, in1.Level())
+	assert.Equal(t, Low, in2.Level())
+	assert.Equal(t, MotorState{Mode: MotorForward, TargetMode: MotorForward, TargetSpeed: 100}, m.State())
+
+	m.update(0.1)
+	_, duty, _ = en.PwmState()
+	assert.Equal(t, uint32(20), duty)
+	m.update(1)
+	_, duty, _ = en.PwmState()
+	assert.Equal(t, uint32(100), duty)
+
+	// reversing slows down to zero first
+	m.Backward()
+	assert.Equal(t, High, in1.Level())
+	m.update(0.25)
+	_, duty, _ = en.PwmState()
+	assert.Equal(t, uint32(50), duty)
+	assert.Equal(t, High, in1.Level())
+	assert.Equal(t, Low, in2.Level())
+	assert.Equal(t, MotorForward, m.State().Mode)
+	assert.Equal(t, MotorBackward, m.State().TargetMode)
+
+	m.update(0.25)
+	_, duty, _ = en.PwmState()
+	assert.Equal(t, uint32(0), duty)
+	assert.Equal(t, Low, in1.Level())
+	assert.Equal(t, High, in2.Level())
+	assert.Equal(t, MotorBackward, m.State().Mode)
+
+	m.update(0.1)
+	_, duty, _ = en.PwmState()
+	assert.Equal(t, uint32(20), duty)
+
+	m.SetSpeed(10)
+	m.update(0.1)
+	assert.Equal(t, 10.0, m.State().Speed)
+
+	m.Brake()
+	_, duty, _ = en.PwmState()
+	assert.Equal(t, High, in1.Level())
+	assert.Equal(t, High, in2.Level())
+	assert.Equal(t, uint32(100), duty)
+	assert.Equal(t, MotorBrake, m.State().Mode)
+	m.update(0.1)
+	assert.Equal(t, MotorBrake, m.State().Mode)
+
+	// forward from brake releases it without ramping down
+	m.Forward()
+	_, duty, _ = en.PwmState()
+	assert.Equal(t, High, in1.Level())
+	assert.Equal(t, Low, in2.Level())
+	assert.Equal(t, uint32(0), duty)
+
+	m.update(0.1)
+	m.Coast()
+	_, duty, _ = en.PwmState()
+	assert.Equal(t, Low, in1.Level())
+	assert.Equal(t, Low, in2.Level())
+	assert.Equal(t, uint32(0), duty)
+	assert.Equal(t, MotorState{Mode: MotorCoast, TargetMode: MotorCoast, TargetSpeed: 10}, m.State())
+
+	// motor B is independent
+	assert.Equal(t, MotorCoast, l.MotorB.State().Mode)
+	assert.Equal(t, []LogicLevel{Low}, pins[2].Writes())
+}
+
+func Test_L298NRampInBackground(t *testing.T) {
+	l, pins := newTestL298N(L298NConfig{SlewRate: 1000, Interval: 5 * time.Millisecond})
+	defer l.Close()
+	in1, in2, en := pins[0], pins[1], pins[4]
+	m := l.MotorA
+	assert.False(t, m.ramping)
+
+	m.SetSpeed(80)
+	m.Forward()
+	assert.Eventually(t, func() bool {
+		return m.State().Speed == 80
+	}, time.Second, 5*time.Millisecond)
+	_, duty, _ := en.PwmState()
+	assert.Equal(t, uint32(80), duty)
+
+	// ramping stops at the target
+	assert.Eventually(t, func() bool {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return !m.ramping
+	}, time.Second, 5*time.Millisecond)
+
+	m.Backward()
+	assert.Eventually(t, func() bool {
+		s := m.State()
+		return s.Mode == MotorBackward && s.Speed == 80
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, Low, in1.Level())
+	assert.Equal(t, High, in2.Level())
+
+	// the inputs were never high together when reversing
+	w1, w2 := in1.Writes(), in2.Writes()
+	assert.Equal(t, len(w1), len(w2))
+	for i := range w1 {
+		assert.False(t, w1[i] == High && w2[i] == High)
+	}
+
+	l.Close()
+	assert.Equal(t, MotorCoast, m.State().Mode)
+	assert.Equal(t, Low, in1.Level())
+	assert.Equal(t, Low, in2.Level())
+}
+
+func Test_L298NWithDifferentialDrive(t *testing.T) {
+	l, pins := newTestL298N(L298NConfig{Interval: time.Hour})
+	defer l.Close()
+	d := NewDifferentialDrive(l.MotorA, l.MotorB)
+
+	d.Drive(0.5, 0)
+	assert.Equal(t, MotorForward, l.MotorA.State().Mode)
+	assert.Equal(t, uint32(50), l.MotorB.State().TargetSpeed)
+
+	d.Brake()
+	assert.Equal(t, MotorBrake, l.MotorA.State().Mode)
+	assert.Equal(t, MotorBrake, l.MotorB.State().Mode)
+	assert.Equal(t, High, pins[2].Level())
+	assert.Equal(t, High, pins[3].Level())
+}
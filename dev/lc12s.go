@@ -31,9 +31,6 @@ import (
 	"fmt"
 	"io"
 	"time"
-
-	"github.com/stianeikeland/go-rpio/v4"
-	"github.com/tarm/serial"
 )
 
 const (
@@ -42,28 +39,32 @@ const (
 
 // LC12S implement Wireless interface
 type LC12S struct {
-	csPin rpio.Pin
-	port  *serial.Port
+	csPin Pin
+	port  SerialPort
 }
 
 // NewLC12S ...
 func NewLC12S(dev string, baud int, csPin uint8) (*LC12S, error) {
-	cfg := &serial.Config{
-		Name:        dev,
+	port, err := OpenSerial(SerialConfig{
+		Dev:         dev,
 		Baud:        baud,
 		ReadTimeout: 3 * time.Second,
-	}
-	port, err := serial.OpenPort(cfg)
+	})
 	if err != nil {
 		return nil, err
 	}
+	return NewLC12SWithPort(port, newPin(csPin)), nil
+}
+
+// NewLC12SWithPort ...
+func NewLC12SWithPort(port SerialPort, csPin Pin) *LC12S {
 	l := &LC12S{
-		csPin: rpio.Pin(csPin),
+		csPin: csPin,
 		port:  port,
 	}
 	l.csPin.Output()
 	l.Sleep()
-	return l, nil
+	return l
 }
 
 // Send ...
@@ -10,18 +10,23 @@ Connect to Pi:
 package dev
 
 import (
-	"github.com/stianeikeland/go-rpio/v4"
+	"context"
 )
 
 // LD2410 implements Detector interface
 type LD2410 struct {
-	out rpio.Pin
+	out Pin
 }
 
 // NewLD2410 ...
 func NewLD2410(out uint8) *LD2410 {
+	return NewLD2410WithPin(newPin(out))
+}
+
+// NewLD2410WithPin ...
+func NewLD2410WithPin(out Pin) *LD2410 {
 	ld := &LD2410{
-		out: rpio.Pin(out),
+		out: out,
 	}
 	ld.out.Input()
 	return ld
@@ -29,5 +34,10 @@ func NewLD2410(out uint8) *LD2410 {
 
 // Detected ...
 func (ld *LD2410) Detected() bool {
-	return ld.out.Read() == rpio.High
+	return ld.out.Read() == High
+}
+
+// Watch delivers events when human is detected or gone until ctx is done
+func (ld *LD2410) Watch(ctx context.Context) <-chan InputEvent {
+	return watchInput(ctx, ld.out, High)
 }
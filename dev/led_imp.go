@@ -9,19 +9,22 @@ package dev
 
 import (
 	"time"
-
-	"github.com/stianeikeland/go-rpio/v4"
 )
 
 // LedImp implements Led interface
 type LedImp struct {
-	pin rpio.Pin
+	pin Pin
 }
 
 // NewLedImp ...
 func NewLedImp(pin uint8) *LedImp {
+	return NewLedImpWithPin(newPin(pin))
+}
+
+// NewLedImpWithPin ...
+func NewLedImpWithPin(pin Pin) *LedImp {
 	led := &LedImp{
-		pin: rpio.Pin(pin),
+		pin: pin,
 	}
 	led.pin.Output()
 	led.pin.Low()
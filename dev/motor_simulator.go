@@ -0,0 +1,74 @@
+package dev
+
+import (
+	"sync"
+)
+
+// MotorSimulator implements MotorDriver interface, it records the commanded direction and speed
+type MotorSimulator struct {
+	mu     sync.Mutex
+	dir    int
+	speed  uint32
+	braked bool
+}
+
+// NewMotorSimulator ...
+func NewMotorSimulator() *MotorSimulator {
+	return &MotorSimulator{}
+}
+
+// Forward ...
+func (m *MotorSimulator) Forward() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dir = 1
+	m.braked = false
+}
+
+// Backward ...
+func (m *MotorSimulator) Backward() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dir = -1
+	m.braked = false
+}
+
+// Stop ...
+func (m *MotorSimulator) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dir = 0
+	m.braked = false
+}
+
+// Brake ...
+func (m *MotorSimulator) Brake() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dir = 0
+	m.braked = true
+}
+
+// Braked returns true if the motor was stopped by Brake()
+func (m *MotorSimulator) Braked() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.braked
+}
+
+// SetSpeed ...
+func (m *MotorSimulator) SetSpeed(percent uint32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if percent > 100 {
+		percent = 100
+	}
+	m.speed = percent
+}
+
+// Velocity returns the commanded velocity -1 ~ 1, negative is backward
+func (m *MotorSimulator) Velocity() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return float64(m.dir) * float64(m.speed) / 100
+}
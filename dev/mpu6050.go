@@ -30,12 +30,7 @@ Connect to Raspberry Pi:
 */
 package dev
 
-import (
-	"golang.org/x/exp/io/i2c"
-)
-
 const (
-	mpu6050Dev   = "/dev/i2c-1"
 	mpu6050Addr  = 0x68
 	accRegister  = 0x3B
 	gyroRegister = 0x43
@@ -43,16 +38,23 @@ const (
 
 // MPU6050 ...
 type MPU6050 struct {
-	dev *i2c.Device
+	dev I2CDevice
 }
 
 // NewMPU6050 ...
 func NewMPU6050() (*MPU6050, error) {
-	dev, err := i2c.Open(&i2c.Devfs{Dev: mpu6050Dev}, mpu6050Addr)
+	dev, err := OpenI2C(DefaultI2CBus, mpu6050Addr)
 	if err != nil {
 		return nil, err
 	}
-	dev.WriteReg(0x6B, []uint8{0}) // power on
+	return NewMPU6050WithI2C(dev)
+}
+
+// NewMPU6050WithI2C ...
+func NewMPU6050WithI2C(dev I2CDevice) (*MPU6050, error) {
+	if err := dev.WriteReg(0x6B, []uint8{0}); err != nil { // power on
+		return nil, err
+	}
 	return &MPU6050{
 		dev: dev,
 	}, nil
@@ -11,19 +11,24 @@ Connect to Pi:
 package dev
 
 import (
-	"github.com/stianeikeland/go-rpio/v4"
+	"context"
 )
 
 // MQ7 implements Detector interface
 type MQ7 struct {
-	do rpio.Pin
-	ao rpio.Pin
+	do Pin
+	ao Pin
 }
 
 // NewMQ7 ...
 func NewMQ7(do uint8) *MQ7 {
+	return NewMQ7WithPin(newPin(do))
+}
+
+// NewMQ7WithPin ...
+func NewMQ7WithPin(do Pin) *MQ7 {
 	mq7 := &MQ7{
-		do: rpio.Pin(do),
+		do: do,
 	}
 	mq7.do.Input()
 	return mq7
@@ -31,5 +36,10 @@ func NewMQ7(do uint8) *MQ7 {
 
 // Detected ...
 func (mq7 *MQ7) Detected() bool {
-	return mq7.do.Read() == rpio.Low
+	return mq7.do.Read() == Low
+}
+
+// Watch delivers events when co gas is detected or gone until ctx is done
+func (mq7 *MQ7) Watch(ctx context.Context) <-chan InputEvent {
+	return watchInput(ctx, mq7.do, Low)
 }
@@ -0,0 +1,272 @@
+/*
+Navigator drives a car through a route of waypoints using a GPS, the yaw of an Accelerometer as the heading,
+and a DriveTrain like DifferentialDrive.
+
+In every step, it steers to the bearing of the current waypoint using a PID on the heading error,
+and moves to the next waypoint within ArrivalRadius. The speed is slowed down near the waypoints and
+when the heading error is large. The car is stopped whenever the fix is lost.
+
+Usage:
+
+	gps, _ := dev.NewNeo6mGPS("/dev/ttyAMA0", 9600)
+	gy25, _ := dev.NewGY25("/dev/ttyUSB0", 115200)
+	l298n := dev.NewL298N(in1, in2, in3, in4, ena, enb)
+	drive := dev.NewDifferentialDrive(l298n.MotorA, l298n.MotorB)
+	nav := dev.NewNavigator(gps, gy25, drive, dev.NavigatorConfig{ArrivalRadius: 5})
+	nav.SetRoute([]geo.Point{{Lat: 39.958195, Lon: 116.436165}, {Lat: 39.958210, Lon: 116.436301}})
+	err := nav.Run(ctx)
+*/
+package dev
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/shanghuiyang/rpi-devices/geo"
+)
+
+// NavState is the state of a Navigator
+type NavState int
+
+// Navigator states
+const (
+	NavIdle NavState = iota
+	NavDriving
+	NavFixLost
+	NavArrived
+)
+
+// String ...
+func (s NavState) String() string {
+	switch s {
+	case NavDriving:
+		return "driving"
+	case NavFixLost:
+		return "fix lost"
+	case NavArrived:
+		return "arrived"
+	default:
+		return "idle"
+	}
+}
+
+var errNoRoute = errors.New("no route")
+
+// NavigatorConfig ...
+type NavigatorConfig struct {
+	// ArrivalRadius in meters, a waypoint is reached within it, default 3
+	ArrivalRadius float64
+	// Kp, Ki and Kd are the gains of the heading PID, the input is the heading error in degrees
+	// and the output is the angular speed -1 ~ 1. Default 0.02, 0 and 0.002.
+	Kp float64
+	Ki float64
+	Kd float64
+	// MaxSpeed and MinSpeed limit the linear speed, 0 ~ 1, default 0.6 and 0.2
+	MaxSpeed float64
+	MinSpeed float64
+	// MaxTurn limits the angular speed, 0 ~ 1, default 0.5
+	MaxTurn float64
+	// SlowRadius in meters, the speed is slowed down to MinSpeed within it, default 3 x ArrivalRadius
+	SlowRadius float64
+	// HeadingOffset in degrees is added to the yaw to get the heading from true north,
+	// and InvertYaw is for the sensors whose yaw increases counterclockwise
+	HeadingOffset float64
+	InvertYaw     bool
+	// Interval is the interval of the steps in Run(), default 100ms
+	Interval time.Duration
+}
+
+// NavStatus is the result of a step
+type NavStatus struct {
+	State    NavState
+	Waypoint int
+	// Distance and Bearing to the current waypoint
+	Distance float64
+	Bearing  float64
+	Heading  float64
+	Linear   float64
+	Angular  float64
+	Fix      Fix
+}
+
+// Navigator ...
+type Navigator struct {
+	gps   FixGPS
+	imu   Accelerometer
+	drive DriveTrain
+	cfg   NavigatorConfig
+	pid   *PID
+
+	mu       sync.Mutex
+	route    []geo.Point
+	waypoint int
+	last     time.Time
+}
+
+// NewNavigator ...
+func NewNavigator(gps FixGPS, imu Accelerometer, drive DriveTrain, cfg NavigatorConfig) *Navigator {
+	if cfg.ArrivalRadius <= 0 {
+		cfg.ArrivalRadius = 3
+	}
+	if cfg.Kp == 0 && cfg.Ki == 0 && cfg.Kd == 0 {
+		cfg.Kp, cfg.Kd = 0.02, 0.002
+	}
+	if cfg.MaxSpeed <= 0 {
+		cfg.MaxSpeed = 0.6
+	}
+	if cfg.MinSpeed <= 0 {
+		cfg.MinSpeed = 0.2
+	}
+	cfg.MinSpeed = math.Min(cfg.MinSpeed, cfg.MaxSpeed)
+	if cfg.MaxTurn <= 0 {
+		cfg.MaxTurn = 0.5
+	}
+	if cfg.SlowRadius <= 0 {
+		cfg.SlowRadius = 3 * cfg.ArrivalRadius
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 100 * time.Millisecond
+	}
+	return &Navigator{
+		gps:   gps,
+		imu:   imu,
+		drive: drive,
+		cfg:   cfg,
+		pid:   NewPID(cfg.Kp, cfg.Ki, cfg.Kd, -cfg.MaxTurn, cfg.MaxTurn),
+	}
+}
+
+// SetRoute sets the waypoints and restarts from the first one
+func (n *Navigator) SetRoute(route []geo.Point) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.route = append([]geo.Point(nil), route...)
+	n.waypoint = 0
+	n.last = time.Time{}
+	n.pid.Reset()
+}
+
+// Step reads the GPS and heading, and drives toward the current waypoint.
+// The drive is stopped if there is no route, the route is finished, the fix is lost or the heading can't be read.
+func (n *Navigator) Step() (NavStatus, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	status := NavStatus{Waypoint: n.waypoint}
+	if len(n.route) == 0 {
+		n.drive.Stop()
+		return status, errNoRoute
+	}
+	if n.waypoint >= len(n.route) {
+		n.drive.Stop()
+		status.State = NavArrived
+		return status, nil
+	}
+
+	fix, err := n.gps.Fix()
+	if err != nil || !fix.Valid() {
+		n.lose()
+		status.State = NavFixLost
+		status.Fix = fix
+		if err != nil {
+			return status, fmt.Errorf("failed to read gps: %w", err)
+		}
+		return status, nil
+	}
+	status.Fix = fix
+
+	pos := fix.Point()
+	status.Distance = geo.Distance(pos, n.route[n.waypoint])
+	for status.Distance <= n.cfg.ArrivalRadius {
+		n.waypoint++
+		n.pid.Reset()
+		status.Waypoint = n.waypoint
+		if n.waypoint >= len(n.route) {
+			n.drive.Stop()
+			status.State = NavArrived
+			return status, nil
+		}
+		status.Distance = geo.Distance(pos, n.route[n.waypoint])
+	}
+
+	yaw, _, _, err := n.imu.Angles()
+	if err != nil {
+		n.lose()
+		status.State = NavIdle
+		return status, fmt.Errorf("failed to read heading: %w", err)
+	}
+	if n.cfg.InvertYaw {
+		yaw = -yaw
+	}
+	status.Heading = math.Mod(math.Mod(yaw+n.cfg.HeadingOffset, 360)+360, 360)
+	status.Bearing = geo.Bearing(pos, n.route[n.waypoint])
+	// heading error in -180 ~ 180, positive means the waypoint is on the right
+	herr := math.Mod(status.Bearing-status.Heading+540, 360) - 180
+
+	dt := n.cfg.Interval.Seconds()
+	if !n.last.IsZero() && fix.Time.After(n.last) {
+		dt = fix.Time.Sub(n.last).Seconds()
+	}
+	n.last = fix.Time
+
+	status.Angular = n.pid.Update(herr, dt)
+	status.Linear = n.speed(herr, status.Distance)
+	status.State = NavDriving
+	n.drive.Drive(status.Linear, status.Angular)
+	return status, nil
+}
+
+// Run steps every interval until the route is finished or ctx is done.
+// Lost fixes and heading errors only stop the car, and it continues when they recover.
+func (n *Navigator) Run(ctx context.Context) error {
+	defer n.drive.Stop()
+	ticker := time.NewTicker(n.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		status, err := n.Step()
+		if errors.Is(err, errNoRoute) {
+			return err
+		}
+		if status.State == NavArrived {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Waypoint returns the index of the current waypoint
+func (n *Navigator) Waypoint() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.waypoint
+}
+
+// lose stops the car and restarts the PID when the fix or heading is lost
+func (n *Navigator) lose() {
+	n.drive.Stop()
+	n.pid.Reset()
+	n.last = time.Time{}
+}
+
+// speed returns the linear speed, it is slowed down by large heading errors and near the waypoint
+func (n *Navigator) speed(herr, dist float64) float64 {
+	// turn in place if the waypoint is behind
+	turn := math.Cos(herr * math.Pi / 180)
+	if turn <= 0 {
+		return 0
+	}
+	speed := n.cfg.MaxSpeed
+	if dist < n.cfg.SlowRadius {
+		r := (dist - n.cfg.ArrivalRadius) / (n.cfg.SlowRadius - n.cfg.ArrivalRadius)
+		speed = n.cfg.MinSpeed + (n.cfg.MaxSpeed-n.cfg.MinSpeed)*math.Max(0, r)
+	}
+	return speed * turn
+}
@@ -0,0 +1,188 @@
+package dev
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shanghuiyang/rpi-devices/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+type fixedYaw struct {
+	yaw float64
+	err error
+}
+
+func (a *fixedYaw) Angles() (yaw, pitch, roll float64, err error) {
+	return a.yaw, 0, 0, a.err
+}
+
+func (a *fixedYaw) Close() error {
+	return nil
+}
+
+func Test_NavigatorWithRoverSimulator(t *testing.T) {
+	start := geo.Point{Lat: 39.958134, Lon: 116.436234}
+	route := []geo.Point{
+		geo.Destination(start, 45, 20),
+		geo.Destination(start, 90, 30),
+		geo.Destination(start, 180, 15),
+		start,
+	}
+	rover := NewRoverSimulator(start, 270, RoverConfig{Seed: 1})
+	drive := NewDifferentialDrive(rover.Left(), rover.Right())
+	nav := NewNavigator(rover, rover, drive, NavigatorConfig{ArrivalRadius: 2})
+	nav.SetRoute(route)
+
+	var status NavStatus
+	var err error
+	reached := 0
+	for i := 0; i < 3000 && status.State != NavArrived; i++ {
+		status, err = nav.Step()
+		assert.NoError(t, err)
+		if status.Waypoint > reached {
+			pos, _ := rover.Pose()
+			assert.Less(t, geo.Distance(pos, route[reached]), 2.5)
+			reached = status.Waypoint
+		}
+		rover.Step(100 * time.Millisecond)
+	}
+	assert.Equal(t, NavArrived, status.State)
+	assert.Equal(t, len(route), nav.Waypoint())
+	assert.Equal(t, 0.0, rover.Left().Velocity())
+	assert.Equal(t, 0.0, rover.Right().Velocity())
+}
+
+func Test_NavigatorFixLost(t *testing.T) {
+	start := geo.Point{Lat: 39.958134, Lon: 116.436234}
+	rover := NewRoverSimulator(start, 0, RoverConfig{})
+	drive := NewDifferentialDrive(rover.Left(), rover.Right())
+	nav := NewNavigator(rover, rover, drive, NavigatorConfig{})
+
+	_, err := nav.Step()
+	assert.Error(t, err)
+
+	nav.SetRoute([]geo.Point{geo.Destination(start, 0, 50)})
+	status, err := nav.Step()
+	assert.NoError(t, err)
+	assert.Equal(t, NavDriving, status.State)
+	assert.InDelta(t, 50, status.Distance, 0.1)
+	assert.InDelta(t, 0, status.Angular, 1e-9)
+	assert.Greater(t, rover.Left().Velocity(), 0.5)
+
+	rover.SetFixLost(true)
+	status, err = nav.Step()
+	assert.NoError(t, err)
+	assert.Equal(t, NavFixLost, status.State)
+	assert.Equal(t, 0.0, rover.Left().Velocity())
+	assert.Equal(t, 0.0, rover.Right().Velocity())
+
+	rover.SetFixLost(false)
+	status, err = nav.Step()
+	assert.NoError(t, err)
+	assert.Equal(t, NavDriving, status.State)
+
+	imu := &fixedYaw{err: errors.New("imu error")}
+	nav = NewNavigator(rover, imu, drive, NavigatorConfig{})
+	nav.SetRoute([]geo.Point{geo.Destination(start, 0, 50)})
+	_, err = nav.Step()
+	assert.Error(t, err)
+	assert.Equal(t, 0.0, rover.Left().Velocity())
+}
+
+func Test_NavigatorSteering(t *testing.T) {
+	start := geo.Point{Lat: 39.958134, Lon: 116.436234}
+	rover := NewRoverSimulator(start, 0, RoverConfig{})
+	imu := &fixedYaw{}
+	nav := NewNavigator(rover, imu, NewDifferentialDrive(rover.Left(), rover.Right()), NavigatorConfig{})
+
+	testCases := []struct {
+		desc    string
+		bearing float64
+		yaw     float64
+		invert  bool
+		offset  float64
+		turn    int
+		moving  bool
+	}{
+		{desc: "ahead", bearing: 0, yaw: 0, turn: 0, moving: true},
+		{desc: "right", bearing: 30, yaw: 0, turn: 1, moving: true},
+		{desc: "left", bearing: 330, yaw: 0, turn: -1, moving: true},
+		{desc: "behind", bearing: 180, yaw: 10, turn: 1, moving: false},
+		{desc: "negative yaw", bearing: 350, yaw: -20, turn: 1, moving: true},
+		{desc: "inverted yaw", bearing: 20, yaw: 20, invert: true, turn: 1, moving: true},
+		{desc: "offset", bearing: 90, yaw: 0, offset: 90, turn: 0, moving: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			nav.cfg.InvertYaw = tc.invert
+			nav.cfg.HeadingOffset = tc.offset
+			imu.yaw = tc.yaw
+			nav.SetRoute([]geo.Point{geo.Destination(start, tc.bearing, 50)})
+			status, err := nav.Step()
+			assert.NoError(t, err)
+			switch tc.turn {
+			case 1:
+				assert.Greater(t, status.Angular, 0.0)
+				assert.Greater(t, rover.Left().Velocity(), rover.Right().Velocity())
+			case -1:
+				assert.Less(t, status.Angular, 0.0)
+				assert.Less(t, rover.Left().Velocity(), rover.Right().Velocity())
+			default:
+				assert.InDelta(t, 0, status.Angular, 1e-6)
+			}
+			if tc.moving {
+				assert.Greater(t, status.Linear, 0.0)
+			} else {
+				assert.Equal(t, 0.0, status.Linear)
+			}
+		})
+	}
+}
+
+func Test_NavigatorWithGPSSimulator(t *testing.T) {
+	points, err := LoadCSV(testCSV)
+	assert.NoError(t, err)
+	points = points[:60]
+	var route []geo.Point
+	for i := 10; i < len(points); i += 10 {
+		route = append(route, points[i].Point())
+	}
+
+	gps, err := NewGPSSimulatorWithConfig(points, GPSSimulatorConfig{
+		Mode:        ReplayStep,
+		Interpolate: true,
+	})
+	assert.NoError(t, err)
+	left, right := NewMotorSimulator(), NewMotorSimulator()
+	nav := NewNavigator(gps, &fixedYaw{}, NewDifferentialDrive(left, right), NavigatorConfig{})
+	nav.SetRoute(route)
+
+	var status NavStatus
+	drove := false
+	for i := 0; i < 1000 && status.State != NavArrived; i++ {
+		status, err = nav.Step()
+		assert.NoError(t, err)
+		if left.Velocity() != 0 || right.Velocity() != 0 {
+			drove = true
+		}
+	}
+	assert.True(t, drove)
+	assert.Equal(t, NavArrived, status.State)
+	assert.Equal(t, len(route), status.Waypoint)
+
+	// the car stops when the fix drops out
+	gps, err = NewGPSSimulatorWithConfig(points, GPSSimulatorConfig{
+		Mode:    ReplayStep,
+		Dropout: 1,
+	})
+	assert.NoError(t, err)
+	nav = NewNavigator(gps, &fixedYaw{}, NewDifferentialDrive(left, right), NavigatorConfig{})
+	nav.SetRoute(route)
+	status, err = nav.Step()
+	assert.NoError(t, err)
+	assert.Equal(t, NavFixLost, status.State)
+	assert.Equal(t, 0.0, left.Velocity())
+	assert.Equal(t, 0.0, right.Velocity())
+}
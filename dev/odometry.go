@@ -0,0 +1,375 @@
+/*
+Odometry estimates the pose of a car by dead reckoning with the encoders of the left and right wheels,
+and corrects the heading with the yaw of an Accelerometer like GY25 or MPU6050.
+
+The pose (x, y, theta) is on a local plane, x is east and y is north in meters, and theta is the heading
+in degrees clockwise from north like the yaw of the sensors. It is tracked by an extended Kalman filter:
+the wheel distances predict the pose with an error growing with the distance, and the yaw corrects the heading.
+The covariance of the pose tells how much it can be trusted.
+
+The pose drifts as the car moves, reset it to a GPS fix when a good one is available,
+the fix becomes the origin of the plane at the first time, and Loc() converts the pose back to lat/lon.
+
+Usage:
+
+	left := dev.NewQuadratureEncoder(5, 6, dev.QuadratureConfig{CountsPerRev: 1320})
+	right := dev.NewQuadratureEncoder(16, 20, dev.QuadratureConfig{CountsPerRev: 1320})
+	gy25, _ := dev.NewGY25("/dev/ttyUSB0", 115200)
+	odo := dev.NewOdometry(left, right, gy25, dev.OdometryConfig{
+		CountsPerMeter: 1320 / (math.Pi * 0.065),
+		TrackWidth:     0.15,
+	})
+	go odo.Run(ctx)
+	for pose := range odo.Subscribe(ctx) {
+		log.Printf("x: %.2f, y: %.2f, theta: %.1f", pose.X, pose.Y, pose.Theta)
+	}
+*/
+package dev
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	defaultOdometryInterval   = 50 * time.Millisecond
+	defaultOdometryWheelNoise = 0.05
+	defaultOdometryYawNoise   = 2
+	poseBufSize               = 16
+)
+
+var errNoOrigin = errors.New("no origin, reset to a gps fix first")
+
+// OdometryConfig ...
+type OdometryConfig struct {
+	// CountsPerMeter converts the counts of the encoders to meters, it is CountsPerRev / (π x WheelDiameter)
+	CountsPerMeter float64
+	// TrackWidth is the distance between the left and right wheels in meters, default 0.15
+	TrackWidth float64
+	// WheelNoise is the standard deviation of the distance of a wheel, in the fraction of the distance, default 0.05
+	WheelNoise float64
+	// YawNoise is the standard deviation of the yaw in degrees, default 2
+	YawNoise float64
+	// InvertYaw is for the sensors whose yaw increases counterclockwise
+	InvertYaw bool
+	// UERE is the user equivalent range error of the GPS in meters, the error of a fix is HDOP x UERE, default 5
+	UERE float64
+	// Interval is the interval of Run(), default 50ms
+	Interval time.Duration
+}
+
+// Pose is the position and heading of a car
+type Pose struct {
+	// X and Y are east and north in meters from the origin
+	X float64
+	Y float64
+	// Theta is the heading in degrees clockwise from north, 0 ~ 360
+	Theta float64
+	// Cov is the covariance of x, y and theta, in meters and degrees
+	Cov  [3][3]float64
+	Time time.Time
+}
+
+// Odometry ...
+type Odometry struct {
+	left  PositionEncoder
+	right PositionEncoder
+	imu   Accelerometer
+	cfg   OdometryConfig
+
+	mu sync.Mutex
+	// x, y in meters and theta in radians
+	x, y, theta float64
+	p           [3][3]float64
+	t           time.Time
+	counts      [2]int64
+	counted     bool
+	// yawOffset maps the yaw to the heading, it is aligned by the first yaw after reset
+	yawOffset float64
+	aligned   bool
+	origin    Fix
+	hasOrigin bool
+	subs      map[chan Pose]struct{}
+}
+
+// NewOdometry creates the odometry at (0, 0) heading north, imu can be nil to use the encoders only
+func NewOdometry(left, right PositionEncoder, imu Accelerometer, cfg OdometryConfig) *Odometry {
+	if cfg.CountsPerMeter <= 0 {
+		cfg.CountsPerMeter = 1
+	}
+	if cfg.TrackWidth <= 0 {
+		cfg.TrackWidth = 0.15
+	}
+	if cfg.WheelNoise <= 0 {
+		cfg.WheelNoise = defaultOdometryWheelNoise
+	}
+	if cfg.YawNoise <= 0 {
+		cfg.YawNoise = defaultOdometryYawNoise
+	}
+	if cfg.UERE <= 0 {
+		cfg.UERE = 5
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultOdometryInterval
+	}
+	return &Odometry{
+		left:  left,
+		right: right,
+		imu:   imu,
+		cfg:   cfg,
+		subs:  make(map[chan Pose]struct{}),
+	}
+}
+
+// Pose returns the current pose
+func (o *Odometry) Pose() Pose {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.pose()
+}
+
+// Update moves the pose by the counts of the left and right encoders at the time t.
+// The first update after creating only records the counts.
+// It is used by Step(), and can be used to feed the counts from other sources.
+func (o *Odometry) Update(left, right int64, t time.Time) Pose {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.update(left, right, t)
+	return o.publish()
+}
+
+// UpdateYaw corrects the heading with the yaw in degrees.
+// The first yaw after creating or resetting is aligned to the heading instead.
+func (o *Odometry) UpdateYaw(yaw float64) Pose {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.updateYaw(yaw)
+	return o.publish()
+}
+
+// Step reads the encoders and the yaw, and updates the pose.
+// The pose is still updated by the encoders if the yaw can't be read.
+func (o *Odometry) Step() (Pose, error) {
+	left, right := o.left.Position(), o.right.Position()
+	var yaw float64
+	var err error
+	if o.imu != nil {
+		yaw, _, _, err = o.imu.Angles()
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.update(left, right, time.Now())
+	if err != nil {
+		return o.publish(), fmt.Errorf("failed to read yaw: %w", err)
+	}
+	if o.imu != nil {
+		o.updateYaw(yaw)
+	}
+	return o.publish(), nil
+}
+
+// Run steps every Interval until ctx is done
+func (o *Odometry) Run(ctx context.Context) error {
+	ticker := time.NewTicker(o.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		_, _ = o.Step()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reset sets the pose and its covariance, the yaw is realigned to the heading
+func (o *Odometry) Reset(pose Pose) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.x, o.y, o.theta = pose.X, pose.Y, pose.Theta*math.Pi/180
+	o.p = pose.Cov
+	for i := 0; i < 3; i++ {
+		o.p[i][2] *= math.Pi / 180
+		o.p[2][i] *= math.Pi / 180
+	}
+	o.aligned = false
+	o.publish()
+}
+
+// ResetToFix moves the position to the fix with the variance of the fix, and keeps the heading.
+// The first fix becomes the origin of the local plane.
+func (o *Odometry) ResetToFix(fix Fix) error {
+	if !fix.Valid() {
+		return errInvalidFix
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if !o.hasOrigin {
+		o.origin, o.hasOrigin = fix, true
+	}
+	o.x = (fix.Lon - o.origin.Lon) * metersPerDegree * math.Cos(o.origin.Lat*math.Pi/180)
+	o.y = (fix.Lat - o.origin.Lat) * metersPerDegree
+	hdop := fix.HDOP
+	if hdop <= 0 {
+		hdop = 1
+	}
+	r := (hdop * o.cfg.UERE) * (hdop * o.cfg.UERE)
+	// the position is independent of the heading after the reset
+	ptt := o.p[2][2]
+	o.p = [3][3]float64{{r, 0, 0}, {0, r, 0}, {0, 0, ptt}}
+	o.publish()
+	return nil
+}
+
+// Loc returns the lat/lon of the pose, it requires an origin set by ResetToFix()
+func (o *Odometry) Loc() (lat, lon float64, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if !o.hasOrigin {
+		return 0, 0, errNoOrigin
+	}
+	lat = o.origin.Lat + o.y/metersPerDegree
+	lon = o.origin.Lon + o.x/(metersPerDegree*math.Cos(o.origin.Lat*math.Pi/180))
+	return lat, lon, nil
+}
+
+// Subscribe returns a channel receiving every updated pose.
+// The channel is closed when ctx is done, and poses are dropped if the receiver falls behind.
+func (o *Odometry) Subscribe(ctx context.Context) <-chan Pose {
+	ch := make(chan Pose, poseBufSize)
+	o.mu.Lock()
+	o.subs[ch] = struct{}{}
+	o.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		delete(o.subs, ch)
+		close(ch)
+	}()
+	return ch
+}
+
+// update predicts the pose by the counts, o.mu must be held
+func (o *Odometry) update(left, right int64, t time.Time) {
+	o.t = t
+	if !o.counted {
+		o.counts, o.counted = [2]int64{left, right}, true
+		return
+	}
+	dl := float64(left-o.counts[0]) / o.cfg.CountsPerMeter
+	dr := float64(right-o.counts[1]) / o.cfg.CountsPerMeter
+	o.counts = [2]int64{left, right}
+	if dl == 0 && dr == 0 {
+		return
+	}
+
+	w := o.cfg.TrackWidth
+	d := (dl + dr) / 2
+	// the left wheel running farther turns clockwise
+	dtheta := (dl - dr) / w
+	mid := o.theta + dtheta/2
+	sin, cos := math.Sin(mid), math.Cos(mid)
+	o.x += d * sin
+	o.y += d * cos
+	o.theta = normalizeRadians(o.theta + dtheta)
+
+	// P = F P F' + G Q G', F is the jacobian of the pose, and G is the one of the wheel distances
+	f := [3][3]float64{
+		{1, 0, d * cos},
+		{0, 1, -d * sin},
+		{0, 0, 1},
+	}
+	g := [3][2]float64{
+		{sin/2 + d*cos/(2*w), sin/2 - d*cos/(2*w)},
+		{cos/2 - d*sin/(2*w), cos/2 + d*sin/(2*w)},
+		{1 / w, -1 / w},
+	}
+	k := o.cfg.WheelNoise * o.cfg.WheelNoise
+	q := [2]float64{k * math.Abs(dl), k * math.Abs(dr)}
+	var p [3][3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for m := 0; m < 3; m++ {
+				for n := 0; n < 3; n++ {
+					p[i][j] += f[i][m] * o.p[m][n] * f[j][n]
+				}
+			}
+			p[i][j] += g[i][0]*q[0]*g[j][0] + g[i][1]*q[1]*g[j][1]
+		}
+	}
+	o.p = p
+}
+
+// updateYaw corrects theta with the yaw, o.mu must be held
+func (o *Odometry) updateYaw(yaw float64) {
+	if o.cfg.InvertYaw {
+		yaw = -yaw
+	}
+	z := yaw * math.Pi / 180
+	if !o.aligned {
+		o.yawOffset, o.aligned = o.theta-z, true
+		return
+	}
+	r := o.cfg.YawNoise * math.Pi / 180
+	s := o.p[2][2] + r*r
+	innovation := normalizeRadians(z + o.yawOffset - o.theta)
+	var k [3]float64
+	for i := range k {
+		k[i] = o.p[i][2] / s
+	}
+	o.x += k[0] * innovation
+	o.y += k[1] * innovation
+	o.theta = normalizeRadians(o.theta + k[2]*innovation)
+	// P = (I - K H) P, H = [0, 0, 1]
+	var p [3][3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			p[i][j] = o.p[i][j] - k[i]*o.p[2][j]
+		}
+	}
+	o.p = p
+}
+
+// pose returns the current pose in degrees, o.mu must be held
+func (o *Odometry) pose() Pose {
+	pose := Pose{
+		X:     o.x,
+		Y:     o.y,
+		Theta: math.Mod(o.theta*180/math.Pi+360, 360),
+		Cov:   o.p,
+		Time:  o.t,
+	}
+	for i := 0; i < 3; i++ {
+		pose.Cov[i][2] *= 180 / math.Pi
+		pose.Cov[2][i] *= 180 / math.Pi
+	}
+	return pose
+}
+
+// publish sends the current pose to the subscribers without blocking, o.mu must be held
+func (o *Odometry) publish() Pose {
+	pose := o.pose()
+	for ch := range o.subs {
+		select {
+		case ch <- pose:
+		default:
+		}
+	}
+	return pose
+}
+
+// normalizeRadians returns the angle in -π ~ π
+func normalizeRadians(a float64) float64 {
+	a = math.Mod(a+math.Pi, 2*math.Pi)
+	if a < 0 {
+		a += 2 * math.Pi
+	}
+	return a - math.Pi
+}
@@ -0,0 +1,189 @@
+package dev
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/shanghuiyang/rpi-devices/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeEncoder struct {
+	pos int64
+}
+
+func (e *fakeEncoder) Position() int64 {
+	return e.pos
+}
+
+// arcCounts returns the counts of the left and right wheels for driving the distance on a circle of the radius,
+// positive radius turns clockwise
+func arcCounts(dist, radius, track, countsPerMeter float64) (left, right int64) {
+	angle := dist / radius
+	l := (radius + track/2) * angle
+	r := (radius - track/2) * angle
+	return int64(math.Round(l * countsPerMeter)), int64(math.Round(r * countsPerMeter))
+}
+
+func Test_OdometryStraight(t *testing.T) {
+	odo := NewOdometry(nil, nil, nil, OdometryConfig{CountsPerMeter: 100, TrackWidth: 0.2})
+	start := time.Now()
+	odo.Update(0, 0, start)
+	var pose Pose
+	for i := 1; i <= 100; i++ {
+		pose = odo.Update(int64(i*10), int64(i*10), start.Add(time.Duration(i)*100*time.Millisecond))
+	}
+	assert.InDelta(t, 0, pose.X, 1e-9)
+	assert.InDelta(t, 10, pose.Y, 1e-9)
+	assert.InDelta(t, 0, pose.Theta, 1e-9)
+	assert.Equal(t, start.Add(10*time.Second), pose.Time)
+
+	// the errors grow along and across the track
+	assert.Greater(t, pose.Cov[0][0], pose.Cov[1][1])
+	assert.Greater(t, pose.Cov[1][1], 0.0)
+	assert.Greater(t, pose.Cov[2][2], 0.0)
+	// the variance of y is the mean of the variances of the wheels
+	assert.InDelta(t, 0.05*0.05*(10+10)/4, pose.Cov[1][1], 1e-9)
+}
+
+func Test_OdometryArc(t *testing.T) {
+	const (
+		track  = 0.2
+		cpm    = 1000.0
+		radius = 2.0
+	)
+	odo := NewOdometry(nil, nil, nil, OdometryConfig{CountsPerMeter: cpm, TrackWidth: track})
+	odo.Update(0, 0, time.Now())
+
+	// a quarter circle clockwise around (2, 0) in 100 steps
+	quarter := math.Pi * radius / 2
+	var pose Pose
+	for i := 1; i <= 100; i++ {
+		l, r := arcCounts(quarter*float64(i)/100, radius, track, cpm)
+		pose = odo.Update(l, r, time.Now())
+	}
+	assert.InDelta(t, 2, pose.X, 0.01)
+	assert.InDelta(t, 2, pose.Y, 0.01)
+	assert.InDelta(t, 90, pose.Theta, 0.1)
+
+	// backward to the start
+	for i := 99; i >= 0; i-- {
+		l, r := arcCounts(quarter*float64(i)/100, radius, track, cpm)
+		pose = odo.Update(l, r, time.Now())
+	}
+	assert.InDelta(t, 0, pose.X, 0.01)
+	assert.InDelta(t, 0, pose.Y, 0.01)
+	assert.InDelta(t, 0, math.Mod(pose.Theta+180, 360)-180, 0.1)
+}
+
+func Test_OdometryYaw(t *testing.T) {
+	const track = 0.2
+	drive := func(odo *Odometry, yaw bool) Pose {
+		odo.Update(0, 0, time.Now())
+		if yaw {
+			odo.UpdateYaw(30)
+		}
+		var pose Pose
+		for i := 1; i <= 100; i++ {
+			// the right wheel slips, the encoders tell turning right but the car runs straight
+			pose = odo.Update(int64(i*10), int64(i*9), time.Now())
+			if yaw {
+				pose = odo.UpdateYaw(30)
+			}
+		}
+		return pose
+	}
+
+	encoders := drive(NewOdometry(nil, nil, nil, OdometryConfig{CountsPerMeter: 100, TrackWidth: track}), false)
+	fused := drive(NewOdometry(nil, nil, nil, OdometryConfig{CountsPerMeter: 100, TrackWidth: track}), true)
+	assert.Greater(t, encoders.Theta, 100.0)
+	assert.InDelta(t, 0, math.Mod(fused.Theta+180, 360)-180, 3)
+	assert.Less(t, fused.Cov[2][2], 2.0*2.0)
+	assert.Less(t, math.Abs(fused.X), 1.0)
+	assert.InDelta(t, 9.5, fused.Y, 0.5)
+
+	// spinning clockwise in place with a sensor whose yaw increases counterclockwise
+	odo := NewOdometry(nil, nil, nil, OdometryConfig{CountsPerMeter: 1000, TrackWidth: track, InvertYaw: true})
+	odo.Update(0, 0, time.Now())
+	odo.UpdateYaw(0)
+	var pose Pose
+	for i := 1; i <= 10; i++ {
+		// 9 degrees every step, and the right wheel slips a little
+		n := int64(math.Round(float64(i) * 9 * math.Pi / 180 * track / 2 * 1000))
+		odo.Update(n, -n*9/10, time.Now())
+		pose = odo.UpdateYaw(float64(-9 * i))
+	}
+	assert.InDelta(t, 90, pose.Theta, 2)
+}
+
+func Test_OdometryResetToFix(t *testing.T) {
+	odo := NewOdometry(nil, nil, nil, OdometryConfig{CountsPerMeter: 100, UERE: 3})
+	_, _, err := odo.Loc()
+	assert.Error(t, err)
+	assert.Error(t, odo.ResetToFix(Fix{}))
+
+	origin := geo.Point{Lat: 39.958134, Lon: 116.436234}
+	assert.NoError(t, odo.ResetToFix(Fix{Lat: origin.Lat, Lon: origin.Lon, HDOP: 2, Type: Fix3D}))
+	pose := odo.Pose()
+	assert.Equal(t, 0.0, pose.X)
+	assert.Equal(t, 0.0, pose.Y)
+	assert.Equal(t, 36.0, pose.Cov[0][0])
+
+	odo.Update(0, 0, time.Now())
+	odo.Update(1000, 1000, time.Now())
+	lat, lon, err := odo.Loc()
+	assert.NoError(t, err)
+	assert.InDelta(t, 10, geo.Distance(origin, geo.Point{Lat: lat, Lon: lon}), 0.01)
+	assert.InDelta(t, 0, geo.Bearing(origin, geo.Point{Lat: lat, Lon: lon}), 0.1)
+
+	// a fix 20m to the east
+	p := geo.Destination(origin, 90, 20)
+	assert.NoError(t, odo.ResetToFix(Fix{Lat: p.Lat, Lon: p.Lon, HDOP: 1, Type: Fix3D}))
+	pose = odo.Pose()
+	assert.InDelta(t, 20, pose.X, 0.01)
+	assert.InDelta(t, 0, pose.Y, 0.01)
+	assert.Equal(t, 9.0, pose.Cov[1][1])
+	assert.Equal(t, 0.0, pose.Cov[0][2])
+
+	odo.Reset(Pose{X: 1, Y: 2, Theta: 90})
+	pose = odo.Pose()
+	assert.Equal(t, 1.0, pose.X)
+	assert.Equal(t, 2.0, pose.Y)
+	assert.InDelta(t, 90, pose.Theta, 1e-9)
+}
+
+func Test_OdometryStep(t *testing.T) {
+	left, right := &fakeEncoder{}, &fakeEncoder{}
+	imu := &fixedYaw{yaw: 45}
+	odo := NewOdometry(left, right, imu, OdometryConfig{CountsPerMeter: 100})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	poses := odo.Subscribe(ctx)
+
+	_, err := odo.Step()
+	assert.NoError(t, err)
+	left.pos, right.pos = 100, 100
+	pose, err := odo.Step()
+	assert.NoError(t, err)
+	assert.InDelta(t, 1, pose.Y, 1e-9)
+
+	imu.err = errors.New("boom")
+	left.pos, right.pos = 200, 200
+	pose, err = odo.Step()
+	assert.Error(t, err)
+	assert.InDelta(t, 2, pose.Y, 1e-9)
+
+	assert.Len(t, poses, 3)
+	assert.Equal(t, 0.0, (<-poses).Y)
+	assert.InDelta(t, 1, (<-poses).Y, 1e-9)
+	cancel()
+	for range poses {
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, odo.Run(ctx), context.DeadlineExceeded)
+}
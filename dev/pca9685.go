@@ -0,0 +1,299 @@
+/*
+PCA9685 is a 16-channel 12-bit PWM controller on the i2c bus.
+It adds pwm outputs for servos, motor drivers and leds when the hardware pwm pins (gpio 12, 13, 18 and 19) run out.
+
+All channels share the same frequency, 24 ~ 1526Hz, and 50Hz is used by default for servos.
+Every channel implements the Pin interface, so it can be used by the drivers taking pwm pins:
+  - servos: pca.Servo(ch, cfg), or NewServoWithPin(pca.Channel(ch), cfg)
+  - motors: pca.Motor(in1, in2, en), or NewL298NWithPins() with the channels as ena and enb
+  - leds: pca.Led(ch), Fade() dims it
+
+Connect to Raspberry Pi:
+  - VCC: any 3.3v pin
+  - GND: any GND pin
+  - SDA: GPIO-2 (SDA)
+  - SCL: GPIO-3 (SCL)
+  - V+ : 5v power for servos
+
+Usage:
+
+	pca, err := dev.NewPCA9685(0x40, 50)
+	servo, err := pca.Servo(0, dev.SG90Config)
+	servo.Roll(45)
+	ena, err := pca.Channel(1)
+	enb, err := pca.Channel(2)
+	l298n := dev.NewL298NWithPins(dev.NewRpioPin(17), dev.NewRpioPin(23), dev.NewRpioPin(27), dev.NewRpioPin(22), ena, enb)
+	led, err := pca.Led(15)
+	led.Fade(3)
+*/
+package dev
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// PCA9685Addr is the default i2c address of PCA9685
+	PCA9685Addr = 0x40
+
+	pca9685Mode1       = 0x00
+	pca9685Mode2       = 0x01
+	pca9685Led0        = 0x06
+	pca9685AllLedOffH  = 0xFD
+	pca9685Prescale    = 0xFE
+	pca9685Restart     = 0x80
+	pca9685AutoInc     = 0x20
+	pca9685Sleep       = 0x10
+	pca9685AllCall     = 0x01
+	pca9685OutDrv      = 0x04
+	pca9685FullBit     = 0x10
+	pca9685Osc         = 25000000
+	pca9685Steps       = 4096
+	pca9685Channels    = 16
+	pca9685WakeDelay   = 500 * time.Microsecond
+	defaultPCA9685Freq = 50
+)
+
+var errInvalidChannel = errors.New("invalid channel")
+
+// PCA9685 ...
+type PCA9685 struct {
+	dev      I2CDevice
+	mu       sync.Mutex
+	freq     float64
+	channels [pca9685Channels]*PCA9685Channel
+}
+
+// NewPCA9685 opens the PCA9685 at the address on the default i2c bus, and sets the frequency in Hz
+func NewPCA9685(addr int, freq float64) (*PCA9685, error) {
+	dev, err := OpenI2C(DefaultI2CBus, addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewPCA9685WithI2C(dev, freq)
+}
+
+// NewPCA9685WithI2C ...
+func NewPCA9685WithI2C(dev I2CDevice, freq float64) (*PCA9685, error) {
+	if freq <= 0 {
+		freq = defaultPCA9685Freq
+	}
+	p := &PCA9685{dev: dev}
+	for i := range p.channels {
+		p.channels[i] = &PCA9685Channel{pca: p, ch: i}
+	}
+	if err := p.dev.WriteReg(pca9685AllLedOffH, []byte{pca9685FullBit}); err != nil {
+		return nil, fmt.Errorf("failed to reset pca9685: %w", err)
+	}
+	if err := p.dev.WriteReg(pca9685Mode2, []byte{pca9685OutDrv}); err != nil {
+		return nil, fmt.Errorf("failed to reset pca9685: %w", err)
+	}
+	if err := p.SetFreq(freq); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// SetFreq sets the pwm frequency of all channels, 24 ~ 1526Hz
+func (p *PCA9685) SetFreq(freq float64) error {
+	prescale := math.Round(pca9685Osc/(pca9685Steps*freq)) - 1
+	prescale = math.Max(3, math.Min(255, prescale))
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// the prescaler can only be set in sleep mode
+	if err := p.dev.WriteReg(pca9685Mode1, []byte{pca9685AutoInc | pca9685AllCall | pca9685Sleep}); err != nil {
+		return fmt.Errorf("failed to set pca9685 frequency: %w", err)
+	}
+	if err := p.dev.WriteReg(pca9685Prescale, []byte{byte(prescale)}); err != nil {
+		return fmt.Errorf("failed to set pca9685 frequency: %w", err)
+	}
+	if err := p.dev.WriteReg(pca9685Mode1, []byte{pca9685AutoInc | pca9685AllCall}); err != nil {
+		return fmt.Errorf("failed to set pca9685 frequency: %w", err)
+	}
+	// wait for the oscillator to be stable before restarting the outputs
+	time.Sleep(pca9685WakeDelay)
+	if err := p.dev.WriteReg(pca9685Mode1, []byte{pca9685Restart | pca9685AutoInc | pca9685AllCall}); err != nil {
+		return fmt.Errorf("failed to set pca9685 frequency: %w", err)
+	}
+	p.freq = pca9685Osc / (pca9685Steps * (prescale + 1))
+	return nil
+}
+
+// Freq returns the actual pwm frequency, which may differ a little from the one set
+func (p *PCA9685) Freq() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.freq
+}
+
+// SetPWM sets the channel to turn on at the step on and turn off at the step off, both are 0 ~ 4095
+func (p *PCA9685) SetPWM(ch int, on, off uint16) error {
+	if ch < 0 || ch >= pca9685Channels {
+		return errInvalidChannel
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.dev.WriteReg(byte(pca9685Led0+4*ch), []byte{byte(on), byte(on >> 8), byte(off), byte(off >> 8)})
+}
+
+// SetDuty sets the duty cycle of the channel, 0 ~ 4096. 0 is fully off, and 4096 is fully on.
+func (p *PCA9685) SetDuty(ch int, duty uint16) error {
+	switch {
+	case duty == 0:
+		return p.SetPWM(ch, 0, pca9685FullBit<<8)
+	case duty >= pca9685Steps:
+		return p.SetPWM(ch, pca9685FullBit<<8, 0)
+	default:
+		return p.SetPWM(ch, 0, duty)
+	}
+}
+
+// SetPulse sets the high time of the channel in every period
+func (p *PCA9685) SetPulse(ch int, pulse time.Duration) error {
+	duty := math.Round(pulse.Seconds() * p.Freq() * pca9685Steps)
+	return p.SetDuty(ch, uint16(math.Min(pca9685Steps, duty)))
+}
+
+// Channel returns the channel 0 ~ 15 as a pwm pin
+func (p *PCA9685) Channel(ch int) (*PCA9685Channel, error) {
+	if ch < 0 || ch >= pca9685Channels {
+		return nil, fmt.Errorf("%w: %d", errInvalidChannel, ch)
+	}
+	return p.channels[ch], nil
+}
+
+// Servo creates a servo on the channel, the frequency of the servo is set to the frequency of the PCA9685
+func (p *PCA9685) Servo(ch int, cfg ServoConfig) (*Servo, error) {
+	pin, err := p.Channel(ch)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Freq = int(math.Round(p.Freq()))
+	return NewServoWithPin(pin, cfg), nil
+}
+
+// Motor creates a motor driver on the channels of in1, in2 and en for the boards like L298N
+func (p *PCA9685) Motor(in1, in2, en int) (*L298NMotor, error) {
+	var pins [3]*PCA9685Channel
+	for i, ch := range []int{in1, in2, en} {
+		pin, err := p.Channel(ch)
+		if err != nil {
+			return nil, err
+		}
+		pins[i] = pin
+	}
+	return newL298NMotor(pins[0], pins[1], pins[2], L298NConfig{
+		SlewRate: defaultL298NSlewRate,
+		Interval: defaultL298NInterval,
+	}), nil
+}
+
+// Led creates a led on the channel
+func (p *PCA9685) Led(ch int) (*LedImp, error) {
+	pin, err := p.Channel(ch)
+	if err != nil {
+		return nil, err
+	}
+	return NewLedImpWithPin(pin), nil
+}
+
+// Close turns off all channels and closes the device
+func (p *PCA9685) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	err := p.dev.WriteReg(pca9685AllLedOffH, []byte{pca9685FullBit})
+	if cerr := p.dev.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// PCA9685Channel is a channel of PCA9685, it implements Pin interface as a pwm output.
+// Input, pull and edge detection are not supported, and the errors of i2c are ignored
+// since the methods of Pin don't return errors.
+// Freq is ignored too, since all channels share the frequency set by PCA9685.SetFreq().
+type PCA9685Channel struct {
+	pca *PCA9685
+	ch  int
+
+	mu    sync.Mutex
+	level LogicLevel
+}
+
+// Input ...
+func (c *PCA9685Channel) Input() {}
+
+// Output ...
+func (c *PCA9685Channel) Output() {}
+
+// Read returns the level last written
+func (c *PCA9685Channel) Read() LogicLevel {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.level
+}
+
+// Write turns the channel fully on or off
+func (c *PCA9685Channel) Write(level LogicLevel) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.level = level
+	duty := uint16(0)
+	if level == High {
+		duty = pca9685Steps
+	}
+	_ = c.pca.SetDuty(c.ch, duty)
+}
+
+// High ...
+func (c *PCA9685Channel) High() {
+	c.Write(High)
+}
+
+// Low ...
+func (c *PCA9685Channel) Low() {
+	c.Write(Low)
+}
+
+// PullUp ...
+func (c *PCA9685Channel) PullUp() {}
+
+// PullDown ...
+func (c *PCA9685Channel) PullDown() {}
+
+// PullOff ...
+func (c *PCA9685Channel) PullOff() {}
+
+// Detect ...
+func (c *PCA9685Channel) Detect(edge Edge) {}
+
+// EdgeDetected ...
+func (c *PCA9685Channel) EdgeDetected() bool {
+	return false
+}
+
+// Pwm ...
+func (c *PCA9685Channel) Pwm() {}
+
+// Freq ...
+func (c *PCA9685Channel) Freq(freq int) {}
+
+// DutyCycle sets the duty cycle to dutyLen/cycleLen in 12-bit resolution
+func (c *PCA9685Channel) DutyCycle(dutyLen, cycleLen uint32) {
+	if cycleLen == 0 {
+		return
+	}
+	duty := math.Round(float64(dutyLen) * pca9685Steps / float64(cycleLen))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.level = Low
+	if duty > 0 {
+		c.level = High
+	}
+	_ = c.pca.SetDuty(c.ch, uint16(math.Min(pca9685Steps, duty)))
+}
@@ -0,0 +1,149 @@
+package dev
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pca9685Duty returns the last duty written to the channel, 4096 for fully on
+func pca9685Duty(t *testing.T, i2c *FakeI2CDevice, ch int) int {
+	w := i2c.RegWrites(byte(pca9685Led0 + 4*ch))
+	if !assert.NotEmpty(t, w) {
+		return -1
+	}
+	last := w[len(w)-1]
+	on, off := int(last[0])|int(last[1])<<8, int(last[2])|int(last[3])<<8
+	switch {
+	case off&0x1000 != 0:
+		return 0
+	case on&0x1000 != 0:
+		return 4096
+	default:
+		return off - on
+	}
+}
+
+func Test_PCA9685(t *testing.T) {
+	i2c := NewFakeI2CDevice()
+	pca, err := NewPCA9685WithI2C(i2c, 50)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{{0x10}}, i2c.RegWrites(pca9685AllLedOffH))
+	assert.Equal(t, [][]byte{{0x04}}, i2c.RegWrites(pca9685Mode2))
+	assert.Equal(t, [][]byte{{0x31}, {0x21}, {0xA1}}, i2c.RegWrites(pca9685Mode1))
+	assert.Equal(t, [][]byte{{121}}, i2c.RegWrites(pca9685Prescale))
+	assert.InDelta(t, 50.03, pca.Freq(), 0.01)
+
+	assert.NoError(t, pca.SetFreq(1000))
+	assert.Equal(t, []byte{5}, i2c.RegWrites(pca9685Prescale)[1])
+	assert.NoError(t, pca.SetFreq(1))
+	assert.Equal(t, []byte{255}, i2c.RegWrites(pca9685Prescale)[2])
+	assert.NoError(t, pca.SetFreq(50))
+
+	assert.NoError(t, pca.SetPWM(3, 0x123, 0x456))
+	assert.Equal(t, [][]byte{{0x23, 0x01, 0x56, 0x04}}, i2c.RegWrites(0x06+4*3))
+	assert.NoError(t, pca.SetDuty(15, 2048))
+	assert.Equal(t, [][]byte{{0, 0, 0x00, 0x08}}, i2c.RegWrites(0x42))
+	assert.NoError(t, pca.SetDuty(15, 0))
+	assert.Equal(t, 0, pca9685Duty(t, i2c, 15))
+	assert.NoError(t, pca.SetDuty(15, 5000))
+	assert.Equal(t, 4096, pca9685Duty(t, i2c, 15))
+	assert.NoError(t, pca.SetPulse(0, 1500*time.Microsecond))
+	assert.Equal(t, 307, pca9685Duty(t, i2c, 0))
+
+	assert.Error(t, pca.SetPWM(16, 0, 0))
+	_, err = pca.Channel(-1)
+	assert.ErrorIs(t, err, errInvalidChannel)
+	_, err = pca.Servo(16, SG90Config)
+	assert.ErrorIs(t, err, errInvalidChannel)
+	_, err = pca.Motor(0, 1, 16)
+	assert.ErrorIs(t, err, errInvalidChannel)
+	_, err = pca.Led(-1)
+	assert.ErrorIs(t, err, errInvalidChannel)
+
+	i2c.SetErr(errors.New("bus error"))
+	assert.Error(t, pca.SetDuty(0, 100))
+	assert.Error(t, pca.SetFreq(50))
+	i2c.SetErr(nil)
+
+	assert.NoError(t, pca.Close())
+	assert.True(t, i2c.Closed())
+	assert.Len(t, i2c.RegWrites(pca9685AllLedOffH), 2)
+
+	i2c = NewFakeI2CDevice()
+	i2c.SetErr(errors.New("bus error"))
+	_, err = NewPCA9685WithI2C(i2c, 50)
+	assert.Error(t, err)
+}
+
+func Test_PCA9685Channel(t *testing.T) {
+	i2c := NewFakeI2CDevice()
+	pca, err := NewPCA9685WithI2C(i2c, 0)
+	assert.NoError(t, err)
+	ch, err := pca.Channel(2)
+	assert.NoError(t, err)
+	var pin Pin = ch
+
+	pin.High()
+	assert.Equal(t, High, pin.Read())
+	assert.Equal(t, 4096, pca9685Duty(t, i2c, 2))
+	pin.Low()
+	assert.Equal(t, Low, pin.Read())
+	assert.Equal(t, 0, pca9685Duty(t, i2c, 2))
+	pin.DutyCycle(25, 100)
+	assert.Equal(t, 1024, pca9685Duty(t, i2c, 2))
+	pin.DutyCycle(1, 3)
+	assert.Equal(t, 1365, pca9685Duty(t, i2c, 2))
+	pin.DutyCycle(0, 0)
+	assert.Equal(t, 1365, pca9685Duty(t, i2c, 2))
+}
+
+func Test_PCA9685Adapters(t *testing.T) {
+	i2c := NewFakeI2CDevice()
+	pca, err := NewPCA9685WithI2C(i2c, 50)
+	assert.NoError(t, err)
+
+	// servo
+	s, err := pca.Servo(0, SG90Config)
+	assert.NoError(t, err)
+	var servo ServoMotor = s
+	servo.Roll(0)
+	// 1450us in a period of 20ms
+	assert.InDelta(t, 1450.0/20000*4096, pca9685Duty(t, i2c, 0), 1)
+	servo.Roll(90)
+	assert.InDelta(t, 500.0/20000*4096, pca9685Duty(t, i2c, 0), 1)
+
+	// motor
+	m, err := pca.Motor(4, 5, 6)
+	assert.NoError(t, err)
+	defer m.Close()
+	var motor MotorDriver = m
+	motor.SetSpeed(50)
+	motor.Forward()
+	assert.Equal(t, 4096, pca9685Duty(t, i2c, 4))
+	assert.Equal(t, 0, pca9685Duty(t, i2c, 5))
+	assert.Eventually(t, func() bool {
+		return pca9685Duty(t, i2c, 6) == 2048
+	}, time.Second, 10*time.Millisecond)
+	m.Brake()
+	assert.Equal(t, 4096, pca9685Duty(t, i2c, 5))
+
+	// l298n with the enable lines on the pca9685
+	ena, _ := pca.Channel(7)
+	enb, _ := pca.Channel(8)
+	l298n := NewL298NWithPins(NewFakePin(), NewFakePin(), NewFakePin(), NewFakePin(), ena, enb)
+	defer l298n.Close()
+	l298n.MotorB.Brake()
+	assert.Equal(t, 4096, pca9685Duty(t, i2c, 8))
+
+	// led
+	l, err := pca.Led(15)
+	assert.NoError(t, err)
+	var led Led = l
+	led.On()
+	assert.Equal(t, 4096, pca9685Duty(t, i2c, 15))
+	led.Off()
+	assert.Equal(t, 0, pca9685Duty(t, i2c, 15))
+}
@@ -35,12 +35,9 @@ package dev
 
 import (
 	"fmt"
-
-	"golang.org/x/exp/io/i2c"
 )
 
 const (
-	pcf8591Dev  = "/dev/i2c-1"
 	pcf8591Addr = 0x48
 	ctrAIN0     = 0x40
 	ctrAIN1     = 0x41
@@ -50,18 +47,23 @@ const (
 
 // PCF8591 ...
 type PCF8591 struct {
-	dev *i2c.Device
+	dev I2CDevice
 }
 
 // NewPCF8591 ...
 func NewPCF8591() (*PCF8591, error) {
-	dev, err := i2c.Open(&i2c.Devfs{Dev: pcf8591Dev}, pcf8591Addr)
+	dev, err := OpenI2C(DefaultI2CBus, pcf8591Addr)
 	if err != nil {
 		return nil, err
 	}
+	return NewPCF8591WithI2C(dev), nil
+}
+
+// NewPCF8591WithI2C ...
+func NewPCF8591WithI2C(dev I2CDevice) *PCF8591 {
 	return &PCF8591{
 		dev: dev,
-	}, nil
+	}
 }
 
 // ReadAIN0 ...
@@ -0,0 +1,59 @@
+package dev
+
+import (
+	"math"
+)
+
+// PID is a proportional-integral-derivative controller
+type PID struct {
+	Kp float64
+	Ki float64
+	Kd float64
+	// Min and Max limit the output, and the integral stops accumulating at the limits
+	Min float64
+	Max float64
+
+	integral float64
+	prev     float64
+	started  bool
+}
+
+// NewPID ...
+func NewPID(kp, ki, kd, min, max float64) *PID {
+	return &PID{
+		Kp:  kp,
+		Ki:  ki,
+		Kd:  kd,
+		Min: min,
+		Max: max,
+	}
+}
+
+// Update returns the output for the error after dt seconds from the last update
+func (p *PID) Update(err, dt float64) float64 {
+	var deriv float64
+	if p.started && dt > 0 {
+		deriv = (err - p.prev) / dt
+	}
+	p.prev = err
+	p.started = true
+
+	integral := p.integral
+	if dt > 0 {
+		integral += err * dt
+	}
+	out := p.Kp*err + p.Ki*integral + p.Kd*deriv
+	if out > p.Max || out < p.Min {
+		// anti-windup
+		return math.Max(p.Min, math.Min(p.Max, out))
+	}
+	p.integral = integral
+	return out
+}
+
+// Reset clears the integral and derivative states
+func (p *PID) Reset() {
+	p.integral = 0
+	p.prev = 0
+	p.started = false
+}
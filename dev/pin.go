@@ -0,0 +1,93 @@
+package dev
+
+import (
+	"time"
+)
+
+// Edge is the kind of level transition a pin can detect.
+type Edge int
+
+const (
+	NoEdge Edge = iota
+	RiseEdge
+	FallEdge
+	AnyEdge
+)
+
+// PinMode is the mode of a pin
+type PinMode int
+
+const (
+	InputMode PinMode = iota
+	OutputMode
+	PwmMode
+)
+
+// Pull is the internal pull resistor of a pin
+type Pull int
+
+const (
+	PullNone Pull = iota
+	PullUpMode
+	PullDownMode
+)
+
+// EdgeEvent is an edge detected on a pin
+type EdgeEvent struct {
+	// Edge is RiseEdge or FallEdge
+	Edge Edge
+	// Time is the time when the edge was detected
+	Time time.Time
+}
+
+// Pin is a single gpio pin.
+// All drivers in this package talk to the hardware through Pin,
+// so they can run on a real raspberry pi using the rpio or gpiochip backend,
+// or on any other machine using FakePin.
+type Pin interface {
+	// Input sets the pin to input mode
+	Input()
+	// Output sets the pin to output mode
+	Output()
+	// Read reads the level of the pin
+	Read() LogicLevel
+	// Write sets the level of the pin
+	Write(level LogicLevel)
+	// High sets the pin to high level
+	High()
+	// Low sets the pin to low level
+	Low()
+	// PullUp enables the internal pull-up resistor
+	PullUp()
+	// PullDown enables the internal pull-down resistor
+	PullDown()
+	// PullOff disables the internal pull resistors
+	PullOff()
+	// Detect enables edge detection on the pin,
+	// use NoEdge to disable it.
+	Detect(edge Edge)
+	// EdgeDetected returns true if an edge has been detected since the last call
+	EdgeDetected() bool
+	// Pwm sets the pin to pwm mode
+	Pwm()
+	// Freq sets the pwm frequency in Hz
+	Freq(freq int)
+	// DutyCycle sets the pwm duty cycle to dutyLen/cycleLen
+	DutyCycle(dutyLen, cycleLen uint32)
+}
+
+// PinFactory creates a Pin by the BCM gpio number
+type PinFactory func(n uint8) Pin
+
+var pinFactory PinFactory = NewRpioPin
+
+// SetPinFactory sets the factory used by the constructors which take gpio numbers,
+// e.g. NewLedImp(26). The rpio backend is used by default.
+// Call it before creating any driver.
+func SetPinFactory(f PinFactory) {
+	pinFactory = f
+}
+
+func newPin(n uint8) Pin {
+	return pinFactory(n)
+}
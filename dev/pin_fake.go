@@ -0,0 +1,224 @@
+/*
+FakePin is an in-memory Pin used to test drivers and applications without a raspberry pi.
+Use Set() to simulate the level driven by an outside device, and Level()/Writes()
+to check what a driver wrote to the pin.
+*/
+package dev
+
+import (
+	"sync"
+)
+
+// FakePin implements Pin interface in memory
+type FakePin struct {
+	mu       sync.Mutex
+	mode     PinMode
+	level    LogicLevel
+	driven   bool
+	pull     Pull
+	edge     Edge
+	detected bool
+	freq     int
+	dutyLen  uint32
+	cycleLen uint32
+	writes   []LogicLevel
+}
+
+// NewFakePin ...
+func NewFakePin() *FakePin {
+	return &FakePin{}
+}
+
+// NewFakePinFactory returns a PinFactory creating fake pins,
+// the created pins can be got from the returned map by gpio number.
+func NewFakePinFactory() (PinFactory, map[uint8]*FakePin) {
+	var mu sync.Mutex
+	pins := make(map[uint8]*FakePin)
+	f := func(n uint8) Pin {
+		mu.Lock()
+		defer mu.Unlock()
+		p, ok := pins[n]
+		if !ok {
+			p = NewFakePin()
+			pins[n] = p
+		}
+		return p
+	}
+	return f, pins
+}
+
+// Input ...
+func (p *FakePin) Input() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mode = InputMode
+}
+
+// Output ...
+func (p *FakePin) Output() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mode = OutputMode
+}
+
+// Read ...
+func (p *FakePin) Read() LogicLevel {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.level
+}
+
+// Write ...
+func (p *FakePin) Write(level LogicLevel) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.writes = append(p.writes, level)
+	p.setLevel(level)
+}
+
+// High ...
+func (p *FakePin) High() {
+	p.Write(High)
+}
+
+// Low ...
+func (p *FakePin) Low() {
+	p.Write(Low)
+}
+
+// PullUp ...
+func (p *FakePin) PullUp() {
+	p.setPull(PullUpMode)
+}
+
+// PullDown ...
+func (p *FakePin) PullDown() {
+	p.setPull(PullDownMode)
+}
+
+// PullOff ...
+func (p *FakePin) PullOff() {
+	p.setPull(PullNone)
+}
+
+// Detect ...
+func (p *FakePin) Detect(edge Edge) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.edge = edge
+	p.detected = false
+}
+
+// EdgeDetected ...
+func (p *FakePin) EdgeDetected() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	d := p.detected
+	p.detected = false
+	return d
+}
+
+// Pwm ...
+func (p *FakePin) Pwm() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mode = PwmMode
+}
+
+// Freq ...
+func (p *FakePin) Freq(freq int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.freq = freq
+}
+
+// DutyCycle ...
+func (p *FakePin) DutyCycle(dutyLen, cycleLen uint32) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dutyLen = dutyLen
+	p.cycleLen = cycleLen
+}
+
+// Set simulates an outside device driving the pin to the level
+func (p *FakePin) Set(level LogicLevel) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.driven = true
+	p.setLevel(level)
+}
+
+// Release simulates the outside device releasing the pin,
+// the level follows the pull resistor after releasing.
+func (p *FakePin) Release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.driven = false
+	p.followPull()
+}
+
+// Mode returns the current mode of the pin
+func (p *FakePin) Mode() PinMode {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.mode
+}
+
+// Level returns the current level of the pin
+func (p *FakePin) Level() LogicLevel {
+	return p.Read()
+}
+
+// PullMode returns the current pull of the pin
+func (p *FakePin) PullMode() Pull {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pull
+}
+
+// Writes returns all levels written by drivers
+func (p *FakePin) Writes() []LogicLevel {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	w := make([]LogicLevel, len(p.writes))
+	copy(w, p.writes)
+	return w
+}
+
+// PwmState returns the current pwm frequency and duty cycle of the pin
+func (p *FakePin) PwmState() (freq int, dutyLen, cycleLen uint32) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.freq, p.dutyLen, p.cycleLen
+}
+
+func (p *FakePin) setPull(pull Pull) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pull = pull
+	if !p.driven {
+		p.followPull()
+	}
+}
+
+func (p *FakePin) followPull() {
+	switch p.pull {
+	case PullUpMode:
+		p.setLevel(High)
+	case PullDownMode:
+		p.setLevel(Low)
+	}
+}
+
+func (p *FakePin) setLevel(level LogicLevel) {
+	if level == p.level {
+		return
+	}
+	if (p.edge == RiseEdge || p.edge == AnyEdge) && level == High {
+		p.detected = true
+	}
+	if (p.edge == FallEdge || p.edge == AnyEdge) && level == Low {
+		p.detected = true
+	}
+	p.level = level
+}
@@ -0,0 +1,62 @@
+package dev
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FakePinEdgeDetected(t *testing.T) {
+	p := NewFakePin()
+	p.Input()
+	p.PullDown()
+	p.Detect(RiseEdge)
+	assert.False(t, p.EdgeDetected())
+
+	p.Set(High)
+	assert.True(t, p.EdgeDetected())
+	assert.False(t, p.EdgeDetected())
+
+	p.Set(Low)
+	assert.False(t, p.EdgeDetected())
+
+	p.Detect(AnyEdge)
+	p.Set(High)
+	p.Release()
+	assert.Equal(t, Low, p.Read())
+	assert.True(t, p.EdgeDetected())
+}
+
+func Test_LedImpWithFakePin(t *testing.T) {
+	p := NewFakePin()
+	led := NewLedImpWithPin(p)
+	assert.Equal(t, OutputMode, p.Mode())
+	assert.Equal(t, Low, p.Level())
+
+	led.On()
+	assert.Equal(t, High, p.Level())
+	led.Blink(2, 0)
+	assert.Equal(t, []LogicLevel{Low, High, High, Low, High, Low}, p.Writes())
+}
+
+func Test_ButtonImpWithFakePin(t *testing.T) {
+	p := NewFakePin()
+	b := NewButtonImpWithPin(p)
+	assert.False(t, b.Pressed())
+	p.Set(High)
+	assert.True(t, b.Pressed())
+}
+
+func Test_RX480E4WithFakePins(t *testing.T) {
+	f, pins := NewFakePinFactory()
+	defer SetPinFactory(pinFactory)
+	SetPinFactory(f)
+
+	rx := NewRX480E4(23, 24, 25, 8)
+	assert.Equal(t, PullDownMode, pins[24].PullMode())
+	assert.False(t, rx.Received(1))
+	pins[24].Set(High)
+	assert.True(t, rx.Received(1))
+	assert.False(t, rx.Received(1))
+	assert.False(t, rx.Received(4))
+}
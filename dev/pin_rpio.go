@@ -0,0 +1,175 @@
+/*
+RpioPin is the Pin backed by go-rpio, which accesses the gpio registers via /dev/gpiomem or /dev/mem.
+It only works on a raspberry pi, and you need root privileges for pwm.
+The hardware pwm is only available on gpio 12, 13, 18 and 19, other pins fall back to SoftPWM.
+*/
+package dev
+
+import (
+	"sync"
+
+	"github.com/stianeikeland/go-rpio/v4"
+)
+
+var rpioOnce sync.Once
+
+// openRpio maps the gpio registers on first use.
+// NOTE:
+// open rpio on not raspberry-os will result to panic.
+// it is deferred to the first rpio pin being created,
+// so that unit tests using FakePin can run on other os like mac or ubuntu amd.
+func openRpio() {
+	rpioOnce.Do(func() {
+		if err := rpio.Open(); err != nil {
+			panic("failed to init rpio")
+		}
+	})
+}
+
+// rpioPwmPins are the pins with hardware pwm
+var rpioPwmPins = map[rpio.Pin]bool{
+	12: true,
+	13: true,
+	18: true,
+	19: true,
+}
+
+var rpioEdges = map[Edge]rpio.Edge{
+	NoEdge:   rpio.NoEdge,
+	RiseEdge: rpio.RiseEdge,
+	FallEdge: rpio.FallEdge,
+	AnyEdge:  rpio.AnyEdge,
+}
+
+// RpioPin implements Pin interface using go-rpio
+type RpioPin struct {
+	pin rpio.Pin
+
+	mu   sync.Mutex
+	soft *softPWMChannel
+}
+
+// NewRpioPin ...
+func NewRpioPin(n uint8) Pin {
+	openRpio()
+	return &RpioPin{pin: rpio.Pin(n)}
+}
+
+// Input ...
+func (p *RpioPin) Input() {
+	p.stopSoftPWM()
+	p.pin.Input()
+}
+
+// Output ...
+func (p *RpioPin) Output() {
+	p.stopSoftPWM()
+	p.pin.Output()
+}
+
+// Read ...
+func (p *RpioPin) Read() LogicLevel {
+	if p.pin.Read() == rpio.High {
+		return High
+	}
+	return Low
+}
+
+// Write ...
+func (p *RpioPin) Write(level LogicLevel) {
+	p.stopSoftPWM()
+	p.write(level)
+}
+
+func (p *RpioPin) write(level LogicLevel) {
+	if level == High {
+		p.pin.High()
+		return
+	}
+	p.pin.Low()
+}
+
+// High ...
+func (p *RpioPin) High() {
+	p.Write(High)
+}
+
+// Low ...
+func (p *RpioPin) Low() {
+	p.Write(Low)
+}
+
+// PullUp ...
+func (p *RpioPin) PullUp() {
+	p.pin.PullUp()
+}
+
+// PullDown ...
+func (p *RpioPin) PullDown() {
+	p.pin.PullDown()
+}
+
+// PullOff ...
+func (p *RpioPin) PullOff() {
+	p.pin.PullOff()
+}
+
+// Detect ...
+func (p *RpioPin) Detect(edge Edge) {
+	p.pin.Detect(rpioEdges[edge])
+}
+
+// EdgeDetected ...
+func (p *RpioPin) EdgeDetected() bool {
+	return p.pin.EdgeDetected()
+}
+
+// Pwm sets the pin to pwm mode, the pins without hardware pwm fall back to SoftPWM
+func (p *RpioPin) Pwm() {
+	if rpioPwmPins[p.pin] {
+		p.pin.Pwm()
+		return
+	}
+	p.pin.Output()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.soft == nil {
+		p.soft = defaultSoftPWM().channel(p.write)
+	}
+}
+
+// Freq ...
+func (p *RpioPin) Freq(freq int) {
+	if soft := p.softPWM(); soft != nil {
+		soft.freq(freq)
+		return
+	}
+	p.pin.Freq(freq)
+}
+
+// DutyCycle ...
+func (p *RpioPin) DutyCycle(dutyLen, cycleLen uint32) {
+	if soft := p.softPWM(); soft != nil {
+		soft.dutyCycle(dutyLen, cycleLen)
+		return
+	}
+	p.pin.DutyCycle(dutyLen, cycleLen)
+}
+
+// softPWM returns the SoftPWM channel if the pin is in software pwm mode
+func (p *RpioPin) softPWM() *softPWMChannel {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.soft
+}
+
+// stopSoftPWM leaves the software pwm mode
+func (p *RpioPin) stopSoftPWM() {
+	p.mu.Lock()
+	soft := p.soft
+	p.soft = nil
+	p.mu.Unlock()
+	if soft != nil {
+		soft.stop()
+	}
+}
@@ -28,33 +28,34 @@ package dev
 import (
 	"errors"
 	"time"
-
-	"github.com/tarm/serial"
 )
 
 // PMS7003 ...
 type PMS7003 struct {
-	port  *serial.Port
+	port  SerialPort
 	buf   [128]byte
 	retry int
 }
 
 // NewPMS7003 ...
 func NewPMS7003(dev string, baud int) (*PMS7003, error) {
-	cfg := &serial.Config{
-		Name:        dev,
+	port, err := OpenSerial(SerialConfig{
+		Dev:         dev,
 		Baud:        baud,
 		ReadTimeout: 5 * time.Second,
-	}
-	port, err := serial.OpenPort(cfg)
+	})
 	if err != nil {
 		return nil, err
 	}
+	return NewPMS7003WithPort(port), nil
+}
 
+// NewPMS7003WithPort ...
+func NewPMS7003WithPort(port SerialPort) *PMS7003 {
 	return &PMS7003{
 		port:  port,
 		retry: 10,
-	}, nil
+	}
 }
 
 // Get returns pm2.5 and pm10 in ug/m3
@@ -1,5 +1,6 @@
 /*
 PumpImp is a driver for ~3.3v pump motor module.
+Use DosingController to dispense a precise volume with a flow meter.
 
 Connect to Raspberry Pi:
   - vcc(red line)  : any data pin(~3.3v)
@@ -9,19 +10,22 @@ package dev
 
 import (
 	"time"
-
-	"github.com/stianeikeland/go-rpio/v4"
 )
 
 // PumpImp implements Pump interface
 type PumpImp struct {
-	pin rpio.Pin
+	pin Pin
 }
 
-// NewLedImp ...
+// NewPumpImp ...
 func NewPumpImp(pin uint8) *PumpImp {
+	return NewPumpImpWithPin(newPin(pin))
+}
+
+// NewPumpImpWithPin ...
+func NewPumpImpWithPin(pin Pin) *PumpImp {
 	p := &PumpImp{
-		pin: rpio.Pin(pin),
+		pin: pin,
 	}
 	p.pin.Output()
 	p.pin.Low()
@@ -0,0 +1,369 @@
+/*
+SoftPWM is a software pwm engine, it drives any output pin by toggling it on time.
+All channels are multiplexed on one timing goroutine, which sleeps until shortly before the next edge,
+and then busy-waits the rest of the time (the spin window) to reduce the jitter, Jitter() reports the max delay.
+A late rising edge delays its high pulse without shortening it, and the periods keep their schedule,
+so the delays don't accumulate.
+
+The hardware pwm is only available on gpio 12, 13, 18 and 19 of Raspberry Pi.
+The rpio and gpiochip backends fall back to the default SoftPWM when Pwm() is called on the other pins,
+so the drivers like Servo, L298N and LedImp work on any pin without changes.
+
+The frequency is limited to MaxFreq (2kHz by default), which is enough for servos, motors and leds.
+Like the hardware pwm of go-rpio, Freq() sets the pwm clock, and the output frequency is freq/cycleLen.
+
+Usage:
+
+	pwm := dev.NewSoftPWMPin(dev.NewRpioPin(17))
+	pwm.Pwm()
+	pwm.Freq(100000)
+	pwm.DutyCycle(25, 100) // 1kHz, 25% duty
+
+	servo := dev.NewServo(17, dev.SG90Config) // falls back to SoftPWM automatically
+*/
+package dev
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSoftPWMMaxFreq    = 2000
+	defaultSoftPWMSpinWindow = 100 * time.Microsecond
+	// defaultSoftPWMFreq is the output frequency when Freq() isn't called
+	defaultSoftPWMFreq = 100
+)
+
+var (
+	softPWMOnce sync.Once
+	softPWM     *SoftPWM
+)
+
+// defaultSoftPWM returns the engine used by the pin backends and NewSoftPWMPin
+func defaultSoftPWM() *SoftPWM {
+	softPWMOnce.Do(func() {
+		softPWM = NewSoftPWM(SoftPWMConfig{})
+	})
+	return softPWM
+}
+
+// SoftPWMConfig ...
+type SoftPWMConfig struct {
+	// MaxFreq limits the output frequency in Hz, default 2000.
+	// Higher frequencies are lowered to MaxFreq keeping the duty cycle.
+	MaxFreq int
+	// SpinWindow is the time busy-waiting before every edge, default 100us.
+	// Larger windows have lower jitter but cost more cpu, negative disables busy-waiting.
+	SpinWindow time.Duration
+}
+
+// SoftPWM is a software pwm engine
+type SoftPWM struct {
+	cfg SoftPWMConfig
+
+	mu       sync.Mutex
+	channels map[*softPWMChannel]struct{}
+	jitter   time.Duration
+	wake     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewSoftPWM creates an engine and starts its timing goroutine
+func NewSoftPWM(cfg SoftPWMConfig) *SoftPWM {
+	e := newSoftPWM(cfg)
+	go e.run()
+	return e
+}
+
+// newSoftPWM creates an engine without starting the timing goroutine
+func newSoftPWM(cfg SoftPWMConfig) *SoftPWM {
+	if cfg.MaxFreq <= 0 {
+		cfg.MaxFreq = defaultSoftPWMMaxFreq
+	}
+	if cfg.SpinWindow == 0 {
+		cfg.SpinWindow = defaultSoftPWMSpinWindow
+	}
+	if cfg.SpinWindow < 0 {
+		cfg.SpinWindow = 0
+	}
+	return &SoftPWM{
+		cfg:      cfg,
+		channels: make(map[*softPWMChannel]struct{}),
+		wake:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+}
+
+// Pin wraps the pin as a channel of the engine
+func (e *SoftPWM) Pin(pin Pin) *SoftPWMPin {
+	return &SoftPWMPin{
+		Pin: pin,
+		ch:  e.channel(pin.Write),
+	}
+}
+
+// Jitter returns the max delay of the edges since the engine started
+func (e *SoftPWM) Jitter() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.jitter
+}
+
+// Close stops all channels and the timing goroutine, the pins keep their current levels
+func (e *SoftPWM) Close() error {
+	e.once.Do(func() {
+		e.mu.Lock()
+		e.channels = make(map[*softPWMChannel]struct{})
+		e.mu.Unlock()
+		close(e.done)
+	})
+	return nil
+}
+
+// channel creates a channel writing levels by write.
+// write is called by the timing goroutine, it must not call back into the engine.
+func (e *SoftPWM) channel(write func(LogicLevel)) *softPWMChannel {
+	return &softPWMChannel{engine: e, write: write}
+}
+
+func (e *SoftPWM) run() {
+	for {
+		next := e.nextEdge()
+		if next.IsZero() {
+			select {
+			case <-e.wake:
+				continue
+			case <-e.done:
+				return
+			}
+		}
+		if !e.wait(next) {
+			select {
+			case <-e.done:
+				return
+			default:
+				continue
+			}
+		}
+
+		e.fire(time.Now())
+	}
+}
+
+// nextEdge returns the time of the earliest edge of all channels, zero if no channel is running
+func (e *SoftPWM) nextEdge() time.Time {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var next time.Time
+	for c := range e.channels {
+		if next.IsZero() || c.next.Before(next) {
+			next = c.next
+		}
+	}
+	return next
+}
+
+// fire toggles the channels whose edges are due at now
+func (e *SoftPWM) fire(now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for c := range e.channels {
+		if !c.next.After(now) {
+			e.toggle(c, now)
+		}
+	}
+}
+
+// wait sleeps until the spin window before t, and busy-waits until t.
+// It returns false if the channels were changed or the engine was closed while sleeping.
+func (e *SoftPWM) wait(t time.Time) bool {
+	if d := time.Until(t) - e.cfg.SpinWindow; d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-e.wake:
+			return false
+		case <-e.done:
+			return false
+		}
+	}
+	for time.Now().Before(t) {
+		runtime.Gosched()
+	}
+	return true
+}
+
+// toggle writes the next edge of the channel and schedules the one after it, e.mu must be held
+func (e *SoftPWM) toggle(c *softPWMChannel, now time.Time) {
+	if late := now.Sub(c.next); late > e.jitter {
+		e.jitter = late
+	}
+	if c.level == High {
+		c.level = Low
+		c.next = c.base.Add(c.period)
+		c.write(Low)
+		return
+	}
+	// the high pulse starts when the rising edge is written, so a late rise delays the pulse
+	// instead of shortening or dropping it, and the next period keeps the schedule
+	c.level = High
+	c.base = c.next
+	if now.Sub(c.base) >= c.period {
+		// too late to catch up, e.g. the system was suspended
+		c.base = now
+	}
+	c.start = now
+	c.next = c.start.Add(c.high)
+	c.write(High)
+}
+
+// set runs the channel in the period with the high time in every period
+func (e *SoftPWM) set(c *softPWMChannel, period, high time.Duration) {
+	if minPeriod := time.Second / time.Duration(e.cfg.MaxFreq); period < minPeriod {
+		high = time.Duration(float64(high) * float64(minPeriod) / float64(period))
+		period = minPeriod
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if high <= 0 || high >= period {
+		// constant levels don't need the timing goroutine
+		delete(e.channels, c)
+		c.level = Low
+		if high > 0 {
+			c.level = High
+		}
+		c.write(c.level)
+		return
+	}
+	c.period, c.high = period, high
+	if _, ok := e.channels[c]; !ok {
+		// start a new period now
+		c.level = Low
+		c.next = time.Now()
+		e.channels[c] = struct{}{}
+	} else if c.level == High {
+		c.next = c.start.Add(high)
+	}
+	e.notify()
+}
+
+// remove stops the channel, the engine doesn't write its pin after remove returns
+func (e *SoftPWM) remove(c *softPWMChannel) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.channels[c]; !ok {
+		return
+	}
+	delete(e.channels, c)
+	e.notify()
+}
+
+// notify wakes up the timing goroutine to reschedule, e.mu must be held
+func (e *SoftPWM) notify() {
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+// softPWMChannel is a pin driven by SoftPWM
+type softPWMChannel struct {
+	engine *SoftPWM
+	write  func(LogicLevel)
+
+	// clock is set by Freq(), it is only accessed by the owner of the channel
+	clock int
+
+	// the fields below are guarded by engine.mu
+	period time.Duration
+	high   time.Duration
+	level  LogicLevel
+	base   time.Time // the scheduled start of the period
+	start  time.Time // the time the rising edge was written
+	next   time.Time
+}
+
+// freq sets the pwm clock in Hz like the hardware pwm
+func (c *softPWMChannel) freq(clock int) {
+	c.clock = clock
+}
+
+// dutyCycle sets the duty cycle to dutyLen/cycleLen, and the period to cycleLen/clock
+func (c *softPWMChannel) dutyCycle(dutyLen, cycleLen uint32) {
+	if cycleLen == 0 {
+		return
+	}
+	period := time.Second / defaultSoftPWMFreq
+	if c.clock > 0 {
+		period = time.Duration(float64(time.Second) * float64(cycleLen) / float64(c.clock))
+	}
+	if period <= 0 {
+		period = 1
+	}
+	high := time.Duration(float64(period) * float64(dutyLen) / float64(cycleLen))
+	c.engine.set(c, period, high)
+}
+
+func (c *softPWMChannel) stop() {
+	c.engine.remove(c)
+}
+
+// SoftPWMPin is a pin driven by SoftPWM, it implements Pin interface.
+// Input, Output and writing levels stop the pwm.
+type SoftPWMPin struct {
+	Pin
+	ch *softPWMChannel
+}
+
+// NewSoftPWMPin wraps the pin as a channel of the default SoftPWM engine
+func NewSoftPWMPin(pin Pin) *SoftPWMPin {
+	return defaultSoftPWM().Pin(pin)
+}
+
+// Input ...
+func (p *SoftPWMPin) Input() {
+	p.ch.stop()
+	p.Pin.Input()
+}
+
+// Output ...
+func (p *SoftPWMPin) Output() {
+	p.ch.stop()
+	p.Pin.Output()
+}
+
+// Write ...
+func (p *SoftPWMPin) Write(level LogicLevel) {
+	p.ch.stop()
+	p.Pin.Write(level)
+}
+
+// High ...
+func (p *SoftPWMPin) High() {
+	p.Write(High)
+}
+
+// Low ...
+func (p *SoftPWMPin) Low() {
+	p.Write(Low)
+}
+
+// Pwm sets the pin to output mode, the pwm starts when DutyCycle() is called
+func (p *SoftPWMPin) Pwm() {
+	p.Output()
+}
+
+// Freq sets the pwm clock in Hz, the output frequency is freq/cycleLen
+func (p *SoftPWMPin) Freq(freq int) {
+	p.ch.freq(freq)
+}
+
+// DutyCycle sets the duty cycle to dutyLen/cycleLen
+func (p *SoftPWMPin) DutyCycle(dutyLen, cycleLen uint32) {
+	p.ch.dutyCycle(dutyLen, cycleLen)
+}
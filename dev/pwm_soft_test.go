@@ -0,0 +1,269 @@
+package dev
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// timedPin records the time of every level written to the fake pin
+type timedPin struct {
+	*FakePin
+	clock func() time.Time
+	mu    sync.Mutex
+	edges []EdgeEvent
+}
+
+func newTimedPin(clock func() time.Time) *timedPin {
+	return &timedPin{FakePin: NewFakePin(), clock: clock}
+}
+
+func (p *timedPin) Write(level LogicLevel) {
+	p.FakePin.Write(level)
+	edge := FallEdge
+	if level == High {
+		edge = RiseEdge
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.edges = append(p.edges, EdgeEvent{Edge: edge, Time: p.clock()})
+}
+
+func (p *timedPin) High() {
+	p.Write(High)
+}
+
+func (p *timedPin) Low() {
+	p.Write(Low)
+}
+
+// pulses returns the high times and the periods of the complete periods written after since
+func (p *timedPin) pulses(since time.Time) (highs, periods []time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var rises []int
+	for i, e := range p.edges {
+		if e.Edge == RiseEdge && !e.Time.Before(since) {
+			rises = append(rises, i)
+		}
+	}
+	for k := 0; k+1 < len(rises); k++ {
+		i := rises[k]
+		high := time.Duration(0)
+		if p.edges[i+1].Edge == FallEdge {
+			high = p.edges[i+1].Time.Sub(p.edges[i].Time)
+		}
+		highs = append(highs, high)
+		periods = append(periods, p.edges[rises[k+1]].Time.Sub(p.edges[i].Time))
+	}
+	return highs, periods
+}
+
+// measure returns the median duty and frequency of the complete periods written after since,
+// the median ignores a few periods delayed by the os scheduler
+func (p *timedPin) measure(since time.Time) (duty, freq float64) {
+	highs, periods := p.pulses(since)
+	if len(periods) == 0 {
+		return 0, 0
+	}
+	var duties, secs []float64
+	for i := range periods {
+		duties = append(duties, float64(highs[i])/float64(periods[i]))
+		secs = append(secs, periods[i].Seconds())
+	}
+	return median(duties), 1 / median(secs)
+}
+
+func median(a []float64) float64 {
+	sort.Float64s(a)
+	return a[len(a)/2]
+}
+
+// softPWMSim drives an engine without the timing goroutine on a simulated clock
+type softPWMSim struct {
+	*SoftPWM
+	now time.Time
+}
+
+func newSoftPWMSim(cfg SoftPWMConfig) *softPWMSim {
+	return &softPWMSim{SoftPWM: newSoftPWM(cfg), now: time.Now()}
+}
+
+func (s *softPWMSim) clock() time.Time {
+	return s.now
+}
+
+// run fires the edges until d later, every edge is written late by late(i) for the i-th edge
+func (s *softPWMSim) run(d time.Duration, late func(i int) time.Duration) {
+	end := s.now.Add(d)
+	for i := 0; ; i++ {
+		next := s.nextEdge()
+		if next.IsZero() || next.After(end) {
+			s.now = end
+			return
+		}
+		if next.After(s.now) {
+			s.now = next
+		}
+		if late != nil {
+			s.now = s.now.Add(late(i))
+		}
+		s.fire(s.now)
+	}
+}
+
+func Test_SoftPWMDuty(t *testing.T) {
+	sim := newSoftPWMSim(SoftPWMConfig{})
+	duties := []uint32{10, 25, 50, 90}
+	pins := make([]*timedPin, len(duties))
+	for i, d := range duties {
+		pins[i] = newTimedPin(sim.clock)
+		p := sim.Pin(pins[i])
+		p.Pwm()
+		p.Freq(10000)
+		p.DutyCycle(d, 100) // 100Hz
+		assert.Equal(t, OutputMode, pins[i].Mode())
+	}
+
+	start := sim.now
+	sim.run(time.Second, nil)
+	for i, d := range duties {
+		highs, periods := pins[i].pulses(start)
+		assert.GreaterOrEqual(t, len(periods), 98)
+		for k := range periods {
+			assert.Equal(t, time.Duration(d)*100*time.Microsecond, highs[k], "duty %v%%", d)
+			assert.Equal(t, 10*time.Millisecond, periods[k])
+		}
+	}
+}
+
+func Test_SoftPWMLate(t *testing.T) {
+	sim := newSoftPWMSim(SoftPWMConfig{})
+	pin := newTimedPin(sim.clock)
+	p := sim.Pin(pin)
+	p.Freq(5000)
+	p.DutyCycle(20, 100) // 4ms in 20ms
+
+	// the edges are late by 0 ~ 8ms, the high pulse is never shortened or dropped
+	start := sim.now
+	sim.run(2*time.Second, func(i int) time.Duration {
+		return time.Duration(i*7%5) * 2 * time.Millisecond
+	})
+	highs, periods := pin.pulses(start)
+	assert.Greater(t, len(periods), 90)
+	var total time.Duration
+	for k := range periods {
+		assert.GreaterOrEqual(t, highs[k], 4*time.Millisecond)
+		assert.LessOrEqual(t, highs[k], 4*time.Millisecond+8*time.Millisecond)
+		assert.InDelta(t, 20*time.Millisecond, periods[k], float64(8*time.Millisecond))
+		total += periods[k]
+	}
+	// the delays don't accumulate
+	assert.InDelta(t, 20*time.Millisecond, total/time.Duration(len(periods)), float64(200*time.Microsecond))
+	assert.Equal(t, 8*time.Millisecond, sim.Jitter())
+}
+
+func Test_SoftPWMChange(t *testing.T) {
+	sim := newSoftPWMSim(SoftPWMConfig{})
+	pin := newTimedPin(sim.clock)
+	p := sim.Pin(pin)
+	p.Pwm()
+	p.Freq(5000)
+	p.DutyCycle(20, 100) // 50Hz
+	start := sim.now
+	sim.run(300*time.Millisecond, nil)
+	duty, freq := pin.measure(start)
+	assert.InDelta(t, 0.2, duty, 1e-9)
+	assert.InDelta(t, 50, freq, 1e-9)
+
+	// changing the duty in a high pulse moves its falling edge
+	sim.run(time.Millisecond, nil)
+	assert.Equal(t, High, pin.Level())
+	p.DutyCycle(70, 100)
+	start = sim.now
+	sim.run(300*time.Millisecond, nil)
+	highs, _ := pin.pulses(start.Add(-time.Millisecond))
+	assert.Equal(t, 14*time.Millisecond, highs[0])
+	duty, _ = pin.measure(start)
+	assert.InDelta(t, 0.7, duty, 1e-9)
+
+	// constant levels
+	p.DutyCycle(100, 100)
+	assert.True(t, sim.nextEdge().IsZero())
+	assert.Equal(t, High, pin.Level())
+	p.DutyCycle(0, 100)
+	assert.True(t, sim.nextEdge().IsZero())
+	assert.Equal(t, Low, pin.Level())
+
+	// writing a level stops the pwm
+	p.DutyCycle(50, 100)
+	assert.False(t, sim.nextEdge().IsZero())
+	p.High()
+	assert.True(t, sim.nextEdge().IsZero())
+	assert.Equal(t, High, pin.Level())
+}
+
+func Test_SoftPWMMaxFreq(t *testing.T) {
+	sim := newSoftPWMSim(SoftPWMConfig{MaxFreq: 200})
+	pin := newTimedPin(sim.clock)
+	p := sim.Pin(pin)
+	p.Pwm()
+	p.Freq(64000)
+	p.DutyCycle(8, 32) // 2kHz is lowered to 200Hz
+	assert.Equal(t, 5*time.Millisecond, p.ch.period)
+	assert.Equal(t, 1250*time.Microsecond, p.ch.high)
+
+	start := sim.now
+	sim.run(300*time.Millisecond, nil)
+	duty, freq := pin.measure(start)
+	assert.InDelta(t, 0.25, duty, 1e-9)
+	assert.InDelta(t, 200, freq, 1e-9)
+}
+
+func Test_SoftPWMServo(t *testing.T) {
+	sim := newSoftPWMSim(SoftPWMConfig{})
+	pin := newTimedPin(sim.clock)
+	p := sim.Pin(pin)
+	servo := NewServoWithPin(p, SG90Config)
+	defer servo.Release()
+
+	// 0 degree is 1450us in every 20ms
+	servo.Roll(0)
+	assert.Equal(t, 20*time.Millisecond, p.ch.period)
+	assert.Equal(t, 1450*time.Microsecond, p.ch.high)
+	start := sim.now
+	sim.run(300*time.Millisecond, nil)
+	highs, periods := pin.pulses(start)
+	assert.Equal(t, 1450*time.Microsecond, highs[0])
+	assert.Equal(t, 20*time.Millisecond, periods[0])
+}
+
+func Test_SoftPWMWallClock(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping pwm on the wall clock in short mode")
+	}
+	pwm := NewSoftPWM(SoftPWMConfig{SpinWindow: 2 * time.Millisecond})
+	defer pwm.Close()
+
+	pin := newTimedPin(time.Now)
+	p := pwm.Pin(pin)
+	p.Freq(5000)
+	p.DutyCycle(20, 100) // 4ms in 20ms
+	start := time.Now()
+	time.Sleep(500 * time.Millisecond)
+
+	highs, periods := pin.pulses(start)
+	assert.NotEmpty(t, periods)
+	var hs, ps []float64
+	for i := range periods {
+		hs = append(hs, highs[i].Seconds())
+		ps = append(ps, periods[i].Seconds())
+	}
+	assert.InDelta(t, 0.004, median(hs), 0.0004)
+	assert.InDelta(t, 0.02, median(ps), 0.002)
+	// no edge is later than a period
+	assert.Less(t, pwm.Jitter(), 20*time.Millisecond)
+}
@@ -0,0 +1,478 @@
+/*
+QuadratureEncoder is a rotary encoder with two channels A and B in quadrature, and an optional index channel.
+It decodes every edge of both channels (x4 decoding), so it tracks the signed position and the direction,
+and it computes the speed in RPM over a sliding window.
+
+Detent mode is for the mechanical encoders with detents, e.g. KY-040,
+it counts one for every detent (a full quadrature cycle) and ignores the contact bounces.
+KY040 is a QuadratureEncoder in detent mode with its push button.
+
+Connect to Raspberry Pi:
+ - vcc: any 3.3v pin
+ - gnd: any gnd pin
+ - A (CLK of KY-040): any data pin
+ - B (DT of KY-040): any data pin
+ - Z (index, optional): any data pin
+ - SW (button of KY-040): any data pin
+
+Usage:
+
+	enc := dev.NewQuadratureEncoder(5, 6, dev.QuadratureConfig{
+		CountsPerRev:  1320, // 330 pulses per revolution x4
+		WheelDiameter: 0.065,
+	})
+	defer enc.Close()
+	log.Printf("pos: %v, rpm: %.1f, speed: %.2f m/s", enc.Position(), enc.RPM(), enc.Speed())
+
+	knob := dev.NewKY040(17, 27, 22)
+	defer knob.Close()
+	for ev := range knob.Subscribe(ctx) {
+		log.Printf("turned %+d to %v", ev.Delta, ev.Position)
+	}
+*/
+package dev
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	defaultEncoderCountsPerRev = 20
+	defaultEncoderWindow       = 500 * time.Millisecond
+	ky040Debounce              = 10 * time.Millisecond
+)
+
+// quadratureSteps is the steps of the transitions from the old state (a<<1 | b) to the new one,
+// indexed by old<<2 | new. A leading B is forward. Invalid transitions (both channels changed) are 0.
+var quadratureSteps = [16]int{
+	0, -1, 1, 0,
+	1, 0, 0, -1,
+	-1, 0, 0, 1,
+	0, 1, -1, 0,
+}
+
+// QuadratureConfig ...
+type QuadratureConfig struct {
+	// CountsPerRev is the counts of one revolution, default 20 (the detents of KY-040).
+	// It is 4 x pulses per revolution of each channel, or the detents per revolution in detent mode.
+	CountsPerRev int
+	// WheelDiameter is the diameter of the wheel driven by the shaft in meters, for Distance() and Speed()
+	WheelDiameter float64
+	// Window is the sliding window for computing the speed, default 500ms
+	Window time.Duration
+	// Reverse reverses the direction
+	Reverse bool
+	// Detent counts one for a full quadrature cycle, for the mechanical encoders with detents
+	Detent bool
+	// ResetOnIndex resets the position to 0 on every rising edge of the index channel
+	ResetOnIndex bool
+	// Pull is the internal pull resistor enabled on the pins
+	Pull Pull
+}
+
+// EncoderEvent is a change of the position of an encoder
+type EncoderEvent struct {
+	// Delta is the change of the position, it is +1 or -1 in most cases
+	Delta int
+	// Position is the position after the change
+	Position int64
+	Time     time.Time
+}
+
+// encoderSample is a position at a time, for computing the speed
+type encoderSample struct {
+	t   time.Time
+	pos int64
+}
+
+// QuadratureEncoder ...
+type QuadratureEncoder struct {
+	cfg    QuadratureConfig
+	a      Pin
+	b      Pin
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu       sync.Mutex
+	state    int
+	rest     int
+	sub      int
+	pos      int64
+	dir      int
+	indexPos int64
+	indexed  bool
+	samples  []encoderSample
+	subs     map[chan EncoderEvent]struct{}
+}
+
+// NewQuadratureEncoder ...
+func NewQuadratureEncoder(a, b uint8, cfg QuadratureConfig) *QuadratureEncoder {
+	return NewQuadratureEncoderWithPins(newPin(a), newPin(b), nil, cfg)
+}
+
+// NewQuadratureEncoderWithPins creates an encoder and starts decoding in the background, index can be nil
+func NewQuadratureEncoderWithPins(a, b, index Pin, cfg QuadratureConfig) *QuadratureEncoder {
+	if cfg.CountsPerRev <= 0 {
+		cfg.CountsPerRev = defaultEncoderCountsPerRev
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = defaultEncoderWindow
+	}
+	for _, p := range []Pin{a, b, index} {
+		if p == nil {
+			continue
+		}
+		p.Input()
+		switch cfg.Pull {
+		case PullUpMode:
+			p.PullUp()
+		case PullDownMode:
+			p.PullDown()
+		}
+	}
+	// the encoder is supposed to rest at a detent when created
+	e := newQuadratureEncoder(cfg, quadratureState(a.Read(), b.Read()))
+	e.a, e.b = a, b
+
+	// watch the edges before returning, so that no edge is missed
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+	aEdges := WatchEdgesInterval(ctx, a, AnyEdge, pulsePollInterval)
+	bEdges := WatchEdgesInterval(ctx, b, AnyEdge, pulsePollInterval)
+	var indexEdges <-chan EdgeEvent
+	if index != nil {
+		indexEdges = WatchEdges(ctx, index, RiseEdge)
+	}
+	go e.run(aEdges, bEdges, indexEdges)
+	return e
+}
+
+// newQuadratureEncoder creates an encoder at the state without decoding
+func newQuadratureEncoder(cfg QuadratureConfig, state int) *QuadratureEncoder {
+	return &QuadratureEncoder{
+		cfg:     cfg,
+		done:    make(chan struct{}),
+		state:   state,
+		rest:    state,
+		samples: []encoderSample{{t: time.Now()}},
+		subs:    make(map[chan EncoderEvent]struct{}),
+	}
+}
+
+// Position returns the signed position in counts
+func (e *QuadratureEncoder) Position() int64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.pos
+}
+
+// SetPosition sets the current position
+func (e *QuadratureEncoder) SetPosition(pos int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.shift(pos - e.pos)
+}
+
+// Reset sets the current position to 0
+func (e *QuadratureEncoder) Reset() {
+	e.SetPosition(0)
+}
+
+// Direction returns the direction of the last count, 1 is forward, -1 is backward, and 0 if never moved
+func (e *QuadratureEncoder) Direction() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.dir
+}
+
+// IndexPosition returns the position at the last index pulse, false if no index pulse was detected
+func (e *QuadratureEncoder) IndexPosition() (int64, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.indexPos, e.indexed
+}
+
+// Revolutions returns the position in revolutions
+func (e *QuadratureEncoder) Revolutions() float64 {
+	return float64(e.Position()) / float64(e.cfg.CountsPerRev)
+}
+
+// RPM returns the signed speed in revolutions per minute over the sliding window
+func (e *QuadratureEncoder) RPM() float64 {
+	return e.rpmAt(time.Now())
+}
+
+// Distance returns the distance in meters the wheel has rolled, it is 0 if WheelDiameter isn't set
+func (e *QuadratureEncoder) Distance() float64 {
+	return e.Revolutions() * math.Pi * e.cfg.WheelDiameter
+}
+
+// Speed returns the signed speed of the wheel in m/s, it is 0 if WheelDiameter isn't set
+func (e *QuadratureEncoder) Speed() float64 {
+	return e.RPM() / 60 * math.Pi * e.cfg.WheelDiameter
+}
+
+// Subscribe returns a channel receiving every change of the position.
+// The channel is closed when ctx is done or the encoder is closed.
+// Events are dropped if the receiver falls behind.
+func (e *QuadratureEncoder) Subscribe(ctx context.Context) <-chan EncoderEvent {
+	ch := make(chan EncoderEvent, eventBufSize)
+	e.mu.Lock()
+	e.subs[ch] = struct{}{}
+	e.mu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-e.done:
+		}
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		if _, ok := e.subs[ch]; ok {
+			delete(e.subs, ch)
+			close(ch)
+		}
+	}()
+	return ch
+}
+
+// Close stops decoding, and closes the channels returned by Subscribe()
+func (e *QuadratureEncoder) Close() error {
+	e.cancel()
+	<-e.done
+	return nil
+}
+
+// run decodes the edges of A and B in the order of their times until both channels are closed.
+// The edges of A and B are watched by different goroutines, so an edge is decoded only after
+// checking the other channel for an earlier one, decoding them out of order counts backward.
+// If the other channel has no pending edge, its level is read from the pin, see edge().
+func (e *QuadratureEncoder) run(a, b, index <-chan EdgeEvent) {
+	defer close(e.done)
+	var nextA, nextB *EdgeEvent
+	for {
+		if nextA == nil {
+			nextA, a = pollEdge(a)
+		}
+		if nextB == nil {
+			nextB, b = pollEdge(b)
+		}
+		switch {
+		case nextA != nil && (nextB == nil || !nextB.Time.Before(nextA.Time)):
+			e.edge(*nextA, true, nextB == nil)
+			nextA = nil
+			continue
+		case nextB != nil:
+			e.edge(*nextB, false, nextA == nil)
+			nextB = nil
+			continue
+		case a == nil && b == nil:
+			return
+		}
+
+		select {
+		case ev, ok := <-a:
+			if !ok {
+				a = nil
+				continue
+			}
+			nextA = &ev
+		case ev, ok := <-b:
+			if !ok {
+				b = nil
+				continue
+			}
+			nextB = &ev
+		case _, ok := <-index:
+			if !ok {
+				index = nil
+				continue
+			}
+			e.indexPulse()
+		}
+	}
+}
+
+// pollEdge receives an edge from ch without blocking, the returned channel is nil if ch is closed
+func pollEdge(ch <-chan EdgeEvent) (*EdgeEvent, <-chan EdgeEvent) {
+	if ch == nil {
+		return nil, nil
+	}
+	select {
+	case ev, ok := <-ch:
+		if !ok {
+			return nil, nil
+		}
+		return &ev, ch
+	default:
+		return nil, ch
+	}
+}
+
+// edge updates the state by an edge of channel A or B.
+// If readOther is true, the level of the other channel is read from its pin, otherwise it is from the edges
+// decoded before. A level differing from the state means the edge of the other channel is delivered late,
+// it is decoded before this edge, and the late edge changes nothing when it arrives.
+func (e *QuadratureEncoder) edge(ev EdgeEvent, isA bool, readOther bool) {
+	e.mu.Lock()
+	state := e.state
+	e.mu.Unlock()
+	a, b := state>>1, state&1
+	if readOther {
+		switch {
+		case isA && e.b != nil:
+			b = int(e.b.Read())
+		case !isA && e.a != nil:
+			a = int(e.a.Read())
+		}
+		if a<<1|b != state {
+			e.update(a<<1|b, ev.Time)
+		}
+	}
+
+	level := levelOf(ev.Edge)
+	if isA {
+		a = level
+	} else {
+		b = level
+	}
+	e.update(a<<1|b, ev.Time)
+}
+
+// update moves to the new state (a<<1 | b) at the time t
+func (e *QuadratureEncoder) update(state int, t time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	step := quadratureSteps[e.state<<2|state]
+	e.state = state
+	if e.cfg.Detent {
+		// count a detent when returning to the rest state through at least half a cycle,
+		// the bounces cancel out each other
+		e.sub += step
+		step = 0
+		if state == e.rest {
+			switch {
+			case e.sub >= 2:
+				step = 1
+			case e.sub <= -2:
+				step = -1
+			}
+			e.sub = 0
+		}
+	}
+	if step == 0 {
+		return
+	}
+	if e.cfg.Reverse {
+		step = -step
+	}
+	e.dir = step
+	e.pos += int64(step)
+	e.sample(t)
+	e.publish(EncoderEvent{Delta: step, Position: e.pos, Time: t})
+}
+
+func (e *QuadratureEncoder) indexPulse() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.indexPos, e.indexed = e.pos, true
+	if e.cfg.ResetOnIndex {
+		e.shift(-e.pos)
+	}
+}
+
+// shift moves the position and the samples by delta without changing the speed, e.mu must be held
+func (e *QuadratureEncoder) shift(delta int64) {
+	e.pos += delta
+	for i := range e.samples {
+		e.samples[i].pos += delta
+	}
+}
+
+// sample records the current position at t, and drops the samples out of the window, e.mu must be held.
+// The last sample before the window is kept as the position at the start of the window.
+func (e *QuadratureEncoder) sample(t time.Time) {
+	e.samples = append(e.samples, encoderSample{t: t, pos: e.pos})
+	start := t.Add(-e.cfg.Window)
+	n := 0
+	for n+1 < len(e.samples) && !e.samples[n+1].t.After(start) {
+		n++
+	}
+	e.samples = e.samples[n:]
+}
+
+// rpmAt returns the speed over the window before now
+func (e *QuadratureEncoder) rpmAt(now time.Time) float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	start := now.Add(-e.cfg.Window)
+	base := e.samples[0]
+	for _, s := range e.samples[1:] {
+		if s.t.After(start) {
+			break
+		}
+		base = s
+	}
+	dt := e.cfg.Window
+	if base.t.After(start) {
+		// the encoder was created in the window
+		dt = now.Sub(base.t)
+	}
+	if dt <= 0 {
+		return 0
+	}
+	revs := float64(e.pos-base.pos) / float64(e.cfg.CountsPerRev)
+	return revs / dt.Minutes()
+}
+
+// publish sends the event to the subscribers without blocking, e.mu must be held
+func (e *QuadratureEncoder) publish(ev EncoderEvent) {
+	for ch := range e.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+func quadratureState(a, b LogicLevel) int {
+	return int(a)<<1 | int(b)
+}
+
+// levelOf returns the level after the edge
+func levelOf(edge Edge) int {
+	if edge == RiseEdge {
+		return 1
+	}
+	return 0
+}
+
+// KY040 is a rotary encoder module with detents and a push button
+type KY040 struct {
+	*QuadratureEncoder
+	// Button is the push button of the knob, use WatchGestures() for clicks and long presses
+	Button *ButtonImp
+}
+
+// NewKY040 ...
+func NewKY040(clk, dt, sw uint8) *KY040 {
+	return NewKY040WithPins(newPin(clk), newPin(dt), newPin(sw))
+}
+
+// NewKY040WithPins creates a KY-040 counting the detents, clockwise is positive
+func NewKY040WithPins(clk, dt, sw Pin) *KY040 {
+	return &KY040{
+		QuadratureEncoder: NewQuadratureEncoderWithPins(clk, dt, nil, QuadratureConfig{
+			Detent: true,
+			Pull:   PullUpMode,
+		}),
+		Button: NewButtonImpWithConfig(sw, ButtonConfig{
+			ActiveLow: true,
+			Pull:      PullUpMode,
+			Debounce:  ky040Debounce,
+		}),
+	}
+}
@@ -0,0 +1,252 @@
+package dev
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// forwardCycle is the states of A and B in a forward quadrature cycle from 00
+var forwardCycle = []int{2, 3, 1, 0}
+
+// turn drives the fake pins through the states of n cycles, backward if n < 0
+func turn(a, b *FakePin, from int, n int) {
+	cycle := forwardCycle
+	if n < 0 {
+		cycle = []int{1, 3, 2, 0}
+		n = -n
+	}
+	// rotate the cycle to start after the state from
+	start := 0
+	for i, s := range cycle {
+		if s == from {
+			start = i + 1
+		}
+	}
+	for i := 0; i < n*4; i++ {
+		s := cycle[(start+i)%4]
+		a.Set(LogicLevel(s >> 1))
+		b.Set(LogicLevel(s & 1))
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func waitPosition(t *testing.T, e *QuadratureEncoder, pos int64) {
+	assert.Eventually(t, func() bool { return e.Position() == pos }, time.Second, time.Millisecond)
+}
+
+func Test_QuadratureEncoderDecode(t *testing.T) {
+	a, b := NewFakePin(), NewFakePin()
+	e := NewQuadratureEncoderWithPins(a, b, nil, QuadratureConfig{CountsPerRev: 8})
+	defer e.Close()
+
+	// x4 decoding
+	for _, s := range forwardCycle {
+		e.update(s, time.Now())
+	}
+	assert.Equal(t, int64(4), e.Position())
+	assert.Equal(t, 1, e.Direction())
+	assert.Equal(t, 0.5, e.Revolutions())
+
+	// backward
+	e.update(1, time.Now())
+	e.update(3, time.Now())
+	assert.Equal(t, int64(2), e.Position())
+	assert.Equal(t, -1, e.Direction())
+
+	// both channels changed, the transition is invalid
+	e.update(0, time.Now())
+	assert.Equal(t, int64(2), e.Position())
+
+	e.SetPosition(100)
+	assert.Equal(t, int64(100), e.Position())
+	e.Reset()
+	assert.Equal(t, int64(0), e.Position())
+}
+
+func Test_QuadratureEncoderOrder(t *testing.T) {
+	// the edges of 50 forward cycles are queued on both channels before decoding
+	a := make(chan EdgeEvent, 100)
+	b := make(chan EdgeEvent, 100)
+	start := time.Now()
+	prev := 0
+	for i := 0; i < 200; i++ {
+		s := forwardCycle[i%4]
+		ev := EdgeEvent{Edge: FallEdge, Time: start.Add(time.Duration(i) * time.Microsecond)}
+		if (s^prev)>>1 == 1 {
+			if s>>1 == 1 {
+				ev.Edge = RiseEdge
+			}
+			a <- ev
+		} else {
+			if s&1 == 1 {
+				ev.Edge = RiseEdge
+			}
+			b <- ev
+		}
+		prev = s
+	}
+	close(a)
+	close(b)
+
+	e := newQuadratureEncoder(QuadratureConfig{CountsPerRev: 20, Window: time.Second}, 0)
+	e.run(a, b, nil)
+	assert.Equal(t, int64(200), e.Position())
+	assert.Equal(t, 1, e.Direction())
+}
+
+func Test_QuadratureEncoderLateEdge(t *testing.T) {
+	pa, pb := NewFakePin(), NewFakePin()
+	a, b, index := make(chan EdgeEvent), make(chan EdgeEvent), make(chan EdgeEvent)
+	e := newQuadratureEncoder(QuadratureConfig{CountsPerRev: 20, Window: time.Second}, 0)
+	e.a, e.b = pa, pb
+	go e.run(a, b, index)
+
+	// set sets the level of the pin, and returns its edge
+	set := func(p *FakePin, level LogicLevel) EdgeEvent {
+		p.Set(level)
+		if level == High {
+			return EdgeEvent{Edge: RiseEdge, Time: time.Now()}
+		}
+		return EdgeEvent{Edge: FallEdge, Time: time.Now()}
+	}
+	// wait returns after the edges sent before are decoded, the index channel is received after decoding
+	wait := func() {
+		index <- EdgeEvent{Edge: RiseEdge, Time: time.Now()}
+	}
+
+	// the edges of A are delivered after the next edges of B, while the pins hold the true levels
+	for i := 0; i < 10; i++ {
+		late := set(pa, High)
+		b <- set(pb, High)
+		wait()
+		assert.Equal(t, int64(i*4+2), e.Position())
+		a <- late
+		wait()
+		assert.Equal(t, int64(i*4+2), e.Position())
+
+		late = set(pa, Low)
+		b <- set(pb, Low)
+		wait()
+		a <- late
+		wait()
+	}
+	assert.Equal(t, int64(40), e.Position())
+	assert.Equal(t, 1, e.Direction())
+
+	close(a)
+	close(b)
+	close(index)
+	<-e.done
+}
+
+func Test_QuadratureEncoderPins(t *testing.T) {
+	a, b, z := NewFakePin(), NewFakePin(), NewFakePin()
+	e := NewQuadratureEncoderWithPins(a, b, z, QuadratureConfig{Reverse: true})
+	defer e.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := e.Subscribe(ctx)
+
+	turn(a, b, 0, 2)
+	waitPosition(t, e, -8)
+	turn(a, b, 0, -1)
+	waitPosition(t, e, -4)
+
+	z.Set(High)
+	assert.Eventually(t, func() bool { _, ok := e.IndexPosition(); return ok }, time.Second, time.Millisecond)
+	pos, _ := e.IndexPosition()
+	assert.Equal(t, int64(-4), pos)
+
+	ev := <-events
+	assert.Equal(t, EncoderEvent{Delta: -1, Position: -1, Time: ev.Time}, ev)
+	cancel()
+	for range events {
+	}
+}
+
+func Test_QuadratureEncoderResetOnIndex(t *testing.T) {
+	a, b, z := NewFakePin(), NewFakePin(), NewFakePin()
+	e := NewQuadratureEncoderWithPins(a, b, z, QuadratureConfig{ResetOnIndex: true})
+	defer e.Close()
+
+	turn(a, b, 0, 1)
+	waitPosition(t, e, 4)
+	z.Set(High)
+	waitPosition(t, e, 0)
+	pos, ok := e.IndexPosition()
+	assert.True(t, ok)
+	assert.Equal(t, int64(4), pos)
+}
+
+func Test_QuadratureEncoderRPM(t *testing.T) {
+	e := NewQuadratureEncoderWithPins(NewFakePin(), NewFakePin(), nil, QuadratureConfig{
+		CountsPerRev:  100,
+		WheelDiameter: 0.1,
+		Window:        time.Second,
+	})
+	defer e.Close()
+
+	// 1000 counts per second for 2s, 10 revolutions per second
+	start := e.samples[0].t
+	for i := 1; i <= 2000; i++ {
+		e.update(forwardCycle[(i-1)%4], start.Add(time.Duration(i)*time.Millisecond))
+		if i == 500 {
+			// in the first window after created
+			assert.InDelta(t, 600, e.rpmAt(start.Add(500*time.Millisecond)), 1)
+		}
+	}
+	now := start.Add(2 * time.Second)
+	assert.InDelta(t, 600, e.rpmAt(now), 1)
+	assert.Less(t, len(e.samples), 1100)
+
+	// slowing down to stop
+	assert.InDelta(t, 300, e.rpmAt(now.Add(500*time.Millisecond)), 1)
+	assert.Equal(t, 0.0, e.rpmAt(now.Add(2*time.Second)))
+
+	// distance and speed of the wheel
+	assert.InDelta(t, 20*math.Pi*0.1, e.Distance(), 1e-9)
+	assert.Equal(t, 0.0, e.Speed())
+
+	// setting the position doesn't change the speed
+	e.Reset()
+	assert.InDelta(t, 600, e.rpmAt(now), 1)
+}
+
+func Test_KY040(t *testing.T) {
+	clk, dt, sw := NewFakePin(), NewFakePin(), NewFakePin()
+	ky := NewKY040WithPins(clk, dt, sw)
+	defer ky.Close()
+	assert.Equal(t, PullUpMode, clk.PullMode())
+	assert.Equal(t, PullUpMode, sw.PullMode())
+
+	// clockwise: clk falls first from the rest state 11
+	for _, s := range []int{1, 0, 2, 3} {
+		ky.update(s, time.Now())
+	}
+	assert.Equal(t, int64(1), ky.Position())
+
+	// bounces are ignored
+	for _, s := range []int{1, 3, 1, 0, 1, 0, 2, 3} {
+		ky.update(s, time.Now())
+	}
+	assert.Equal(t, int64(2), ky.Position())
+
+	// turning back before the detent doesn't count
+	for _, s := range []int{1, 0, 1, 3} {
+		ky.update(s, time.Now())
+	}
+	assert.Equal(t, int64(2), ky.Position())
+
+	// counterclockwise with the pins
+	turn(clk, dt, 3, -1)
+	waitPosition(t, ky.QuadratureEncoder, 1)
+
+	// the push button
+	assert.False(t, ky.Button.Pressed())
+	sw.Set(Low)
+	assert.True(t, ky.Button.Pressed())
+}
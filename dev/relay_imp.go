@@ -11,19 +11,20 @@ Connect to Raspberry Pi:
 */
 package dev
 
-import (
-	"github.com/stianeikeland/go-rpio/v4"
-)
-
 // RelayImp ...
 type RelayImp struct {
-	pin rpio.Pin
+	pin Pin
 }
 
 // NewRelayImp ...
 func NewRelayImp(pin uint8) *RelayImp {
+	return NewRelayImpWithPin(newPin(pin))
+}
+
+// NewRelayImpWithPin ...
+func NewRelayImpWithPin(pin Pin) *RelayImp {
 	r := &RelayImp{
-		pin: rpio.Pin(pin),
+		pin: pin,
 	}
 	r.pin.Output()
 	r.pin.Low()
@@ -11,19 +11,24 @@ Connect to Pi:
 package dev
 
 import (
-	"github.com/stianeikeland/go-rpio/v4"
+	"context"
 )
 
 // RFP602 implements Detector interface
 type RFP602 struct {
-	do rpio.Pin
-	ao rpio.Pin
+	do Pin
+	ao Pin
 }
 
 // NewRFP602 ...
 func NewRFP602(do uint8) *RFP602 {
+	return NewRFP602WithPin(newPin(do))
+}
+
+// NewRFP602WithPin ...
+func NewRFP602WithPin(do Pin) *RFP602 {
 	rfp := &RFP602{
-		do: rpio.Pin(do),
+		do: do,
 	}
 	rfp.do.Input()
 	return rfp
@@ -31,5 +36,10 @@ func NewRFP602(do uint8) *RFP602 {
 
 // Detected ...
 func (rfp *RFP602) Detected() bool {
-	return rfp.do.Read() == rpio.Low
+	return rfp.do.Read() == Low
+}
+
+// Watch delivers events when pressure is detected or gone until ctx is done
+func (rfp *RFP602) Watch(ctx context.Context) <-chan InputEvent {
+	return watchInput(ctx, rfp.do, Low)
 }
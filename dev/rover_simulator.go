@@ -0,0 +1,157 @@
+/*
+RoverSimulator simulates a car with differential drive, a GPS and a heading sensor for offline testing.
+Drive it using the simulated motors, and move it forward in time using Step().
+
+Usage:
+
+	rover := dev.NewRoverSimulator(start, 0, dev.RoverConfig{})
+	drive := dev.NewDifferentialDrive(rover.Left(), rover.Right())
+	nav := dev.NewNavigator(rover, rover, drive, dev.NavigatorConfig{})
+	for {
+		rover.Step(100 * time.Millisecond)
+		nav.Step()
+	}
+*/
+package dev
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/shanghuiyang/rpi-devices/geo"
+)
+
+// RoverConfig ...
+type RoverConfig struct {
+	// MaxSpeed is the speed of a side at 100% in m/s, default 1
+	MaxSpeed float64
+	// TrackWidth is the distance between the left and right wheels in meters, default 0.2
+	TrackWidth float64
+	// Noise is the standard deviation of the gaussian noise of GPS positions in meters
+	Noise float64
+	Seed  int64
+}
+
+// RoverSimulator implements FixGPS and Accelerometer interfaces
+type RoverSimulator struct {
+	cfg   RoverConfig
+	left  *MotorSimulator
+	right *MotorSimulator
+
+	mu      sync.Mutex
+	pos     geo.Point
+	heading float64
+	speed   float64
+	time    time.Time
+	lost    bool
+	rand    *rand.Rand
+}
+
+// NewRoverSimulator creates a rover at the position facing the heading in degrees from true north
+func NewRoverSimulator(pos geo.Point, heading float64, cfg RoverConfig) *RoverSimulator {
+	if cfg.MaxSpeed <= 0 {
+		cfg.MaxSpeed = 1
+	}
+	if cfg.TrackWidth <= 0 {
+		cfg.TrackWidth = 0.2
+	}
+	return &RoverSimulator{
+		cfg:     cfg,
+		left:    NewMotorSimulator(),
+		right:   NewMotorSimulator(),
+		pos:     pos,
+		heading: heading,
+		time:    time.Now().UTC(),
+		rand:    rand.New(rand.NewSource(cfg.Seed)),
+	}
+}
+
+// Left returns the left motor
+func (r *RoverSimulator) Left() *MotorSimulator {
+	return r.left
+}
+
+// Right returns the right motor
+func (r *RoverSimulator) Right() *MotorSimulator {
+	return r.right
+}
+
+// Step moves the rover forward dt in time
+func (r *RoverSimulator) Step(dt time.Duration) {
+	vl := r.left.Velocity() * r.cfg.MaxSpeed
+	vr := r.right.Velocity() * r.cfg.MaxSpeed
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sec := dt.Seconds()
+	r.speed = (vl + vr) / 2
+	// turn at the middle of the step
+	turn := (vl - vr) / r.cfg.TrackWidth * sec * 180 / math.Pi
+	r.pos = geo.Destination(r.pos, math.Mod(r.heading+turn/2+360, 360), r.speed*sec)
+	r.heading = math.Mod(r.heading+turn+360, 360)
+	r.time = r.time.Add(dt)
+}
+
+// Pose returns the true position and heading
+func (r *RoverSimulator) Pose() (geo.Point, float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pos, r.heading
+}
+
+// SetFixLost simulates losing the GPS fix
+func (r *RoverSimulator) SetFixLost(lost bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lost = lost
+}
+
+// Loc ...
+func (r *RoverSimulator) Loc() (lat, lon float64, err error) {
+	fix, _ := r.Fix()
+	if !fix.Valid() {
+		return 0, 0, errInvalidFix
+	}
+	return fix.Lat, fix.Lon, nil
+}
+
+// Fix ...
+func (r *RoverSimulator) Fix() (Fix, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.lost {
+		return Fix{Time: r.time}, nil
+	}
+	p := r.pos
+	if r.cfg.Noise > 0 {
+		p = geo.Destination(p, r.rand.Float64()*360, math.Abs(r.rand.NormFloat64())*r.cfg.Noise)
+	}
+	return Fix{
+		Time:       r.time,
+		Lat:        p.Lat,
+		Lon:        p.Lon,
+		Speed:      math.Abs(r.speed),
+		Course:     r.heading,
+		Satellites: defaultSimSats,
+		HDOP:       defaultSimHDOP,
+		Type:       Fix3D,
+	}, nil
+}
+
+// Angles returns the heading as yaw in -180 ~ 180 like GY25
+func (r *RoverSimulator) Angles() (yaw, pitch, roll float64, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	yaw = r.heading
+	if yaw > 180 {
+		yaw -= 360
+	}
+	return yaw, 0, 0, nil
+}
+
+// Close ...
+func (r *RoverSimulator) Close() error {
+	return nil
+}
@@ -14,24 +14,29 @@ Connect to Raspberry Pi:
 package dev
 
 import (
-	"github.com/stianeikeland/go-rpio/v4"
+	"context"
 )
 
 // RX480E4 implements RFReceiver
 type RX480E4 struct {
-	channels [4]rpio.Pin
+	channels [4]Pin
 }
 
 // NewRX480E4 ...
 func NewRX480E4(d0, d1, d2, d3 uint8) *RX480E4 {
-	channels := [4]rpio.Pin{rpio.Pin(d0), rpio.Pin(d1), rpio.Pin(d2), rpio.Pin(d3)}
+	return NewRX480E4WithPins(newPin(d0), newPin(d1), newPin(d2), newPin(d3))
+}
+
+// NewRX480E4WithPins ...
+func NewRX480E4WithPins(d0, d1, d2, d3 Pin) *RX480E4 {
+	channels := [4]Pin{d0, d1, d2, d3}
 	rx := &RX480E4{
 		channels: channels,
 	}
 	for _, ch := range rx.channels {
 		ch.Input()
 		ch.PullDown()
-		ch.Detect(rpio.RiseEdge)
+		ch.Detect(RiseEdge)
 	}
 	return rx
 }
@@ -43,3 +48,14 @@ func (rx *RX480E4) Received(ch int) bool {
 	}
 	return rx.channels[ch].EdgeDetected()
 }
+
+// WatchChannel delivers an event every time the signal of the channel is received until ctx is done.
+// Please NOTE that Received() of the channel shouldn't be called while watching.
+func (rx *RX480E4) WatchChannel(ctx context.Context, ch int) <-chan InputEvent {
+	if ch < 0 || ch > 3 {
+		c := make(chan InputEvent)
+		close(c)
+		return c
+	}
+	return watchPulses(ctx, rx.channels[ch], EdgePollInterval)
+}
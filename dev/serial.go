@@ -0,0 +1,161 @@
+package dev
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/tarm/serial"
+)
+
+// SerialPort is a serial port used by uart drivers.
+// All uart drivers in this package talk to the hardware through SerialPort.
+type SerialPort interface {
+	io.ReadWriteCloser
+	// Flush discards the data received but not read
+	Flush() error
+}
+
+// SerialConfig is the config for opening a serial port
+type SerialConfig struct {
+	// Dev is the device of the port, e.g. /dev/ttyAMA0
+	Dev string
+	// Baud is the baud rate, e.g. 9600
+	Baud int
+	// ReadTimeout is the max time to wait for data in Read(),
+	// Read() blocks until there is data if it is 0.
+	ReadTimeout time.Duration
+}
+
+// OpenSerial opens a serial port using the config
+func OpenSerial(cfg SerialConfig) (SerialPort, error) {
+	port, err := serial.OpenPort(&serial.Config{
+		Name:        cfg.Dev,
+		Baud:        cfg.Baud,
+		ReadTimeout: cfg.ReadTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return port, nil
+}
+
+// FakeSerialPort implements SerialPort in memory for testing.
+// Script the data to be read using Feed(), or reply to the written data using OnWrite().
+// Like a real port, Read() returns io.EOF if no data arrives in the read timeout.
+type FakeSerialPort struct {
+	mu      sync.Mutex
+	rx      []byte
+	tx      []byte
+	reply   func(data []byte) []byte
+	timeout time.Duration
+	flushes int
+	closed  bool
+}
+
+// NewFakeSerialPort ...
+func NewFakeSerialPort() *FakeSerialPort {
+	return &FakeSerialPort{
+		timeout: 100 * time.Millisecond,
+	}
+}
+
+// Feed appends data to be read from the port
+func (p *FakeSerialPort) Feed(data []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rx = append(p.rx, data...)
+}
+
+// OnWrite sets a function replying to the data written to the port,
+// the reply will be read from the port.
+// Use an echo function to make a loopback port.
+func (p *FakeSerialPort) OnWrite(reply func(data []byte) []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.reply = reply
+}
+
+// SetReadTimeout ...
+func (p *FakeSerialPort) SetReadTimeout(timeout time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.timeout = timeout
+}
+
+// Written returns all data written to the port
+func (p *FakeSerialPort) Written() []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]byte{}, p.tx...)
+}
+
+// Flushes returns the times of Flush() being called
+func (p *FakeSerialPort) Flushes() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.flushes
+}
+
+// Closed ...
+func (p *FakeSerialPort) Closed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+// Read ...
+func (p *FakeSerialPort) Read(b []byte) (int, error) {
+	p.mu.Lock()
+	deadline := time.Now().Add(p.timeout)
+	p.mu.Unlock()
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return 0, io.ErrClosedPipe
+		}
+		if len(p.rx) > 0 {
+			n := copy(b, p.rx)
+			p.rx = p.rx[n:]
+			p.mu.Unlock()
+			return n, nil
+		}
+		p.mu.Unlock()
+		if time.Now().After(deadline) {
+			return 0, io.EOF
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Write ...
+func (p *FakeSerialPort) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return 0, io.ErrClosedPipe
+	}
+	p.tx = append(p.tx, b...)
+	if p.reply != nil {
+		p.rx = append(p.rx, p.reply(append([]byte{}, b...))...)
+	}
+	return len(b), nil
+}
+
+// Flush only counts the calls and keeps the data fed,
+// so that drivers flushing the port before reading can be tested with scripted data.
+func (p *FakeSerialPort) Flush() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.flushes++
+	return nil
+}
+
+// Close ...
+func (p *FakeSerialPort) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	return nil
+}
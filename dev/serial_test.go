@@ -0,0 +1,80 @@
+package dev
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_US100WithFakeSerialPort(t *testing.T) {
+	port := NewFakeSerialPort()
+	port.OnWrite(func(data []byte) []byte {
+		if len(data) == 1 && data[0] == trigData[0] {
+			return []byte{0x04, 0xD2} // 1234mm
+		}
+		return nil
+	})
+	us := NewUS100UARTWithPort(port)
+
+	dist, err := us.Dist()
+	assert.NoError(t, err)
+	assert.Equal(t, 123.4, dist)
+	assert.Equal(t, trigData, port.Written())
+	assert.Equal(t, 1, port.Flushes())
+
+	assert.NoError(t, us.Close())
+	assert.True(t, port.Closed())
+}
+
+func Test_GY25WithFakeSerialPort(t *testing.T) {
+	port := NewFakeSerialPort()
+	// noise, then a frame: yaw=90.00, pitch=-1.00, roll=0.50
+	port.Feed([]byte{0x00, 0x13, 0xAA, 0x23, 0x28, 0xFF, 0x9C, 0x00, 0x32, 0x55, 0xAA, 0x00, 0x00, 0x00, 0x00, 0x00})
+	gy := NewGY25WithPort(port)
+
+	yaw, pitch, roll, err := gy.Angles()
+	assert.NoError(t, err)
+	assert.Equal(t, 90.0, yaw)
+	assert.Equal(t, -1.0, pitch)
+	assert.Equal(t, 0.5, roll)
+}
+
+func Test_GPSWithFakeSerialPort(t *testing.T) {
+	data := "1.03,1.38*0A\r\n" + // the tail of a sentence
+		"$GPGSV,3,1,11,10,63,137,17,07,61,098,15,05,59,290,20,08,54,157,30*70\r\n" +
+		"$GNRMC,083245.00,A,3957.48804,N,11626.17404,E,0.012,,041119,,,A*00\r\n" + // bad checksum
+		"$GNRMC,083245.00,A,3957.48804,N,11626.17404,E,0.012,,041119,,,A*65\r\n"
+	port := NewFakeSerialPort()
+	port.Feed([]byte(strings.Repeat(data, 4)))
+	gps := NewNeo6mGPSWithPort(port)
+	lat, lon, err := gps.Loc()
+	assert.NoError(t, err)
+	assert.InDelta(t, 39.958134, lat, 1e-6)
+	assert.InDelta(t, 116.436234, lon, 1e-6)
+
+	data = "$BDRMC,,V,,,,,,,,,,N*42\r\n"
+	port = NewFakeSerialPort()
+	port.Feed([]byte(strings.Repeat(data, 32)))
+	ht := NewHT1818GPSWithPort(port)
+	_, _, err = ht.Loc()
+	assert.ErrorIs(t, err, errInvalidFix)
+}
+
+func Test_FakeSerialPortReadTimeout(t *testing.T) {
+	port := NewFakeSerialPort()
+	port.SetReadTimeout(10 * time.Millisecond)
+	buf := make([]byte, 8)
+	n, err := port.Read(buf)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, io.EOF, err)
+
+	port.OnWrite(func(data []byte) []byte { return data })
+	_, err = port.Write([]byte("ping"))
+	assert.NoError(t, err)
+	n, err = port.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "ping", string(buf[:n]))
+}
@@ -0,0 +1,248 @@
+/*
+Servo drives a hobby servo motor with pwm pulses, configured by the pulse widths of its angle range.
+
+The pwm clock runs at 1.2MHz, which is divided exactly from the oscillators of both rpi4 (54MHz) and
+the early rpis (19.2MHz), so the pulse width has a resolution of 0.83us, less than 0.2 degree for most servos.
+
+Presets:
+ - SG90Config: 500 ~ 2400us for -90 ~ 90 degree
+ - MG996RConfig: 500 ~ 2500us for -90 ~ 90 degree
+ - ContinuousServoConfig: 1000 ~ 2000us for full speed counterclockwise ~ clockwise, use Spin() to drive it
+
+Connect to Raspberry Pi:
+ - the red line:	any 5v pin, or an external 5v power for large servos like MG996R
+ - the brown line: 	any gnd pin
+ - the yellow line:	any data pin, gpio 12, 13, 18 or 19 (pwm pins) are preferred, others fall back to software pwm with a little jitter
+
+Usage:
+
+	cfg := dev.MG996RConfig
+	cfg.Offset = -3 // calibrate the center
+	servo := dev.NewServo(18, cfg)
+	servo.Roll(45)
+	servo.MoveTo(ctx, -45, time.Second) // ease in and out in 1s
+*/
+package dev
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// servoClock is the pwm clock in Hz
+	servoClock       = 1200000
+	defaultServoFreq = 50
+)
+
+// ServoConfig ...
+type ServoConfig struct {
+	// MinPulse and MaxPulse are the pulse widths at MinAngle and MaxAngle
+	MinPulse time.Duration
+	MaxPulse time.Duration
+	MinAngle float64
+	MaxAngle float64
+	// Reverse maps MinAngle to MaxPulse and MaxAngle to MinPulse.
+	// Most servos turn counterclockwise as the pulse gets wider, Reverse makes positive angles clockwise for them.
+	Reverse bool
+	// Offset in degrees is added to every angle to calibrate the center of a servo
+	Offset float64
+	// Freq is the pwm frequency in Hz, default 50
+	Freq int
+}
+
+// Servo presets
+var (
+	SG90Config = ServoConfig{
+		MinPulse: 500 * time.Microsecond,
+		MaxPulse: 2400 * time.Microsecond,
+		MinAngle: -90,
+		MaxAngle: 90,
+		Reverse:  true,
+	}
+	MG996RConfig = ServoConfig{
+		MinPulse: 500 * time.Microsecond,
+		MaxPulse: 2500 * time.Microsecond,
+		MinAngle: -90,
+		MaxAngle: 90,
+		Reverse:  true,
+	}
+	ContinuousServoConfig = ServoConfig{
+		MinPulse: 1000 * time.Microsecond,
+		MaxPulse: 2000 * time.Microsecond,
+		MinAngle: -90,
+		MaxAngle: 90,
+		Reverse:  true,
+	}
+)
+
+// Servo implements ServoMotor interface
+type Servo struct {
+	pin   Pin
+	cfg   ServoConfig
+	cycle uint32
+
+	mu    sync.Mutex
+	angle float64
+	held  bool
+}
+
+// NewServo ...
+func NewServo(pin uint8, cfg ServoConfig) *Servo {
+	return NewServoWithPin(newPin(pin), cfg)
+}
+
+// NewServoWithPin ...
+func NewServoWithPin(pin Pin, cfg ServoConfig) *Servo {
+	if cfg.MinPulse <= 0 || cfg.MaxPulse <= cfg.MinPulse {
+		cfg.MinPulse, cfg.MaxPulse = SG90Config.MinPulse, SG90Config.MaxPulse
+	}
+	if cfg.MaxAngle <= cfg.MinAngle {
+		cfg.MinAngle, cfg.MaxAngle = -90, 90
+	}
+	if cfg.Freq <= 0 {
+		cfg.Freq = defaultServoFreq
+	}
+	s := &Servo{
+		pin:   pin,
+		cfg:   cfg,
+		cycle: uint32(servoClock / cfg.Freq),
+	}
+	s.pin.Pwm()
+	s.pin.Freq(servoClock)
+	s.pin.DutyCycle(0, s.cycle)
+	return s
+}
+
+// Roll rolls to the angle, it is limited in MinAngle ~ MaxAngle
+func (s *Servo) Roll(angle float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roll(angle)
+}
+
+// Angle returns the current angle
+func (s *Servo) Angle() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.angle
+}
+
+// MoveTo rolls from the current angle to the angle in the duration, easing in and out.
+// It blocks until the move is finished or ctx is done.
+func (s *Servo) MoveTo(ctx context.Context, angle float64, duration time.Duration) error {
+	s.mu.Lock()
+	from, held := s.angle, s.held
+	s.mu.Unlock()
+	if !held || duration <= 0 {
+		// the position is unknown without holding
+		s.Roll(angle)
+		return nil
+	}
+	return s.sweep(ctx, from, angle, duration)
+}
+
+// Sweep rolls to from, and then to the angle in the duration, easing in and out
+func (s *Servo) Sweep(ctx context.Context, from, to float64, duration time.Duration) error {
+	s.Roll(from)
+	return s.sweep(ctx, from, to, duration)
+}
+
+// Spin drives a continuous rotation servo at the speed -1 ~ 1, positive is clockwise
+func (s *Servo) Spin(speed float64) {
+	speed = math.Max(-1, math.Min(1, speed))
+	center := (s.cfg.MinAngle + s.cfg.MaxAngle) / 2
+	s.Roll(center + speed*(s.cfg.MaxAngle-s.cfg.MinAngle)/2)
+}
+
+// Stop rolls to the center, which stops a continuous rotation servo
+func (s *Servo) Stop() {
+	s.Spin(0)
+}
+
+// Release stops the pulses, the servo doesn't hold its position anymore
+func (s *Servo) Release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.held = false
+	s.pin.DutyCycle(0, s.cycle)
+}
+
+// Calibrate sets MinPulse and MaxPulse measured on the servo
+func (s *Servo) Calibrate(minPulse, maxPulse time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if minPulse <= 0 || maxPulse <= minPulse {
+		return
+	}
+	s.cfg.MinPulse, s.cfg.MaxPulse = minPulse, maxPulse
+	if s.held {
+		s.roll(s.angle)
+	}
+}
+
+// SetOffset sets the offset in degrees to calibrate the center
+func (s *Servo) SetOffset(offset float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg.Offset = offset
+	if s.held {
+		s.roll(s.angle)
+	}
+}
+
+// Pulse returns the pulse width of the angle
+func (s *Servo) Pulse(angle float64) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pulse(s.clamp(angle))
+}
+
+func (s *Servo) sweep(ctx context.Context, from, to float64, duration time.Duration) error {
+	period := time.Second / time.Duration(s.cfg.Freq)
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	start := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		r := float64(time.Since(start)) / float64(duration)
+		if r >= 1 {
+			s.Roll(to)
+			return nil
+		}
+		s.Roll(from + (to-from)*easeInOut(r))
+	}
+}
+
+// roll rolls to the angle, s.mu must be held
+func (s *Servo) roll(angle float64) {
+	s.angle = s.clamp(angle)
+	s.held = true
+	duty := math.Round(s.pulse(s.angle).Seconds() * servoClock)
+	s.pin.DutyCycle(uint32(duty), s.cycle)
+}
+
+func (s *Servo) clamp(angle float64) float64 {
+	return math.Max(s.cfg.MinAngle, math.Min(s.cfg.MaxAngle, angle))
+}
+
+// pulse returns the pulse width of the angle with the offset
+func (s *Servo) pulse(angle float64) time.Duration {
+	r := (angle + s.cfg.Offset - s.cfg.MinAngle) / (s.cfg.MaxAngle - s.cfg.MinAngle)
+	if s.cfg.Reverse {
+		r = 1 - r
+	}
+	r = math.Max(0, math.Min(1, r))
+	return s.cfg.MinPulse + time.Duration(r*float64(s.cfg.MaxPulse-s.cfg.MinPulse))
+}
+
+// easeInOut maps 0 ~ 1 to 0 ~ 1 on a sine curve, it starts and ends slowly
+func easeInOut(r float64) float64 {
+	return (1 - math.Cos(math.Pi*r)) / 2
+}
@@ -0,0 +1,138 @@
+package dev
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// servoPulse returns the pulse width of the pin driven by a servo
+func servoPulse(p *FakePin) time.Duration {
+	freq, duty, _ := p.PwmState()
+	return time.Duration(float64(duty) / float64(freq) * float64(time.Second))
+}
+
+func Test_Servo(t *testing.T) {
+	pin := NewFakePin()
+	s := NewServoWithPin(pin, SG90Config)
+	freq, duty, cycle := pin.PwmState()
+	assert.Equal(t, PwmMode, pin.Mode())
+	assert.Equal(t, 1200000, freq)
+	assert.Equal(t, uint32(0), duty)
+	assert.Equal(t, uint32(24000), cycle)
+
+	testCases := []struct {
+		angle float64
+		pulse time.Duration
+	}{
+		{-90, 2400 * time.Microsecond},
+		{0, 1450 * time.Microsecond},
+		{90, 500 * time.Microsecond},
+		{45, 975 * time.Microsecond},
+		{0.5, 1444722 * time.Nanosecond},
+		{-120, 2400 * time.Microsecond},
+		{120, 500 * time.Microsecond},
+	}
+	for _, tc := range testCases {
+		s.Roll(tc.angle)
+		assert.InDelta(t, tc.pulse, servoPulse(pin), float64(time.Microsecond), "angle %v", tc.angle)
+	}
+	assert.Equal(t, 90.0, s.Angle())
+
+	// calibration
+	s.SetOffset(10)
+	s.Roll(0)
+	assert.InDelta(t, 1450*time.Microsecond-time.Duration(10*1900/180*float64(time.Microsecond)), servoPulse(pin), float64(time.Microsecond))
+	s.SetOffset(0)
+	assert.InDelta(t, 1450*time.Microsecond, servoPulse(pin), float64(time.Microsecond))
+	s.Calibrate(600*time.Microsecond, 2300*time.Microsecond)
+	assert.InDelta(t, 1450*time.Microsecond, servoPulse(pin), float64(time.Microsecond))
+	s.Roll(90)
+	assert.InDelta(t, 600*time.Microsecond, servoPulse(pin), float64(time.Microsecond))
+
+	s.Release()
+	_, duty, _ = pin.PwmState()
+	assert.Equal(t, uint32(0), duty)
+}
+
+func Test_ServoContinuous(t *testing.T) {
+	pin := NewFakePin()
+	s := NewServoWithPin(pin, ContinuousServoConfig)
+	s.Spin(1)
+	assert.InDelta(t, 1000*time.Microsecond, servoPulse(pin), float64(time.Microsecond))
+	s.Spin(-0.5)
+	assert.InDelta(t, 1750*time.Microsecond, servoPulse(pin), float64(time.Microsecond))
+	s.Stop()
+	assert.InDelta(t, 1500*time.Microsecond, servoPulse(pin), float64(time.Microsecond))
+}
+
+func Test_ServoMoveTo(t *testing.T) {
+	ctx := context.Background()
+	pin := NewFakePin()
+	s := NewServoWithPin(pin, ServoConfig{
+		MinPulse: 1000 * time.Microsecond,
+		MaxPulse: 2000 * time.Microsecond,
+		MinAngle: 0,
+		MaxAngle: 180,
+		Freq:     200,
+	})
+	_, _, cycle := pin.PwmState()
+	assert.Equal(t, uint32(6000), cycle)
+
+	// the position is unknown before the first roll
+	assert.NoError(t, s.MoveTo(ctx, 30, time.Second))
+	assert.Equal(t, 30.0, s.Angle())
+
+	var angles []float64
+	done := make(chan error)
+	go func() {
+		done <- s.MoveTo(ctx, 150, 200*time.Millisecond)
+	}()
+	timeout := time.After(time.Second)
+	for moving := true; moving; {
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+			moving = false
+		case <-timeout:
+			t.Fatal("timeout")
+		case <-time.After(time.Millisecond):
+			angles = append(angles, s.Angle())
+		}
+	}
+	assert.Equal(t, 150.0, s.Angle())
+	assert.InDelta(t, 1833333*time.Nanosecond, servoPulse(pin), float64(time.Microsecond))
+	// the angle moves forward smoothly through the middle
+	middle := 0
+	for i := 1; i < len(angles); i++ {
+		assert.GreaterOrEqual(t, angles[i], angles[i-1])
+		if angles[i] > 30 && angles[i] < 150 {
+			middle++
+		}
+	}
+	assert.Greater(t, middle, 10)
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.Error(t, s.Sweep(ctx, 0, 180, time.Second))
+	assert.Equal(t, 0.0, s.Angle())
+
+	assert.InDelta(t, 0, easeInOut(0), 1e-9)
+	assert.InDelta(t, 0.5, easeInOut(0.5), 1e-9)
+	assert.InDelta(t, 1, easeInOut(1), 1e-9)
+	assert.Less(t, easeInOut(0.1), 0.1)
+	assert.Greater(t, easeInOut(0.9), 0.9)
+}
+
+func Test_SG90(t *testing.T) {
+	pin := NewFakePin()
+	sg := NewSG90WithPin(pin)
+	sg.Roll(30)
+	assert.InDelta(t, 1450*time.Microsecond-time.Duration(30*1900/180*float64(time.Microsecond)), servoPulse(pin), float64(time.Microsecond))
+	// out of range angles are ignored
+	sg.Roll(100)
+	assert.Equal(t, 30.0, sg.Angle())
+	var _ ServoMotor = sg
+}
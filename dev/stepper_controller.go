@@ -0,0 +1,487 @@
+/*
+StepperController moves a StepperMotor in the background with acceleration profiles and tracks its absolute position.
+
+It steps the motor one step at a time following a motion plan, so the motor accelerates to MaxSpeed and
+decelerates to stop at the target instead of starting and stopping at full speed, which stalls large motors.
+
+Profiles:
+ - TrapezoidalProfile: constant acceleration, the velocity ramps linearly.
+ - SCurveProfile: the acceleration ramps smoothly from and back to zero, the velocity follows a sine curve.
+   It is gentler on the mechanics, and takes a bit longer than the trapezoidal profile.
+
+Usage:
+
+	a4988 := dev.NewA4988(step, dir, ms1, ms2, ms3)
+	c := dev.NewStepperController(a4988, dev.StepperConfig{
+		MaxSpeed: 800,
+		Accel:    1600,
+		Profile:  dev.SCurveProfile,
+	})
+	limit := dev.NewCollisionSwitch(pin)
+	err := c.Home(ctx, limit, -1)
+	c.MoveTo(1600)
+	c.Wait(ctx)
+	fmt.Println(c.Position())
+*/
+package dev
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// MotionProfile is the velocity profile of a move
+type MotionProfile int
+
+// Motion profiles
+const (
+	TrapezoidalProfile MotionProfile = iota
+	SCurveProfile
+)
+
+var (
+	errStepperBusy   = errors.New("stepper is moving")
+	errEmergencyStop = errors.New("emergency stop")
+	errLimitNotFound = errors.New("limit switch not found")
+	errHomingStopped = errors.New("homing stopped")
+)
+
+// StepperConfig ...
+type StepperConfig struct {
+	// MaxSpeed in steps per second, default 500
+	MaxSpeed float64
+	// Accel is the max acceleration in steps per second^2, default 1000
+	Accel   float64
+	Profile MotionProfile
+	// HomingSpeed is the constant speed in steps per second when homing, default MaxSpeed/4
+	HomingSpeed float64
+	// HomingBackoff is the steps to move back after releasing the limit switch, default 0
+	HomingBackoff int
+	// HomingMaxSteps fails homing if the limit switch isn't hit in so many steps, 0 means no limit
+	HomingMaxSteps int
+}
+
+// StepperController ...
+type StepperController struct {
+	motor StepperMotor
+	cfg   StepperConfig
+
+	mu       sync.Mutex
+	pos      int
+	speed    float64
+	moving   bool
+	stopping bool
+	err      error
+	stop     chan struct{} // closed to decelerate to stop
+	estop    chan struct{} // closed to stop immediately
+	done     chan struct{} // closed when the move is finished
+}
+
+// NewStepperController ...
+func NewStepperController(motor StepperMotor, cfg StepperConfig) *StepperController {
+	if cfg.MaxSpeed <= 0 {
+		cfg.MaxSpeed = 500
+	}
+	if cfg.Accel <= 0 {
+		cfg.Accel = 1000
+	}
+	if cfg.HomingSpeed <= 0 {
+		cfg.HomingSpeed = cfg.MaxSpeed / 4
+	}
+	done := make(chan struct{})
+	close(done)
+	return &StepperController{
+		motor: motor,
+		cfg:   cfg,
+		done:  done,
+	}
+}
+
+// Position returns the absolute position in steps
+func (c *StepperController) Position() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pos
+}
+
+// SetPosition sets the current position, it fails if the motor is moving
+func (c *StepperController) SetPosition(pos int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.moving {
+		return errStepperBusy
+	}
+	c.pos = pos
+	return nil
+}
+
+// Speed returns the current speed in steps per second, negative is counter-clockwise
+func (c *StepperController) Speed() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.speed
+}
+
+// Moving ...
+func (c *StepperController) Moving() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.moving
+}
+
+// MoveTo starts moving to the absolute position in the background, it fails if the motor is moving
+func (c *StepperController) MoveTo(pos int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.start(pos - c.pos)
+}
+
+// Move starts moving n steps relative to the current position in the background,
+// it fails if the motor is moving
+func (c *StepperController) Move(n int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.start(n)
+}
+
+// Wait waits for the move to finish.
+// It returns an error if the move was stopped by EmergencyStop() or ctx is done.
+func (c *StepperController) Wait(ctx context.Context) error {
+	c.mu.Lock()
+	done := c.done
+	c.mu.Unlock()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Stop decelerates to stop following the profile, use Wait() to wait for it
+func (c *StepperController) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.moving && !c.stopping {
+		c.stopping = true
+		close(c.stop)
+	}
+}
+
+// EmergencyStop stops immediately without decelerating, the position is kept
+func (c *StepperController) EmergencyStop() {
+	c.mu.Lock()
+	if !c.moving {
+		c.mu.Unlock()
+		return
+	}
+	select {
+	case <-c.estop:
+	default:
+		close(c.estop)
+	}
+	done := c.done
+	c.mu.Unlock()
+	<-done
+}
+
+// Home moves in the direction (1 or -1) at HomingSpeed until the limit switch is detected,
+// then moves back until it is released, backs off HomingBackoff steps, and sets the position to 0.
+// It blocks until homing is finished, Stop() and EmergencyStop() abort it.
+func (c *StepperController) Home(ctx context.Context, limit Detector, dir int) error {
+	if dir >= 0 {
+		dir = 1
+	} else {
+		dir = -1
+	}
+	c.mu.Lock()
+	if c.moving {
+		c.mu.Unlock()
+		return errStepperBusy
+	}
+	c.reset()
+	c.moving = true
+	stop, estop, done := c.stop, c.estop, c.done
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.moving = false
+		c.speed = 0
+		c.mu.Unlock()
+		close(done)
+	}()
+
+	step := func(dir int, interval time.Duration) error {
+		err := c.stepAt(ctx, dir, interval, stop, estop)
+		if err != nil {
+			c.mu.Lock()
+			c.err = err
+			c.mu.Unlock()
+		}
+		return err
+	}
+	interval := time.Duration(float64(time.Second) / c.cfg.HomingSpeed)
+	// seek the switch
+	for n := 0; !limit.Detected(); n++ {
+		if c.cfg.HomingMaxSteps > 0 && n >= c.cfg.HomingMaxSteps {
+			return errLimitNotFound
+		}
+		if err := step(dir, interval); err != nil {
+			return err
+		}
+	}
+	// release the switch slowly
+	for n := 0; limit.Detected(); n++ {
+		if c.cfg.HomingMaxSteps > 0 && n >= c.cfg.HomingMaxSteps {
+			return errLimitNotFound
+		}
+		if err := step(-dir, 4*interval); err != nil {
+			return err
+		}
+	}
+	for n := 0; n < c.cfg.HomingBackoff; n++ {
+		if err := step(-dir, interval); err != nil {
+			return err
+		}
+	}
+	c.mu.Lock()
+	c.pos = 0
+	c.mu.Unlock()
+	return nil
+}
+
+// stepAt steps once and waits for the interval, stop or estop aborts it
+func (c *StepperController) stepAt(ctx context.Context, dir int, interval time.Duration, stop, estop chan struct{}) error {
+	c.motor.Step(dir)
+	c.mu.Lock()
+	c.pos += dir
+	c.speed = float64(dir) * float64(time.Second) / float64(interval)
+	c.mu.Unlock()
+	t := time.NewTimer(interval)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-stop:
+		return errHomingStopped
+	case <-estop:
+		return errEmergencyStop
+	case <-t.C:
+		return nil
+	}
+}
+
+// reset prepares the states for a new move, c.mu must be held
+func (c *StepperController) reset() {
+	c.err = nil
+	c.stopping = false
+	c.stop = make(chan struct{})
+	c.estop = make(chan struct{})
+	c.done = make(chan struct{})
+}
+
+// start starts moving n steps, c.mu must be held
+func (c *StepperController) start(n int) error {
+	if c.moving {
+		return errStepperBusy
+	}
+	c.reset()
+	if n == 0 {
+		close(c.done)
+		return nil
+	}
+	c.moving = true
+	dir := 1
+	if n < 0 {
+		dir = -1
+	}
+	plan := newMovePlan(float64(n*dir), c.cfg.MaxSpeed, c.cfg.Accel, c.cfg.Profile)
+	go c.run(plan, dir, c.stop, c.estop, c.done)
+	return nil
+}
+
+// run steps the motor following the plan
+func (c *StepperController) run(plan *motionPlan, dir int, stop, estop, done chan struct{}) {
+	defer func() {
+		c.mu.Lock()
+		c.moving = false
+		c.speed = 0
+		c.mu.Unlock()
+		close(done)
+	}()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+
+	start := time.Now()
+	steps := plan.steps()
+	for k := 1; k <= steps; k++ {
+		t := plan.timeAt(float64(k))
+		timer.Reset(time.Until(start.Add(time.Duration(t * float64(time.Second)))))
+		select {
+		case <-estop:
+			c.mu.Lock()
+			c.err = errEmergencyStop
+			c.mu.Unlock()
+			return
+		case <-stop:
+			// decelerate from the current speed
+			v := plan.velocityAt(time.Since(start).Seconds())
+			plan = newStopPlan(v, c.cfg.Accel, c.cfg.Profile)
+			start, steps, k = time.Now(), plan.steps(), 0
+			stop = nil
+			if !timer.Stop() {
+				<-timer.C
+			}
+			continue
+		case <-timer.C:
+		}
+		c.motor.Step(dir)
+		c.mu.Lock()
+		c.pos += dir
+		c.speed = float64(dir) * plan.velocityAt(t)
+		c.mu.Unlock()
+	}
+}
+
+// motionSegment is a part of a motion plan, the velocity changes from v0 to v1 in duration seconds
+type motionSegment struct {
+	v0, v1   float64
+	duration float64
+	// smooth ramps the velocity following a sine curve, otherwise linearly
+	smooth bool
+}
+
+// velocity at t seconds from the start of the segment
+func (s motionSegment) velocity(t float64) float64 {
+	r := t / s.duration
+	if s.smooth {
+		r = (1 - math.Cos(math.Pi*r)) / 2
+	}
+	return s.v0 + (s.v1-s.v0)*r
+}
+
+// distance at t seconds from the start of the segment
+func (s motionSegment) distance(t float64) float64 {
+	if s.smooth {
+		return s.v0*t + (s.v1-s.v0)/2*(t-s.duration/math.Pi*math.Sin(math.Pi*t/s.duration))
+	}
+	return s.v0*t + (s.v1-s.v0)*t*t/(2*s.duration)
+}
+
+// motionPlan is the velocity profile of a move, in steps and seconds
+type motionPlan struct {
+	segments []motionSegment
+	total    float64
+}
+
+// newMovePlan plans a move of the distance from stop to stop
+func newMovePlan(dist, vmax, accel float64, profile MotionProfile) *motionPlan {
+	smooth := profile == SCurveProfile
+	// the distance and time to accelerate from 0 to v
+	rampDist := func(v float64) float64 {
+		if smooth {
+			return v * v * math.Pi / (4 * accel)
+		}
+		return v * v / (2 * accel)
+	}
+	rampTime := func(v float64) float64 {
+		if smooth {
+			// the peak acceleration of the sine curve is pi/2 times the average
+			return math.Pi * v / (2 * accel)
+		}
+		return v / accel
+	}
+
+	vpeak := vmax
+	if 2*rampDist(vmax) > dist {
+		// never reaches the max speed
+		if smooth {
+			vpeak = math.Sqrt(2 * accel * dist / math.Pi)
+		} else {
+			vpeak = math.Sqrt(accel * dist)
+		}
+	}
+	ramp := rampTime(vpeak)
+	cruise := (dist - 2*rampDist(vpeak)) / vpeak
+	p := &motionPlan{total: dist}
+	p.segments = append(p.segments, motionSegment{v0: 0, v1: vpeak, duration: ramp, smooth: smooth})
+	if cruise > 0 {
+		p.segments = append(p.segments, motionSegment{v0: vpeak, v1: vpeak, duration: cruise})
+	}
+	p.segments = append(p.segments, motionSegment{v0: vpeak, v1: 0, duration: ramp, smooth: smooth})
+	return p
+}
+
+// newStopPlan plans decelerating from the velocity to stop
+func newStopPlan(v, accel float64, profile MotionProfile) *motionPlan {
+	if v <= 0 {
+		return &motionPlan{}
+	}
+	seg := motionSegment{v0: v, v1: 0, duration: v / accel}
+	if profile == SCurveProfile {
+		seg.smooth = true
+		seg.duration = math.Pi * v / (2 * accel)
+	}
+	return &motionPlan{
+		segments: []motionSegment{seg},
+		total:    seg.distance(seg.duration),
+	}
+}
+
+// steps returns the number of whole steps of the plan
+func (p *motionPlan) steps() int {
+	return int(math.Round(p.total))
+}
+
+// duration returns the total time of the plan
+func (p *motionPlan) duration() float64 {
+	var d float64
+	for _, s := range p.segments {
+		d += s.duration
+	}
+	return d
+}
+
+// velocityAt returns the velocity at t seconds
+func (p *motionPlan) velocityAt(t float64) float64 {
+	for _, s := range p.segments {
+		if t <= s.duration {
+			return s.velocity(math.Max(0, t))
+		}
+		t -= s.duration
+	}
+	return 0
+}
+
+// timeAt returns the time in seconds when the position reaches dist.
+// It returns the end of the plan if dist is beyond the plan.
+func (p *motionPlan) timeAt(dist float64) float64 {
+	var t float64
+	for _, s := range p.segments {
+		d := s.distance(s.duration)
+		if dist > d {
+			dist -= d
+			t += s.duration
+			continue
+		}
+		// the distance increases monotonically within a segment
+		lo, hi := 0.0, s.duration
+		for i := 0; i < 50; i++ {
+			mid := (lo + hi) / 2
+			if s.distance(mid) < dist {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		return t + (lo+hi)/2
+	}
+	return t
+}
@@ -0,0 +1,227 @@
+package dev
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeStepper records the steps
+type fakeStepper struct {
+	mu    sync.Mutex
+	pos   int
+	steps int
+	times []time.Time
+}
+
+func (s *fakeStepper) Step(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pos += n
+	if n < 0 {
+		n = -n
+	}
+	s.steps += n
+	s.times = append(s.times, time.Now())
+}
+
+func (s *fakeStepper) Roll(angle float64) {}
+
+func (s *fakeStepper) SetMode(mode StepperMode) error {
+	return nil
+}
+
+func (s *fakeStepper) position() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pos
+}
+
+// fakeLimitSwitch is detected when the stepper is at or beyond the position
+type fakeLimitSwitch struct {
+	stepper *fakeStepper
+	at      int
+}
+
+func (l *fakeLimitSwitch) Detected() bool {
+	return l.stepper.position() <= l.at
+}
+
+func Test_TrapezoidalPlan(t *testing.T) {
+	p := newMovePlan(1000, 500, 1000, TrapezoidalProfile)
+	assert.Equal(t, 1000, p.steps())
+	assert.InDelta(t, 2.5, p.duration(), 1e-9)
+	assert.InDelta(t, 0.5, p.timeAt(125), 1e-6)
+	assert.InDelta(t, 2.0, p.timeAt(875), 1e-6)
+	assert.InDelta(t, 2.5, p.timeAt(1000), 1e-6)
+	assert.InDelta(t, 250, p.velocityAt(0.25), 1e-9)
+	assert.InDelta(t, 500, p.velocityAt(1), 1e-9)
+	assert.InDelta(t, 0, p.velocityAt(2.5), 1e-9)
+
+	// too short to reach the max speed
+	p = newMovePlan(100, 500, 1000, TrapezoidalProfile)
+	vpeak := math.Sqrt(1000 * 100)
+	assert.InDelta(t, 2*vpeak/1000, p.duration(), 1e-9)
+	assert.InDelta(t, vpeak, p.velocityAt(vpeak/1000), 1e-9)
+	assert.InDelta(t, vpeak/1000, p.timeAt(50), 1e-6)
+}
+
+func Test_SCurvePlan(t *testing.T) {
+	for _, dist := range []float64{1000, 100} {
+		p := newMovePlan(dist, 500, 1000, SCurveProfile)
+		d := p.duration()
+		// the velocity is almost 0 at the end, so the time is less precise
+		assert.InDelta(t, d, p.timeAt(dist), 1e-4)
+		assert.InDelta(t, d/2, p.timeAt(dist/2), 1e-6)
+
+		// the velocity is smooth, and the acceleration never exceeds the limit
+		dt := d / 10000
+		maxAccel := 0.0
+		for tt := dt; tt <= d; tt += dt {
+			a := (p.velocityAt(tt) - p.velocityAt(tt-dt)) / dt
+			maxAccel = math.Max(maxAccel, math.Abs(a))
+		}
+		assert.InDelta(t, 1000, maxAccel, 1)
+		assert.InDelta(t, 0, p.velocityAt(0), 1e-9)
+		assert.InDelta(t, 0, p.velocityAt(d), 1e-9)
+		assert.LessOrEqual(t, p.velocityAt(d/2), 500.0)
+	}
+
+	// s-curve takes longer than trapezoidal with the same max acceleration
+	assert.Greater(t, newMovePlan(1000, 500, 1000, SCurveProfile).duration(),
+		newMovePlan(1000, 500, 1000, TrapezoidalProfile).duration())
+}
+
+func Test_StopPlan(t *testing.T) {
+	p := newStopPlan(500, 1000, TrapezoidalProfile)
+	assert.InDelta(t, 0.5, p.duration(), 1e-9)
+	assert.Equal(t, 125, p.steps())
+
+	p = newStopPlan(500, 1000, SCurveProfile)
+	assert.InDelta(t, math.Pi/4, p.duration(), 1e-9)
+	assert.InDelta(t, 500*math.Pi/8, p.total, 1e-9)
+
+	assert.Equal(t, 0, newStopPlan(0, 1000, SCurveProfile).steps())
+}
+
+func Test_StepperControllerMove(t *testing.T) {
+	ctx := context.Background()
+	for _, profile := range []MotionProfile{TrapezoidalProfile, SCurveProfile} {
+		s := &fakeStepper{}
+		c := NewStepperController(s, StepperConfig{MaxSpeed: 4000, Accel: 40000, Profile: profile})
+		assert.NoError(t, c.Wait(ctx))
+
+		assert.NoError(t, c.Move(200))
+		assert.True(t, c.Moving())
+		assert.Error(t, c.MoveTo(0))
+		assert.Error(t, c.SetPosition(0))
+		assert.NoError(t, c.Wait(ctx))
+		assert.False(t, c.Moving())
+		assert.Equal(t, 200, c.Position())
+		assert.Equal(t, 200, s.position())
+		assert.Equal(t, 0.0, c.Speed())
+
+		assert.NoError(t, c.MoveTo(-100))
+		assert.NoError(t, c.Wait(ctx))
+		assert.Equal(t, -100, c.Position())
+		assert.Equal(t, -100, s.position())
+		assert.Equal(t, 500, s.steps)
+
+		// the planned steps are slower at the start than in the middle
+		plan := newMovePlan(300, 4000, 40000, profile)
+		first := plan.timeAt(2) - plan.timeAt(1)
+		middle := plan.timeAt(151) - plan.timeAt(150)
+		assert.Greater(t, first, 2*middle)
+
+		assert.NoError(t, c.SetPosition(0))
+		assert.NoError(t, c.MoveTo(0))
+		assert.NoError(t, c.Wait(ctx))
+		assert.Equal(t, 0, c.Position())
+	}
+}
+
+func Test_StepperControllerAccel(t *testing.T) {
+	for _, profile := range []MotionProfile{TrapezoidalProfile, SCurveProfile} {
+		s := &fakeStepper{}
+		c := NewStepperController(s, StepperConfig{MaxSpeed: 4000, Accel: 40000, Profile: profile})
+		assert.NoError(t, c.Move(2000))
+		assert.NoError(t, c.Wait(context.Background()))
+		assert.Len(t, s.times, 2000)
+
+		// the steps are scheduled on the absolute times of the plan, so a late step doesn't delay the others.
+		// The first 10 steps take about 22ms (35ms in s-curve), and 10 steps at the max speed take 2.5ms.
+		first := s.times[10].Sub(s.times[0])
+		cruise := s.times[1100].Sub(s.times[900]) / 20
+		assert.Greater(t, first, 2*cruise, "profile %v", profile)
+	}
+}
+
+func Test_StepperControllerStop(t *testing.T) {
+	ctx := context.Background()
+	s := &fakeStepper{}
+	c := NewStepperController(s, StepperConfig{MaxSpeed: 2000, Accel: 10000})
+
+	assert.NoError(t, c.Move(100000))
+	assert.Eventually(t, func() bool {
+		return c.Speed() > 1500
+	}, time.Second, time.Millisecond)
+	c.Stop()
+	at := c.Position()
+	assert.NoError(t, c.Wait(ctx))
+	// decelerating from 2000 steps/s takes 200 steps
+	assert.InDelta(t, 200, c.Position()-at, 100)
+	assert.Equal(t, s.position(), c.Position())
+
+	// emergency stop doesn't decelerate
+	assert.NoError(t, c.Move(-100000))
+	assert.Eventually(t, func() bool {
+		return c.Speed() < -1500
+	}, time.Second, time.Millisecond)
+	c.EmergencyStop()
+	at = c.Position()
+	assert.Error(t, c.Wait(ctx))
+	assert.Equal(t, at, c.Position())
+	assert.Equal(t, s.position(), c.Position())
+	assert.False(t, c.Moving())
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	assert.NoError(t, c.Move(100000))
+	assert.Error(t, c.Wait(ctx))
+	c.EmergencyStop()
+}
+
+func Test_StepperControllerHome(t *testing.T) {
+	ctx := context.Background()
+	s := &fakeStepper{}
+	limit := &fakeLimitSwitch{stepper: s, at: -30}
+	c := NewStepperController(s, StepperConfig{HomingSpeed: 10000, HomingBackoff: 5})
+
+	assert.NoError(t, c.Home(ctx, limit, -1))
+	assert.Equal(t, 0, c.Position())
+	assert.Equal(t, -24, s.position())
+	assert.False(t, c.Moving())
+
+	assert.NoError(t, c.MoveTo(10))
+	assert.NoError(t, c.Wait(ctx))
+	assert.Equal(t, -14, s.position())
+
+	// the switch is never hit
+	c = NewStepperController(s, StepperConfig{HomingSpeed: 10000, HomingMaxSteps: 20})
+	assert.Error(t, c.Home(ctx, limit, 1))
+
+	// aborted by emergency stop
+	c = NewStepperController(s, StepperConfig{HomingSpeed: 100})
+	errs := make(chan error)
+	go func() {
+		errs <- c.Home(ctx, limit, 1)
+	}()
+	assert.Eventually(t, c.Moving, time.Second, time.Millisecond)
+	c.EmergencyStop()
+	assert.Error(t, <-errs)
+	assert.Error(t, c.Wait(ctx))
+}
@@ -10,18 +10,23 @@ Connect to Pi:
 package dev
 
 import (
-	"github.com/stianeikeland/go-rpio/v4"
+	"context"
 )
 
 // SW420 implements Detector interface
 type SW420 struct {
-	pin rpio.Pin
+	pin Pin
 }
 
 // NewSW420 ...
 func NewSW420(pin uint8) *SW420 {
+	return NewSW420WithPin(newPin(pin))
+}
+
+// NewSW420WithPin ...
+func NewSW420WithPin(pin Pin) *SW420 {
 	sw := &SW420{
-		pin: rpio.Pin(pin),
+		pin: pin,
 	}
 	sw.pin.Input()
 	return sw
@@ -30,5 +35,10 @@ func NewSW420(pin uint8) *SW420 {
 // Detected returns true if the sensor detects shaking,
 // or return false
 func (sw *SW420) Detected() bool {
-	return sw.pin.Read() == rpio.High
+	return sw.pin.Read() == High
+}
+
+// Watch delivers events when shaking starts or stops until ctx is done
+func (sw *SW420) Watch(ctx context.Context) <-chan InputEvent {
+	return watchInput(ctx, sw.pin, High)
 }
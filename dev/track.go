@@ -0,0 +1,167 @@
+/*
+Track loads GPS tracks from GPX 1.1 files and CSV files.
+
+The CSV file has one point per line in the format of time,lat,lon, e.g.
+
+	2019-11-04T08:32:45,39.958134,116.436234
+*/
+package dev
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shanghuiyang/rpi-devices/geo"
+)
+
+const (
+	// CSVTimeLayout is the layout of the time in track CSV files, in UTC
+	CSVTimeLayout = "2006-01-02T15:04:05"
+)
+
+// TrackPoint is a point of a GPS track
+type TrackPoint struct {
+	// Time is zero if the point has no timestamp
+	Time time.Time
+	Lat  float64
+	Lon  float64
+	// Ele is the elevation in meters
+	Ele float64
+}
+
+// Point ...
+func (p TrackPoint) Point() geo.Point {
+	return geo.Point{Lat: p.Lat, Lon: p.Lon}
+}
+
+type gpxPoint struct {
+	Lat  float64 `xml:"lat,attr"`
+	Lon  float64 `xml:"lon,attr"`
+	Ele  float64 `xml:"ele"`
+	Time string  `xml:"time"`
+}
+
+// LoadGPX loads the points of all tracks in a GPX file
+func LoadGPX(file string) ([]TrackPoint, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadGPX(f)
+}
+
+// ReadGPX reads the points of all tracks in GPX data.
+// An unclosed document, e.g. a track being recorded or cut by a power failure,
+// is read up to its last complete point.
+func ReadGPX(r io.Reader) ([]TrackPoint, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	// some apps write a utf-8 BOM
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var path []string
+	var points []TrackPoint
+	started := false
+	for {
+		tok, err := d.Token()
+		if started && (err == io.EOF || unexpectedEOF(err)) {
+			return points, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse gpx error: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			started = true
+			n := len(path)
+			if t.Name.Local != "trkpt" || n < 2 || path[n-2] != "trk" || path[n-1] != "trkseg" {
+				path = append(path, t.Name.Local)
+				continue
+			}
+			var p gpxPoint
+			if err := d.DecodeElement(&p, &t); err != nil {
+				if unexpectedEOF(err) {
+					return points, nil
+				}
+				return nil, fmt.Errorf("parse gpx error: %w", err)
+			}
+			pt := TrackPoint{
+				Lat: p.Lat,
+				Lon: p.Lon,
+				Ele: p.Ele,
+			}
+			if s := strings.TrimSpace(p.Time); s != "" {
+				t, err := time.Parse(time.RFC3339, s)
+				if err != nil {
+					return nil, fmt.Errorf("parse gpx time error: %w", err)
+				}
+				pt.Time = t.UTC()
+			}
+			points = append(points, pt)
+		case xml.EndElement:
+			path = path[:len(path)-1]
+		}
+	}
+}
+
+// unexpectedEOF returns true if the xml data ends in an unclosed element
+func unexpectedEOF(err error) bool {
+	var se *xml.SyntaxError
+	return errors.As(err, &se) && se.Msg == "unexpected EOF"
+}
+
+// LoadCSV loads the points in a CSV file
+func LoadCSV(file string) ([]TrackPoint, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadCSV(f)
+}
+
+// ReadCSV reads the points in CSV data. Blank lines are skipped.
+func ReadCSV(r io.Reader) ([]TrackPoint, error) {
+	var points []TrackPoint
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		items := strings.Split(line, ",")
+		if len(items) != 3 {
+			return nil, fmt.Errorf("invalid csv format at line %v", n)
+		}
+		t, err := time.Parse(CSVTimeLayout, items[0])
+		if err != nil {
+			return nil, fmt.Errorf("parse time error at line %v: %w", n, err)
+		}
+		lat, err := strconv.ParseFloat(items[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse lat error at line %v: %w", n, err)
+		}
+		lon, err := strconv.ParseFloat(items[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse lon error at line %v: %w", n, err)
+		}
+		points = append(points, TrackPoint{Time: t, Lat: lat, Lon: lon})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return points, nil
+}
@@ -0,0 +1,137 @@
+package dev
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+const (
+	gpxHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="rpi-devices" xmlns="http://www.topografix.com/GPX/1/1" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://www.topografix.com/GPX/1/1 http://www.topografix.com/GPX/1/1/gpx.xsd">
+  <trk>
+`
+	gpxSegmentStart = "    <trkseg>\n"
+	gpxSegmentEnd   = "    </trkseg>\n"
+	gpxFooter       = "  </trk>\n</gpx>\n"
+)
+
+// GPXWriter writes a GPX 1.1 track.
+// The points are appended and synced to disk one by one, and the closing tags are written by Close().
+// ReadGPX reads an unclosed file up to its last complete point, so a power cut loses the last point at most.
+type GPXWriter struct {
+	f      *os.File
+	inSeg  bool
+	newSeg bool
+}
+
+// CreateGPX creates or truncates the file, and writes an empty track with the name
+func CreateGPX(file, name string) (*GPXWriter, error) {
+	f, err := os.Create(file)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	b.WriteString(gpxHeader)
+	if name != "" {
+		b.WriteString("    <name>")
+		if err := xml.EscapeText(&b, []byte(name)); err != nil {
+			f.Close()
+			return nil, err
+		}
+		b.WriteString("</name>\n")
+	}
+	w := &GPXWriter{f: f}
+	if err := w.write(b.Bytes()); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// NewSegment starts a new segment from the next point
+func (w *GPXWriter) NewSegment() {
+	w.newSeg = true
+}
+
+// Write appends the point to the current segment
+func (w *GPXWriter) Write(p TrackPoint) error {
+	var b bytes.Buffer
+	if w.inSeg && w.newSeg {
+		b.WriteString(gpxSegmentEnd)
+		w.inSeg = false
+	}
+	if !w.inSeg {
+		b.WriteString(gpxSegmentStart)
+		w.inSeg = true
+	}
+	w.newSeg = false
+
+	fmt.Fprintf(&b, "      <trkpt lat=\"%v\" lon=\"%v\">\n",
+		strconv.FormatFloat(p.Lat, 'f', -1, 64), strconv.FormatFloat(p.Lon, 'f', -1, 64))
+	if p.Ele != 0 {
+		fmt.Fprintf(&b, "        <ele>%v</ele>\n", strconv.FormatFloat(p.Ele, 'f', -1, 64))
+	}
+	if !p.Time.IsZero() {
+		fmt.Fprintf(&b, "        <time>%v</time>\n", p.Time.UTC().Format("2006-01-02T15:04:05.999Z"))
+	}
+	b.WriteString("      </trkpt>\n")
+	return w.write(b.Bytes())
+}
+
+// Close writes the closing tags and closes the file
+func (w *GPXWriter) Close() error {
+	footer := gpxFooter
+	if w.inSeg {
+		footer = gpxSegmentEnd + footer
+	}
+	if err := w.write([]byte(footer)); err != nil {
+		w.f.Close()
+		return err
+	}
+	return w.f.Close()
+}
+
+// write appends data to the file, and syncs the file
+func (w *GPXWriter) write(data []byte) error {
+	if _, err := w.f.Write(data); err != nil {
+		return fmt.Errorf("write gpx error: %w", err)
+	}
+	if err := w.f.Sync(); err != nil {
+		return fmt.Errorf("sync gpx error: %w", err)
+	}
+	return nil
+}
+
+// CSVWriter writes points in the format of time,lat,lon, and syncs the file after every point
+type CSVWriter struct {
+	f *os.File
+}
+
+// CreateCSV creates the file, or appends to it if it exists
+func CreateCSV(file string) (*CSVWriter, error) {
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &CSVWriter{f: f}, nil
+}
+
+// Write ...
+func (w *CSVWriter) Write(p TrackPoint) error {
+	line := fmt.Sprintf("%v,%.6f,%.6f\n", p.Time.UTC().Format(CSVTimeLayout), p.Lat, p.Lon)
+	if _, err := w.f.WriteString(line); err != nil {
+		return fmt.Errorf("write csv error: %w", err)
+	}
+	if err := w.f.Sync(); err != nil {
+		return fmt.Errorf("sync csv error: %w", err)
+	}
+	return nil
+}
+
+// Close ...
+func (w *CSVWriter) Close() error {
+	return w.f.Close()
+}
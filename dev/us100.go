@@ -41,9 +41,6 @@ package dev
 import (
 	"fmt"
 	"time"
-
-	"github.com/stianeikeland/go-rpio/v4"
-	"github.com/tarm/serial"
 )
 
 const (
@@ -61,19 +58,24 @@ type US100 struct {
 	buf   [4]byte
 
 	// ttl mode
-	trig rpio.Pin
-	echo rpio.Pin
+	trig Pin
+	echo Pin
 
 	// uart mode
-	port *serial.Port
+	port SerialPort
 }
 
 // NewUS100GPIO creates US100 using GPOI interface
 func NewUS100GPIO(trig, echo uint8) (*US100, error) {
+	return NewUS100GPIOWithPins(newPin(trig), newPin(echo))
+}
+
+// NewUS100GPIOWithPins creates US100 using GPOI interface with the given pins
+func NewUS100GPIOWithPins(trig, echo Pin) (*US100, error) {
 	us := &US100{
 		iface: GPIO,
-		trig:  rpio.Pin(trig),
-		echo:  rpio.Pin(echo),
+		trig:  trig,
+		echo:  echo,
 	}
 	us.trig.Output()
 	us.trig.Low()
@@ -83,19 +85,23 @@ func NewUS100GPIO(trig, echo uint8) (*US100, error) {
 
 // NewUS100UART creates US100 using UART interface
 func NewUS100UART(dev string, baud int) (*US100, error) {
-	cfg := &serial.Config{
-		Name:        dev,
+	port, err := OpenSerial(SerialConfig{
+		Dev:         dev,
 		Baud:        baud,
 		ReadTimeout: 1 * time.Second,
-	}
-	port, err := serial.OpenPort(cfg)
+	})
 	if err != nil {
 		return nil, err
 	}
+	return NewUS100UARTWithPort(port), nil
+}
+
+// NewUS100UARTWithPort creates US100 using UART interface with the given port
+func NewUS100UARTWithPort(port SerialPort) *US100 {
 	return &US100{
 		iface: UART,
 		port:  port,
-	}, nil
+	}
 }
 
 // Value returns the distance in cm to objects
@@ -141,7 +147,7 @@ func (us *US100) distFromGPIO() (float64, error) {
 	delayUs(1)
 
 	us.echo.PullDown()
-	us.echo.Detect(rpio.RiseEdge)
+	us.echo.Detect(RiseEdge)
 	for i := 0; !us.echo.EdgeDetected(); i++ {
 		if i >= us100Timeout {
 			return us100MaxDist, nil
@@ -150,7 +156,7 @@ func (us *US100) distFromGPIO() (float64, error) {
 	}
 
 	start := time.Now()
-	us.echo.Detect(rpio.FallEdge)
+	us.echo.Detect(FallEdge)
 	for i := 0; !us.echo.EdgeDetected(); i++ {
 		if i >= us100Timeout {
 			return us100MaxDist, nil
@@ -158,7 +164,7 @@ func (us *US100) distFromGPIO() (float64, error) {
 		delayNs(1)
 	}
 	dist := time.Since(start).Seconds() * voiceSpeed / 2.0
-	us.echo.Detect(rpio.NoEdge)
+	us.echo.Detect(NoEdge)
 	us.trig.Low()
 	return dist, nil
 }
@@ -11,18 +11,23 @@ Connect to Raspberry Pi:
 package dev
 
 import (
-	"github.com/stianeikeland/go-rpio/v4"
+	"context"
 )
 
 // VoiceDetector implements Detector interface
 type VoiceDetector struct {
-	pin rpio.Pin
+	pin Pin
 }
 
 // NewVoiceDetector ...
 func NewVoiceDetector(pin uint8) *VoiceDetector {
+	return NewVoiceDetectorWithPin(newPin(pin))
+}
+
+// NewVoiceDetectorWithPin ...
+func NewVoiceDetectorWithPin(pin Pin) *VoiceDetector {
 	v := &VoiceDetector{
-		pin: rpio.Pin(pin),
+		pin: pin,
 	}
 	v.pin.Input()
 	return v
@@ -30,5 +35,10 @@ func NewVoiceDetector(pin uint8) *VoiceDetector {
 
 // Detected ...
 func (v *VoiceDetector) Detected() bool {
-	return v.pin.Read() == rpio.Low
+	return v.pin.Read() == Low
+}
+
+// Watch delivers events when voice is detected or gone until ctx is done
+func (v *VoiceDetector) Watch(ctx context.Context) <-chan InputEvent {
+	return watchInput(ctx, v.pin, Low)
 }
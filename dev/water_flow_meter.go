@@ -1,21 +1,455 @@
+/*
+WaterFlowMeter is a hall effect water flow sensor like YF-S201, which sends a pulse for a fixed volume of water.
+It counts the pulses by edge events in the background, and reports the flow rate and the total volume.
+
+The K-factor (pulses per liter) is 450 for YF-S201 by default, and it differs a little from sensor to sensor.
+Calibrate it by pouring a known volume:
+
+	w.StartCalibration()
+	// pour exactly 1 liter through the sensor
+	k, err := w.Calibrate(1)
+
+The total volume can be persisted to TotalFile, so it survives restarts.
+A leak alarm is raised when the water keeps flowing for LeakTime without a stop longer than IdleTime.
+
+Connect to Raspberry Pi:
+ - red:    5v pin
+ - black:  any gnd pin
+ - yellow: any data pin, through a voltage divider or level shifter since the output is 5v
+
+Usage:
+
+	w, err := dev.NewWaterFlowMeterWithConfig(dev.NewRpioPin(17), dev.WaterFlowMeterConfig{
+		TotalFile: "/var/lib/water/total",
+		LeakTime:  30 * time.Minute,
+	})
+	defer w.Close()
+	log.Printf("flow: %.2f L/min, total: %.1f L", w.Rate(), w.Total())
+	for ev := range w.WatchLeak(ctx) {
+		log.Printf("leaking: %v", ev.Active)
+	}
+*/
 package dev
 
-import "github.com/stianeikeland/go-rpio/v4"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPulsesPerLiter = 450
+	defaultFlowWindow     = time.Second
+	defaultFlowIdleTime   = 10 * time.Second
+	defaultFlowSaveTime   = 10 * time.Second
+	flowCheckInterval     = time.Second
+)
+
+var errNoPulses = errors.New("no pulses counted")
+
+// WaterFlowMeterConfig ...
+type WaterFlowMeterConfig struct {
+	// PulsesPerLiter is the K-factor, default 450 for YF-S201
+	PulsesPerLiter float64
+	// Window is the sliding window for computing the flow rate, default 1s
+	Window time.Duration
+	// TotalFile persists the total volume across restarts, the total isn't persisted if it is empty
+	TotalFile string
+	// SaveInterval is the interval of saving the total volume if it changed, default 10s
+	SaveInterval time.Duration
+	// LeakTime raises the leak alarm if the water keeps flowing for it, 0 disables the alarm
+	LeakTime time.Duration
+	// IdleTime is the time without pulses to consider the flow stopped, default 10s
+	IdleTime time.Duration
+}
 
 // WaterFlowMeter implements Detector interface
 type WaterFlowMeter struct {
-	pin rpio.Pin
+	pin    Pin
+	cfg    WaterFlowMeterConfig
+	once   sync.Once
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu         sync.Mutex
+	k          float64
+	total      float64
+	saved      float64
+	pulses     uint64
+	calibStart uint64
+	calibrated bool
+	recent     []time.Time
+	last       time.Time
+	flowStart  time.Time
+	leaking    bool
+	subs       map[chan InputEvent]struct{}
+	leakSubs   map[chan InputEvent]struct{}
 }
 
 // NewWaterFlowMeter ...
 func NewWaterFlowMeter(pin uint8) *WaterFlowMeter {
+	return NewWaterFlowMeterWithPin(newPin(pin))
+}
+
+// NewWaterFlowMeterWithPin creates a meter for YF-S201 without persisting and leak alarm.
+// It starts counting on the first call of Watch(), WatchLeak(), Rate(), Total(), Pulses(), Leaking() or StartCalibration(),
+// so it works as a passive detector if only Detected() is used.
+func NewWaterFlowMeterWithPin(pin Pin) *WaterFlowMeter {
+	w, _ := newWaterFlowMeter(pin, WaterFlowMeterConfig{})
+	return w
+}
+
+// NewWaterFlowMeterWithConfig creates a meter and starts counting, the total volume is loaded from TotalFile
+func NewWaterFlowMeterWithConfig(pin Pin, cfg WaterFlowMeterConfig) (*WaterFlowMeter, error) {
+	w, err := newWaterFlowMeter(pin, cfg)
+	if err != nil {
+		return nil, err
+	}
+	w.start()
+	return w, nil
+}
+
+// newWaterFlowMeter creates a meter without counting
+func newWaterFlowMeter(pin Pin, cfg WaterFlowMeterConfig) (*WaterFlowMeter, error) {
+	if cfg.PulsesPerLiter <= 0 {
+		cfg.PulsesPerLiter = defaultPulsesPerLiter
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = defaultFlowWindow
+	}
+	if cfg.SaveInterval <= 0 {
+		cfg.SaveInterval = defaultFlowSaveTime
+	}
+	if cfg.IdleTime <= 0 {
+		cfg.IdleTime = defaultFlowIdleTime
+	}
 	w := &WaterFlowMeter{
-		pin: rpio.Pin(pin),
+		pin:      pin,
+		cfg:      cfg,
+		k:        cfg.PulsesPerLiter,
+		done:     make(chan struct{}),
+		subs:     make(map[chan InputEvent]struct{}),
+		leakSubs: make(map[chan InputEvent]struct{}),
+	}
+	if cfg.TotalFile != "" {
+		total, err := loadFlowTotal(cfg.TotalFile)
+		if err != nil {
+			return nil, err
+		}
+		w.total, w.saved = total, total
 	}
 	w.pin.Input()
-	return w
+	return w, nil
 }
 
+// Detected returns true if the level of the pin is high
 func (w *WaterFlowMeter) Detected() bool {
-	return w.pin.Read() == rpio.High
+	return w.pin.Read() == High
+}
+
+// Watch delivers events on every pulse of the meter until ctx is done,
+// the event is active on the rising edge and inactive on the falling edge.
+func (w *WaterFlowMeter) Watch(ctx context.Context) <-chan InputEvent {
+	w.start()
+	return w.subscribe(ctx, w.subs)
+}
+
+// WatchLeak delivers an active event when the leak alarm is raised, and an inactive one when the flow stops
+func (w *WaterFlowMeter) WatchLeak(ctx context.Context) <-chan InputEvent {
+	w.start()
+	return w.subscribe(ctx, w.leakSubs)
+}
+
+// Rate returns the flow rate in L/min over the sliding window
+func (w *WaterFlowMeter) Rate() float64 {
+	w.start()
+	return w.rateAt(time.Now())
+}
+
+// Total returns the total volume in liters
+func (w *WaterFlowMeter) Total() float64 {
+	w.start()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.total
+}
+
+// ResetTotal sets the total volume to 0
+func (w *WaterFlowMeter) ResetTotal() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.total = 0
+}
+
+// Pulses returns the pulses counted since created
+func (w *WaterFlowMeter) Pulses() uint64 {
+	w.start()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.pulses
+}
+
+// Leaking returns true if the leak alarm is raised
+func (w *WaterFlowMeter) Leaking() bool {
+	w.start()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.leaking
+}
+
+// PulsesPerLiter returns the K-factor
+func (w *WaterFlowMeter) PulsesPerLiter() float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.k
+}
+
+// SetPulsesPerLiter sets the K-factor
+func (w *WaterFlowMeter) SetPulsesPerLiter(k float64) {
+	if k <= 0 {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.k = k
+}
+
+// StartCalibration starts counting the pulses for Calibrate()
+func (w *WaterFlowMeter) StartCalibration() {
+	w.start()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.calibStart, w.calibrated = w.pulses, true
+}
+
+// Calibrate sets the K-factor by the pulses counted since StartCalibration() for the liters poured,
+// and returns the new K-factor
+func (w *WaterFlowMeter) Calibrate(liters float64) (float64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	n := w.pulses - w.calibStart
+	if !w.calibrated || n == 0 {
+		return 0, errNoPulses
+	}
+	if liters <= 0 {
+		return 0, fmt.Errorf("invalid volume %v", liters)
+	}
+	w.k = float64(n) / liters
+	w.calibrated = false
+	return w.k, nil
+}
+
+// Close stops counting, saves the total volume, and closes the channels returned by Watch() and WatchLeak()
+func (w *WaterFlowMeter) Close() error {
+	w.once.Do(func() {
+		// never started counting
+		w.cancel = func() {}
+		close(w.done)
+	})
+	w.cancel()
+	<-w.done
+	return w.save()
+}
+
+// start starts counting in the background if it isn't started or closed
+func (w *WaterFlowMeter) start() {
+	w.once.Do(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		w.cancel = cancel
+		edges := WatchEdgesInterval(ctx, w.pin, AnyEdge, pulsePollInterval)
+		go w.run(edges)
+	})
+}
+
+func (w *WaterFlowMeter) run(edges <-chan EdgeEvent) {
+	defer close(w.done)
+	defer w.closeSubs()
+	check := time.NewTicker(flowCheckInterval)
+	defer check.Stop()
+	save := time.NewTicker(w.cfg.SaveInterval)
+	defer save.Stop()
+	for {
+		select {
+		case ev, ok := <-edges:
+			if !ok {
+				return
+			}
+			if ev.Edge == RiseEdge {
+				w.pulse(ev.Time)
+			}
+			w.publish(w.subs, InputEvent{Active: ev.Edge == RiseEdge, Time: ev.Time})
+		case now := <-check.C:
+			w.check(now)
+		case <-save.C:
+			_ = w.save()
+		}
+	}
+}
+
+// pulse counts a pulse at the time t
+func (w *WaterFlowMeter) pulse(t time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pulses++
+	w.total += 1 / w.k
+	w.recent = append(w.recent, t)
+	w.trim(t)
+
+	if w.last.IsZero() || t.Sub(w.last) > w.cfg.IdleTime {
+		w.flowStart = t
+	}
+	w.last = t
+	if w.cfg.LeakTime > 0 && !w.leaking && t.Sub(w.flowStart) >= w.cfg.LeakTime {
+		w.leaking = true
+		w.publishLocked(w.leakSubs, InputEvent{Active: true, Time: t})
+	}
+}
+
+// check clears the leak alarm if the flow has stopped
+func (w *WaterFlowMeter) check(now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.trim(now)
+	if w.leaking && now.Sub(w.last) > w.cfg.IdleTime {
+		w.leaking = false
+		w.publishLocked(w.leakSubs, InputEvent{Active: false, Time: now})
+	}
+}
+
+// rateAt returns the flow rate in L/min over the window before now
+func (w *WaterFlowMeter) rateAt(now time.Time) float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	start := now.Add(-w.cfg.Window)
+	n := 0
+	for _, t := range w.recent {
+		if t.After(start) && !t.After(now) {
+			n++
+		}
+	}
+	return float64(n) / w.k / w.cfg.Window.Minutes()
+}
+
+// trim drops the pulses out of the window, w.mu must be held
+func (w *WaterFlowMeter) trim(now time.Time) {
+	start := now.Add(-w.cfg.Window)
+	i := 0
+	for i < len(w.recent) && !w.recent[i].After(start) {
+		i++
+	}
+	w.recent = w.recent[i:]
+}
+
+// save writes the total volume to TotalFile if it changed
+func (w *WaterFlowMeter) save() error {
+	if w.cfg.TotalFile == "" {
+		return nil
+	}
+	w.mu.Lock()
+	total := w.total
+	changed := total != w.saved
+	w.mu.Unlock()
+	if !changed {
+		return nil
+	}
+	if err := saveFlowTotal(w.cfg.TotalFile, total); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.saved = total
+	w.mu.Unlock()
+	return nil
+}
+
+func (w *WaterFlowMeter) subscribe(ctx context.Context, subs map[chan InputEvent]struct{}) <-chan InputEvent {
+	ch := make(chan InputEvent, eventBufSize)
+	w.mu.Lock()
+	subs[ch] = struct{}{}
+	w.mu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-w.done:
+		}
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if _, ok := subs[ch]; ok {
+			delete(subs, ch)
+			close(ch)
+		}
+	}()
+	return ch
+}
+
+func (w *WaterFlowMeter) publish(subs map[chan InputEvent]struct{}, ev InputEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.publishLocked(subs, ev)
+}
+
+// publishLocked sends the event without blocking, w.mu must be held
+func (w *WaterFlowMeter) publishLocked(subs map[chan InputEvent]struct{}, ev InputEvent) {
+	for ch := range subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+func (w *WaterFlowMeter) closeSubs() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, subs := range []map[chan InputEvent]struct{}{w.subs, w.leakSubs} {
+		for ch := range subs {
+			delete(subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// loadFlowTotal reads the total volume from the file, it is 0 if the file doesn't exist
+func loadFlowTotal(file string) (float64, error) {
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to load total volume: %w", err)
+	}
+	total, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load total volume: %w", err)
+	}
+	return total, nil
+}
+
+// saveFlowTotal writes the total volume to a temp file and renames it to the file,
+// so the file is never left half written on power loss.
+func saveFlowTotal(file string, total float64) error {
+	tmp := file + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to save total volume: %w", err)
+	}
+	_, err = fmt.Fprintf(f, "%.6f\n", total)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, file)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save total volume: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,179 @@
+package dev
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// flowPulses drives n pulses on the fake pin
+func flowPulses(p *FakePin, n int) {
+	for i := 0; i < n; i++ {
+		p.Set(High)
+		time.Sleep(2 * time.Millisecond)
+		p.Set(Low)
+		time.Sleep(2 * time.Millisecond)
+	}
+}
+
+func Test_WaterFlowMeterCount(t *testing.T) {
+	pin := NewFakePin()
+	w, err := NewWaterFlowMeterWithConfig(pin, WaterFlowMeterConfig{PulsesPerLiter: 10})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := w.Watch(ctx)
+
+	flowPulses(pin, 20)
+	assert.Eventually(t, func() bool { return w.Pulses() == 20 }, time.Second, time.Millisecond)
+	assert.InDelta(t, 2, w.Total(), 1e-9)
+	assert.Greater(t, w.Rate(), 0.0)
+	assert.True(t, (<-events).Active)
+	assert.False(t, (<-events).Active)
+
+	w.ResetTotal()
+	assert.Equal(t, 0.0, w.Total())
+	cancel()
+	for range events {
+	}
+	assert.NoError(t, w.Close())
+}
+
+func Test_WaterFlowMeterRate(t *testing.T) {
+	w, err := NewWaterFlowMeterWithConfig(NewFakePin(), WaterFlowMeterConfig{Window: 2 * time.Second})
+	assert.NoError(t, err)
+	defer w.Close()
+
+	// 7.5 pulses per second is 1 L/min for YF-S201
+	start := time.Now()
+	for i := 0; i < 75; i++ {
+		w.pulse(start.Add(time.Duration(i) * time.Second * 2 / 15))
+	}
+	now := start.Add(10 * time.Second)
+	assert.InDelta(t, 1, w.rateAt(now), 0.1)
+	assert.InDelta(t, 75.0/450, w.Total(), 1e-9)
+
+	// the flow stops
+	w.check(now.Add(3 * time.Second))
+	assert.Equal(t, 0.0, w.rateAt(now.Add(3*time.Second)))
+}
+
+func Test_WaterFlowMeterLeak(t *testing.T) {
+	w, err := NewWaterFlowMeterWithConfig(NewFakePin(), WaterFlowMeterConfig{
+		LeakTime: 10 * time.Minute,
+		IdleTime: 5 * time.Second,
+	})
+	assert.NoError(t, err)
+	defer w.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	alarms := w.WatchLeak(ctx)
+
+	// a short stop resets the continuous flow
+	start := time.Now()
+	w.pulse(start)
+	w.pulse(start.Add(4 * time.Minute))
+	assert.False(t, w.Leaking())
+
+	// a trickle every 2s for 10 minutes
+	t0 := start.Add(4 * time.Minute)
+	var last time.Time
+	for i := 1; i <= 300; i++ {
+		last = t0.Add(time.Duration(i) * 2 * time.Second)
+		w.pulse(last)
+	}
+	assert.True(t, w.Leaking())
+	ev := <-alarms
+	assert.True(t, ev.Active)
+	assert.Equal(t, t0.Add(10*time.Minute), ev.Time)
+
+	// the alarm keeps until the flow stops
+	w.check(last.Add(3 * time.Second))
+	assert.True(t, w.Leaking())
+	w.check(last.Add(6 * time.Second))
+	assert.False(t, w.Leaking())
+	assert.False(t, (<-alarms).Active)
+}
+
+func Test_WaterFlowMeterPersist(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "total")
+	cfg := WaterFlowMeterConfig{PulsesPerLiter: 2, TotalFile: file}
+
+	w, err := NewWaterFlowMeterWithConfig(NewFakePin(), cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, w.Total())
+	for i := 0; i < 5; i++ {
+		w.pulse(time.Now())
+	}
+	assert.NoError(t, w.Close())
+	data, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "2.500000\n", string(data))
+
+	// restart
+	w, err = NewWaterFlowMeterWithConfig(NewFakePin(), cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, 2.5, w.Total())
+	w.pulse(time.Now())
+	assert.NoError(t, w.Close())
+
+	w, err = NewWaterFlowMeterWithConfig(NewFakePin(), cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, 3.0, w.Total())
+	assert.NoError(t, w.Close())
+
+	assert.NoError(t, os.WriteFile(file, []byte("bad"), 0644))
+	_, err = NewWaterFlowMeterWithConfig(NewFakePin(), cfg)
+	assert.Error(t, err)
+}
+
+func Test_WaterFlowMeterCalibrate(t *testing.T) {
+	w := NewWaterFlowMeterWithPin(NewFakePin())
+	defer w.Close()
+	assert.Equal(t, 450.0, w.PulsesPerLiter())
+
+	_, err := w.Calibrate(1)
+	assert.Error(t, err)
+
+	w.StartCalibration()
+	for i := 0; i < 240; i++ {
+		w.pulse(time.Now())
+	}
+	_, err = w.Calibrate(0)
+	assert.Error(t, err)
+	k, err := w.Calibrate(0.5)
+	assert.NoError(t, err)
+	assert.Equal(t, 480.0, k)
+	assert.Equal(t, 480.0, w.PulsesPerLiter())
+
+	w.SetPulsesPerLiter(7.5)
+	assert.Equal(t, 7.5, w.PulsesPerLiter())
+}
+
+func Test_WaterFlowMeterLazy(t *testing.T) {
+	pin := NewFakePin()
+	w := NewWaterFlowMeterWithPin(pin)
+
+	// a passive detector until counting is needed
+	pin.Set(High)
+	assert.True(t, w.Detected())
+	pin.Set(Low)
+	assert.Nil(t, w.cancel)
+
+	assert.Equal(t, 0.0, w.Total())
+	assert.NotNil(t, w.cancel)
+	flowPulses(pin, 9)
+	assert.Eventually(t, func() bool { return w.Pulses() == 9 }, time.Second, time.Millisecond)
+	assert.NoError(t, w.Close())
+
+	// closing without counting
+	w = NewWaterFlowMeterWithPin(NewFakePin())
+	assert.NoError(t, w.Close())
+	assert.Equal(t, 0.0, w.Total())
+	assert.Equal(t, uint64(0), w.Pulses())
+}
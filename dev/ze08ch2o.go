@@ -28,8 +28,6 @@ import (
 	"fmt"
 	"math"
 	"time"
-
-	"github.com/tarm/serial"
 )
 
 const (
@@ -37,9 +35,16 @@ const (
 	ze08ch2oHistorySize = 10
 )
 
+var ze08ch2oConfig = SerialConfig{
+	Dev:         "/dev/ttyAMA0",
+	Baud:        9600,
+	ReadTimeout: 5 * time.Second,
+}
+
 // ZE08CH2O implements CH2OMeter interface
 type ZE08CH2O struct {
-	port     *serial.Port
+	port     SerialPort
+	cfg      *SerialConfig
 	buf      [32]byte
 	maxRetry int
 
@@ -47,22 +52,40 @@ type ZE08CH2O struct {
 	next    int
 }
 
-// NewZE08CH2O ...
+// NewZE08CH2O creates ZE08CH2O on /dev/ttyAMA0 with 9600 baud
 func NewZE08CH2O() (*ZE08CH2O, error) {
+	return NewZE08CH2OWithConfig(ze08ch2oConfig)
+}
+
+// NewZE08CH2OWithConfig creates ZE08CH2O using the serial config.
+// The port will be reopened using the config if reading data fails.
+func NewZE08CH2OWithConfig(cfg SerialConfig) (*ZE08CH2O, error) {
+	ze := newZE08CH2O()
+	ze.cfg = &cfg
+	if err := ze.open(); err != nil {
+		return nil, err
+	}
+	return ze, nil
+}
+
+// NewZE08CH2OWithPort creates ZE08CH2O using the port.
+// The port won't be reopened if reading data fails.
+func NewZE08CH2OWithPort(port SerialPort) *ZE08CH2O {
+	ze := newZE08CH2O()
+	ze.port = port
+	return ze
+}
+
+func newZE08CH2O() *ZE08CH2O {
 	history := make([]float64, ze08ch2oHistorySize)
 	for i := range history {
 		history[i] = -1
 	}
-
-	ze := &ZE08CH2O{
+	return &ZE08CH2O{
 		maxRetry: 10,
 		history:  history,
 		next:     0,
 	}
-	if err := ze.open(); err != nil {
-		return nil, err
-	}
-	return ze, nil
 }
 
 // Get returns ch2o in mg/m3
@@ -75,6 +98,9 @@ func (ze *ZE08CH2O) Value() (float64, error) {
 		for a < 9 {
 			n, err := ze.port.Read(ze.buf[a:])
 			if err != nil {
+				if ze.cfg == nil {
+					return 0, fmt.Errorf("read port error: %w", err)
+				}
 				// try to reopen serial
 				if err := ze.port.Close(); err != nil {
 					return 0, fmt.Errorf("close port error: %w", err)
@@ -111,12 +137,7 @@ func (ze *ZE08CH2O) Close() error {
 }
 
 func (ze *ZE08CH2O) open() error {
-	c := &serial.Config{
-		Name:        "/dev/ttyAMA0",
-		Baud:        9600,
-		ReadTimeout: 5 * time.Second,
-	}
-	port, err := serial.OpenPort(c)
+	port, err := OpenSerial(*ze.cfg)
 	if err != nil {
 		return err
 	}
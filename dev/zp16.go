@@ -24,26 +24,28 @@ package dev
 
 import (
 	"fmt"
-
-	"github.com/tarm/serial"
 )
 
 // ZP16 ...
 type ZP16 struct {
-	port *serial.Port
+	port SerialPort
 }
 
 // NewZP16 ...
 func NewZP16(dev string, baud int) (*ZP16, error) {
-	cfg := &serial.Config{
-		Name: dev,
+	port, err := OpenSerial(SerialConfig{
+		Dev:  dev,
 		Baud: baud,
-	}
-	port, err := serial.OpenPort(cfg)
+	})
 	if err != nil {
 		return nil, err
 	}
-	return &ZP16{port}, nil
+	return NewZP16WithPort(port), nil
+}
+
+// NewZP16WithPort ...
+func NewZP16WithPort(port SerialPort) *ZP16 {
+	return &ZP16{port}
 }
 
 // Loc ...
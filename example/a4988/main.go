@@ -16,8 +16,16 @@ const (
 )
 
 func main() {
-	stepper := dev.NewA4988(step, dir, ms1, ms2, ms3)
-	stepper.SetMode(dev.HalfMode)
+	stepper := dev.NewA4988WithConfig(
+		dev.NewRpioPin(step), dev.NewRpioPin(dir),
+		dev.NewRpioPin(ms1), dev.NewRpioPin(ms2), dev.NewRpioPin(ms3),
+		dev.A4988Config{
+			Mode:   dev.HalfMode,
+			Enable: dev.NewRpioPin(enb),
+		},
+	)
+	stepper.Enable()
+	defer stepper.Disable()
 	var s int
 	for {
 		fmt.Printf(">>steps: ")
@@ -17,6 +17,7 @@ func main() {
 	stepper := dev.NewBYJ2848(in1, in2, in3, in4)
 	var step int
 	for {
+		// the stepper is in FullMode by default, 2048 steps per revolution
 		fmt.Printf(">>steps: ")
 		if n, err := fmt.Scanf("%d", &step); n != 1 || err != nil {
 			fmt.Printf("invalid steps, error: %v", err)
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/shanghuiyang/rpi-devices/dev"
+)
+
+const (
+	pumpPin  = 26
+	meterPin = 17
+	targetMl = 250
+)
+
+func main() {
+	meter := dev.NewWaterFlowMeter(meterPin)
+	defer meter.Close()
+	pump := dev.NewPumpImp(pumpPin)
+	c := dev.NewDosingController(pump, meter, dev.DosingConfig{
+		MaxRuntime: time.Minute,
+		DryRunTime: 5 * time.Second,
+	})
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	ml, err := c.Dose(ctx, targetMl)
+	if err != nil {
+		log.Printf("failed to dose %v ml, error: %v", targetMl, err)
+	}
+	log.Printf("dispensed %.1f ml", ml)
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"log"
-	"time"
+	"os"
+	"os/signal"
 
 	"github.com/shanghuiyang/rpi-devices/dev"
 )
@@ -20,13 +22,15 @@ func main() {
 	}
 	defer gps.Close()
 
-	for {
-		time.Sleep(1 * time.Second)
-		lat, lon, err := gps.Loc()
-		if err != nil {
-			log.Printf("failed, error: %v", err)
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	for fix := range gps.Subscribe(ctx) {
+		if !fix.Valid() {
+			log.Printf("no fix")
 			continue
 		}
-		log.Printf("%v, %v", lat, lon)
+		log.Printf("%v, %v, alt: %.1fm, speed: %.1fm/s, satellites: %v, hdop: %v, %v",
+			fix.Lat, fix.Lon, fix.Alt, fix.Speed, fix.Satellites, fix.HDOP, fix.Type)
 	}
 }
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/shanghuiyang/rpi-devices/dev"
+)
+
+const (
+	clkPin = 17
+	dtPin  = 27
+	swPin  = 22
+)
+
+func main() {
+	knob := dev.NewKY040(clkPin, dtPin, swPin)
+	defer knob.Close()
+
+	ctx := context.Background()
+	gestures := dev.WatchGestures(ctx, knob.Button, dev.DefaultGestureConfig)
+	turns := knob.Subscribe(ctx)
+	for {
+		select {
+		case ev := <-turns:
+			log.Printf("turned %+d, position: %v", ev.Delta, ev.Position)
+		case g := <-gestures:
+			if g.Gesture == dev.LongPress {
+				knob.Reset()
+				log.Printf("reset position")
+				continue
+			}
+			log.Printf("clicked, position: %v", knob.Position())
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/shanghuiyang/rpi-devices/dev"
+)
+
+const (
+	servoChannel = 0
+	ledChannel   = 15
+)
+
+func main() {
+	pca, err := dev.NewPCA9685(dev.PCA9685Addr, 50)
+	if err != nil {
+		log.Printf("failed to create pca9685, error: %v", err)
+		return
+	}
+	defer pca.Close()
+
+	servo, err := pca.Servo(servoChannel, dev.SG90Config)
+	if err != nil {
+		log.Printf("failed to create servo, error: %v", err)
+		return
+	}
+	led, err := pca.Led(ledChannel)
+	if err != nil {
+		log.Printf("failed to create led, error: %v", err)
+		return
+	}
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		led.On()
+		servo.Sweep(ctx, -90, 90, 2*time.Second)
+		led.Off()
+		servo.MoveTo(ctx, -90, 2*time.Second)
+	}
+	led.Fade(3)
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"log"
-	"time"
+	"os"
+	"os/signal"
 
 	"github.com/shanghuiyang/rpi-devices/dev"
 )
@@ -29,59 +31,22 @@ func main() {
 		led.Off()
 	}()
 
-	ledOn := false
-	chA := make(chan bool)
-	chB := make(chan bool)
-	chC := make(chan bool)
-	chD := make(chan bool)
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
 
-	go func(ch chan bool) {
-		for {
-			if r.Received(channelButonA) {
-				ch <- true
-				time.Sleep(1 * time.Second)
-				continue
-			}
-			time.Sleep(20 * time.Millisecond)
-		}
-	}(chA)
-
-	go func(ch chan bool) {
-		for {
-			if r.Received(channelButonB) {
-				ch <- true
-				time.Sleep(1 * time.Second)
-				continue
-			}
-			time.Sleep(20 * time.Millisecond)
-		}
-	}(chB)
-
-	go func(ch chan bool) {
-		for {
-			if r.Received(channelButonC) {
-				ch <- true
-				time.Sleep(1 * time.Second)
-				continue
-			}
-			time.Sleep(20 * time.Millisecond)
-		}
-	}(chC)
-
-	go func(ch chan bool) {
-		for {
-			if r.Received(channelButonD) {
-				ch <- true
-				time.Sleep(1 * time.Second)
-				continue
-			}
-			time.Sleep(20 * time.Millisecond)
-		}
-	}(chD)
+	chA := r.WatchChannel(ctx, channelButonA)
+	chB := r.WatchChannel(ctx, channelButonB)
+	chC := r.WatchChannel(ctx, channelButonC)
+	chD := r.WatchChannel(ctx, channelButonD)
 
+	ledOn := false
 	for {
 		select {
-		case <-chA:
+		case _, ok := <-chA:
+			if !ok {
+				chA = nil
+				continue
+			}
 			log.Printf("pressed A")
 			if ledOn {
 				led.Off()
@@ -90,14 +55,26 @@ func main() {
 				led.On()
 				ledOn = true
 			}
-		case <-chB:
+		case _, ok := <-chB:
+			if !ok {
+				chB = nil
+				continue
+			}
 			log.Printf("pressed B")
-		case <-chC:
+		case _, ok := <-chC:
+			if !ok {
+				chC = nil
+				continue
+			}
 			log.Printf("pressed C")
-		case <-chD:
+		case _, ok := <-chD:
+			if !ok {
+				chD = nil
+				continue
+			}
 			log.Printf("pressed D")
-		default:
-			time.Sleep(20 * time.Millisecond)
+		case <-ctx.Done():
+			return
 		}
 	}
 }
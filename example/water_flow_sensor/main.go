@@ -1,25 +1,41 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"log"
 	"time"
 
 	"github.com/shanghuiyang/rpi-devices/dev"
 )
 
-const pulsesPerLiter float32 = 450
+const (
+	pin            = 17
+	pulsesPerLiter = 450
+)
 
 func main() {
-	w := dev.NewWaterFlowMeter(17)
-	var numberOfPulsesCounted int = 0
+	w, err := dev.NewWaterFlowMeterWithConfig(dev.NewRpioPin(pin), dev.WaterFlowMeterConfig{
+		PulsesPerLiter: pulsesPerLiter,
+		TotalFile:      "water_total.txt",
+		LeakTime:       30 * time.Minute,
+	})
+	if err != nil {
+		log.Printf("failed to create water flow meter, error: %v", err)
+		return
+	}
+	defer w.Close()
 
+	leaks := w.WatchLeak(context.Background())
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
 	for {
-		if w.Detected() {
-			numberOfPulsesCounted++
-			fmt.Printf("MiliLiters Flowed: %f", (float32(1000*numberOfPulsesCounted) / pulsesPerLiter))
-			continue
+		select {
+		case ev := <-leaks:
+			if ev.Active {
+				log.Printf("water keeps flowing for 30 minutes, it may be leaking")
+			}
+		case <-ticker.C:
+			log.Printf("flow: %.2f L/min, total: %.3f L", w.Rate(), w.Total())
 		}
-
-		time.Sleep(100 * time.Millisecond)
 	}
 }
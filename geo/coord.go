@@ -0,0 +1,279 @@
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const dmsSeparators = " °'\"′″:"
+
+var (
+	errInvalidDMS  = errors.New("geo: invalid dms")
+	errInvalidNMEA = errors.New("geo: invalid nmea coordinate")
+	errInvalidUTM  = errors.New("geo: invalid utm")
+
+	dmsNumber = regexp.MustCompile(`\d+(\.\d+)?`)
+)
+
+// DMS is an angle in degrees, minutes and seconds
+type DMS struct {
+	Negative bool
+	Degrees  int
+	Minutes  int
+	Seconds  float64
+}
+
+// ToDMS converts decimal degrees to DMS
+func ToDMS(deg float64) DMS {
+	dms := DMS{Negative: deg < 0}
+	deg = math.Abs(deg)
+	// round to 1/10000 seconds first, so 59.99999 seconds carries to the next minute
+	total := math.Round(deg*3600*10000) / 10000
+	dms.Degrees = int(total / 3600)
+	total -= float64(dms.Degrees) * 3600
+	dms.Minutes = int(total / 60)
+	dms.Seconds = total - float64(dms.Minutes)*60
+	return dms
+}
+
+// Decimal converts DMS to decimal degrees
+func (d DMS) Decimal() float64 {
+	deg := float64(d.Degrees) + float64(d.Minutes)/60 + d.Seconds/3600
+	if d.Negative {
+		return -deg
+	}
+	return deg
+}
+
+// FormatDMS formats a point like 39°57'29.28"N 116°26'10.44"E
+func FormatDMS(p Point) string {
+	return formatDMS(p.Lat, "N", "S") + " " + formatDMS(p.Lon, "E", "W")
+}
+
+func formatDMS(deg float64, pos, neg string) string {
+	dms := ToDMS(deg)
+	h := pos
+	if dms.Negative {
+		h = neg
+	}
+	return fmt.Sprintf("%d°%d'%.2f\"%s", dms.Degrees, dms.Minutes, dms.Seconds, h)
+}
+
+// ParseDMS parses an angle like 39°57'29.28"N, 39 57 29.28 N, -39°57.488' or 39.958134 to decimal degrees.
+// S and W are negative.
+func ParseDMS(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	neg := false
+	if n := len(s); n > 0 {
+		switch strings.ToUpper(s[n-1:]) {
+		case "N", "E":
+			s = s[:n-1]
+		case "S", "W":
+			s, neg = s[:n-1], true
+		}
+	}
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "-") {
+		if neg {
+			return 0, errInvalidDMS
+		}
+		s, neg = s[1:], true
+	}
+
+	// only numbers and separators are allowed
+	if strings.Trim(dmsNumber.ReplaceAllString(s, ""), dmsSeparators) != "" {
+		return 0, errInvalidDMS
+	}
+	nums := dmsNumber.FindAllString(s, -1)
+	if len(nums) == 0 || len(nums) > 3 {
+		return 0, errInvalidDMS
+	}
+	var v [3]float64
+	for i, num := range nums {
+		v[i], _ = strconv.ParseFloat(num, 64)
+		// only the last number can have decimals
+		if i < len(nums)-1 && strings.Contains(num, ".") {
+			return 0, errInvalidDMS
+		}
+	}
+	if v[1] >= 60 || v[2] >= 60 {
+		return 0, errInvalidDMS
+	}
+	deg := v[0] + v[1]/60 + v[2]/3600
+	if neg {
+		deg = -deg
+	}
+	return deg, nil
+}
+
+// ToNMEALat converts latitude to NMEA ddmm.mmmm and the hemisphere N/S
+func ToNMEALat(lat float64) (string, string) {
+	return toNMEA(lat, 2, "N", "S")
+}
+
+// ToNMEALon converts longitude to NMEA dddmm.mmmm and the hemisphere E/W
+func ToNMEALon(lon float64) (string, string) {
+	return toNMEA(lon, 3, "E", "W")
+}
+
+func toNMEA(deg float64, width int, pos, neg string) (string, string) {
+	h := pos
+	if deg < 0 {
+		h, deg = neg, -deg
+	}
+	d := math.Floor(deg)
+	min := math.Round((deg-d)*60*10000) / 10000
+	if min >= 60 {
+		d, min = d+1, 0
+	}
+	return fmt.Sprintf("%0*d%07.4f", width, int(d), min), h
+}
+
+// FromNMEA converts NMEA ddmm.mmmm/dddmm.mmmm with the hemisphere N/S/E/W to decimal degrees
+func FromNMEA(s, hemisphere string) (float64, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, errInvalidNMEA
+	}
+	deg := math.Floor(v / 100)
+	min := v - deg*100
+	if min >= 60 {
+		return 0, errInvalidNMEA
+	}
+	deg += min / 60
+	switch hemisphere {
+	case "N", "E":
+	case "S", "W":
+		deg = -deg
+	default:
+		return 0, errInvalidNMEA
+	}
+	return deg, nil
+}
+
+// UTM is a coordinate in the universal transverse mercator system on WGS84
+type UTM struct {
+	Zone int
+	// Band is the latitude band letter C ~ X
+	Band     byte
+	North    bool
+	Easting  float64
+	Northing float64
+}
+
+// String formats UTM like 50S 453231.6 4423249.9
+func (u UTM) String() string {
+	return fmt.Sprintf("%d%c %.1f %.1f", u.Zone, u.Band, u.Easting, u.Northing)
+}
+
+const (
+	utmK0            = 0.9996
+	utmFalseEasting  = 500000.0
+	utmFalseNorthing = 10000000.0
+	utmBands         = "CDEFGHJKLMNPQRSTUVWXX"
+)
+
+// ToUTM converts a point to UTM, the latitude must be within -80 ~ 84
+func ToUTM(p Point) (UTM, error) {
+	if p.Lat < -80 || p.Lat > 84 || p.Lon < -180 || p.Lon > 180 {
+		return UTM{}, errInvalidUTM
+	}
+	zone := int(math.Floor((p.Lon+180)/6)) + 1
+	if zone > 60 {
+		zone = 60
+	}
+	// exceptions of Norway and Svalbard
+	switch {
+	case p.Lat >= 56 && p.Lat < 64 && p.Lon >= 3 && p.Lon < 12:
+		zone = 32
+	case p.Lat >= 72 && p.Lat <= 84 && p.Lon >= 0:
+		switch {
+		case p.Lon < 9:
+			zone = 31
+		case p.Lon < 21:
+			zone = 33
+		case p.Lon < 33:
+			zone = 35
+		case p.Lon < 42:
+			zone = 37
+		}
+	}
+	lon0 := radians(float64(zone-1)*6 - 180 + 3)
+
+	e2 := wgs84F * (2 - wgs84F)
+	ep2 := e2 / (1 - e2)
+	phi, lam := radians(p.Lat), radians(p.Lon)
+	sinPhi, cosPhi := math.Sincos(phi)
+	tanPhi := math.Tan(phi)
+	N := wgs84A / math.Sqrt(1-e2*sinPhi*sinPhi)
+	T := tanPhi * tanPhi
+	C := ep2 * cosPhi * cosPhi
+	A := cosPhi * (lam - lon0)
+	M := meridianArc(phi)
+
+	easting := utmK0*N*(A+(1-T+C)*A*A*A/6+(5-18*T+T*T+72*C-58*ep2)*A*A*A*A*A/120) + utmFalseEasting
+	northing := utmK0 * (M + N*tanPhi*(A*A/2+(5-T+9*C+4*C*C)*A*A*A*A/24+
+		(61-58*T+T*T+600*C-330*ep2)*A*A*A*A*A*A/720))
+	if p.Lat < 0 {
+		northing += utmFalseNorthing
+	}
+	return UTM{
+		Zone:     zone,
+		Band:     utmBands[int(math.Floor((p.Lat+80)/8))],
+		North:    p.Lat >= 0,
+		Easting:  easting,
+		Northing: northing,
+	}, nil
+}
+
+// FromUTM converts UTM to a point
+func FromUTM(u UTM) (Point, error) {
+	if u.Zone < 1 || u.Zone > 60 {
+		return Point{}, errInvalidUTM
+	}
+	e2 := wgs84F * (2 - wgs84F)
+	ep2 := e2 / (1 - e2)
+	e1 := (1 - math.Sqrt(1-e2)) / (1 + math.Sqrt(1-e2))
+	lon0 := radians(float64(u.Zone-1)*6 - 180 + 3)
+
+	x := u.Easting - utmFalseEasting
+	y := u.Northing
+	if !u.North {
+		y -= utmFalseNorthing
+	}
+	M := y / utmK0
+	mu := M / (wgs84A * (1 - e2/4 - 3*e2*e2/64 - 5*e2*e2*e2/256))
+	// footpoint latitude
+	phi1 := mu + (3*e1/2-27*e1*e1*e1/32)*math.Sin(2*mu) +
+		(21*e1*e1/16-55*e1*e1*e1*e1/32)*math.Sin(4*mu) +
+		(151*e1*e1*e1/96)*math.Sin(6*mu) +
+		(1097*e1*e1*e1*e1/512)*math.Sin(8*mu)
+
+	sinPhi1, cosPhi1 := math.Sincos(phi1)
+	tanPhi1 := math.Tan(phi1)
+	N1 := wgs84A / math.Sqrt(1-e2*sinPhi1*sinPhi1)
+	T1 := tanPhi1 * tanPhi1
+	C1 := ep2 * cosPhi1 * cosPhi1
+	R1 := wgs84A * (1 - e2) / math.Pow(1-e2*sinPhi1*sinPhi1, 1.5)
+	D := x / (N1 * utmK0)
+
+	phi := phi1 - (N1*tanPhi1/R1)*(D*D/2-(5+3*T1+10*C1-4*C1*C1-9*ep2)*D*D*D*D/24+
+		(61+90*T1+298*C1+45*T1*T1-252*ep2-3*C1*C1)*D*D*D*D*D*D/720)
+	lam := lon0 + (D-(1+2*T1+C1)*D*D*D/6+
+		(5-2*C1+28*T1-3*C1*C1+8*ep2+24*T1*T1)*D*D*D*D*D/120)/cosPhi1
+	return Point{Lat: degrees(phi), Lon: degrees(lam)}, nil
+}
+
+// meridianArc returns the length of the meridian from the equator to the latitude
+func meridianArc(phi float64) float64 {
+	e2 := wgs84F * (2 - wgs84F)
+	e4, e6 := e2*e2, e2*e2*e2
+	return wgs84A * ((1-e2/4-3*e4/64-5*e6/256)*phi -
+		(3*e2/8+3*e4/32+45*e6/1024)*math.Sin(2*phi) +
+		(15*e4/256+45*e6/1024)*math.Sin(4*phi) -
+		(35*e6/3072)*math.Sin(6*phi))
+}
@@ -0,0 +1,165 @@
+package geo
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// fenceEventBufSize is the buffer size of the channel returned by WatchFences
+	fenceEventBufSize = 16
+	// defaultFenceInterval is the interval of WatchFences if the given one isn't positive
+	defaultFenceInterval = time.Second
+)
+
+// Area is an area on the earth
+type Area interface {
+	Contains(p Point) bool
+}
+
+// Circle is the area within Radius meters from Center
+type Circle struct {
+	Center Point
+	Radius float64
+}
+
+// Contains ...
+func (c Circle) Contains(p Point) bool {
+	return Distance(c.Center, p) <= c.Radius
+}
+
+// Polygon is the area inside the vertexes, it is closed automatically.
+// Edges are treated as straight lines in lat/lon, which is fine for areas of a few kilometers,
+// and it must not cross the 180th meridian.
+type Polygon []Point
+
+// Contains ...
+func (poly Polygon) Contains(p Point) bool {
+	inside := false
+	n := len(poly)
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		a, b := poly[i], poly[j]
+		if (a.Lat > p.Lat) != (b.Lat > p.Lat) &&
+			p.Lon < (b.Lon-a.Lon)*(p.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// Fence is a named area
+type Fence struct {
+	Name string
+	Area Area
+}
+
+// FenceEventType ...
+type FenceEventType int
+
+// Fence event types
+const (
+	Enter FenceEventType = iota
+	Exit
+)
+
+// String ...
+func (t FenceEventType) String() string {
+	if t == Enter {
+		return "enter"
+	}
+	return "exit"
+}
+
+// FenceEvent is sent when a location enters or exits a fence
+type FenceEvent struct {
+	Fence string
+	Type  FenceEventType
+	Point Point
+	Time  time.Time
+}
+
+// FenceMonitor detects entering and exiting the fences from a sequence of locations
+type FenceMonitor struct {
+	fences []Fence
+	inside []bool
+}
+
+// NewFenceMonitor ...
+func NewFenceMonitor(fences ...Fence) *FenceMonitor {
+	return &FenceMonitor{
+		fences: fences,
+		inside: make([]bool, len(fences)),
+	}
+}
+
+// Update returns the events caused by the location p.
+// The first location inside a fence causes an Enter event.
+func (m *FenceMonitor) Update(p Point, t time.Time) []FenceEvent {
+	var events []FenceEvent
+	for i, f := range m.fences {
+		in := f.Area.Contains(p)
+		if in == m.inside[i] {
+			continue
+		}
+		m.inside[i] = in
+		typ := Exit
+		if in {
+			typ = Enter
+		}
+		events = append(events, FenceEvent{
+			Fence: f.Name,
+			Type:  typ,
+			Point: p,
+			Time:  t,
+		})
+	}
+	return events
+}
+
+// Inside returns the names of the fences the last location is in
+func (m *FenceMonitor) Inside() []string {
+	var names []string
+	for i, in := range m.inside {
+		if in {
+			names = append(names, m.fences[i].Name)
+		}
+	}
+	return names
+}
+
+// Locator is anything reporting its location, like dev.GPS
+type Locator interface {
+	Loc() (lat, lon float64, err error)
+}
+
+// WatchFences reads the location every interval (default 1s) and sends enter/exit events,
+// locations with errors are skipped. The channel is closed when ctx is done.
+func WatchFences(ctx context.Context, loc Locator, interval time.Duration, fences ...Fence) <-chan FenceEvent {
+	if interval <= 0 {
+		interval = defaultFenceInterval
+	}
+	ch := make(chan FenceEvent, fenceEventBufSize)
+	m := NewFenceMonitor(fences...)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if lat, lon, err := loc.Loc(); err == nil {
+				for _, ev := range m.Update(Point{Lat: lat, Lon: lon}, time.Now()) {
+					select {
+					case ch <- ev:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return ch
+}
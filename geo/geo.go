@@ -0,0 +1,163 @@
+/*
+Package geo provides geodesy utilities for GPS applications:
+  - distance: haversine on a sphere, and Vincenty on the WGS84 ellipsoid
+  - initial bearing, destination point and cross-track error
+  - circle and polygon geofences with enter/exit detection
+  - coordinate conversions between decimal degrees, DMS, NMEA ddmm.mmmm and UTM
+
+Usage:
+
+	home := geo.Point{Lat: 39.958134, Lon: 116.436234}
+	office := geo.Point{Lat: 39.982662, Lon: 116.320312}
+	d := geo.Distance(home, office)
+	b := geo.Bearing(home, office)
+*/
+package geo
+
+import (
+	"errors"
+	"math"
+)
+
+const (
+	// EarthRadius is the mean radius of the earth in meters
+	EarthRadius = 6371008.8
+
+	// WGS84 ellipsoid
+	wgs84A = 6378137.0
+	wgs84F = 1 / 298.257223563
+	wgs84B = wgs84A * (1 - wgs84F)
+
+	vincentyMaxIterations = 200
+	vincentyTolerance     = 1e-12
+)
+
+// ErrNotConverged is returned by VincentyDistance for nearly antipodal points
+var ErrNotConverged = errors.New("geo: vincenty formula failed to converge")
+
+// Point is a location in decimal degrees, negative for south and west
+type Point struct {
+	Lat float64
+	Lon float64
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
+
+// normalizeBearing returns the bearing in [0, 360)
+func normalizeBearing(deg float64) float64 {
+	deg = math.Mod(deg, 360)
+	if deg < 0 {
+		deg += 360
+	}
+	return deg
+}
+
+// Distance returns the great-circle distance between two points in meters using the haversine formula.
+// The error is up to 0.5% since it treats the earth as a sphere.
+func Distance(p1, p2 Point) float64 {
+	phi1, phi2 := radians(p1.Lat), radians(p2.Lat)
+	dphi := phi2 - phi1
+	dlam := radians(p2.Lon - p1.Lon)
+	a := math.Sin(dphi/2)*math.Sin(dphi/2) + math.Cos(phi1)*math.Cos(phi2)*math.Sin(dlam/2)*math.Sin(dlam/2)
+	return 2 * EarthRadius * math.Asin(math.Sqrt(math.Min(1, a)))
+}
+
+// VincentyDistance returns the distance between two points on the WGS84 ellipsoid in meters,
+// it is accurate to within 0.5mm. It returns ErrNotConverged for nearly antipodal points.
+func VincentyDistance(p1, p2 Point) (float64, error) {
+	L := radians(p2.Lon - p1.Lon)
+	U1 := math.Atan((1 - wgs84F) * math.Tan(radians(p1.Lat)))
+	U2 := math.Atan((1 - wgs84F) * math.Tan(radians(p2.Lat)))
+	sinU1, cosU1 := math.Sincos(U1)
+	sinU2, cosU2 := math.Sincos(U2)
+
+	lambda := L
+	var sinSigma, cosSigma, sigma, cosSqAlpha, cos2SigmaM float64
+	converged := false
+	for i := 0; i < vincentyMaxIterations; i++ {
+		sinLambda, cosLambda := math.Sincos(lambda)
+		sinSigma = math.Sqrt((cosU2*sinLambda)*(cosU2*sinLambda) +
+			(cosU1*sinU2-sinU1*cosU2*cosLambda)*(cosU1*sinU2-sinU1*cosU2*cosLambda))
+		if sinSigma == 0 {
+			// coincident points
+			return 0, nil
+		}
+		cosSigma = sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma = math.Atan2(sinSigma, cosSigma)
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cosSqAlpha = 1 - sinAlpha*sinAlpha
+		cos2SigmaM = 0
+		if cosSqAlpha != 0 {
+			// not on the equator
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cosSqAlpha
+		}
+		C := wgs84F / 16 * cosSqAlpha * (4 + wgs84F*(4-3*cosSqAlpha))
+		prev := lambda
+		lambda = L + (1-C)*wgs84F*sinAlpha*
+			(sigma+C*sinSigma*(cos2SigmaM+C*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+		if math.Abs(lambda-prev) < vincentyTolerance {
+			converged = true
+			break
+		}
+	}
+	if !converged {
+		return 0, ErrNotConverged
+	}
+
+	uSq := cosSqAlpha * (wgs84A*wgs84A - wgs84B*wgs84B) / (wgs84B * wgs84B)
+	A := 1 + uSq/16384*(4096+uSq*(-768+uSq*(320-175*uSq)))
+	B := uSq / 1024 * (256 + uSq*(-128+uSq*(74-47*uSq)))
+	deltaSigma := B * sinSigma * (cos2SigmaM + B/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+		B/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+	return wgs84B * A * (sigma - deltaSigma), nil
+}
+
+// Bearing returns the initial bearing from p1 to p2 in degrees from true north, 0 ~ 360
+func Bearing(p1, p2 Point) float64 {
+	phi1, phi2 := radians(p1.Lat), radians(p2.Lat)
+	dlam := radians(p2.Lon - p1.Lon)
+	y := math.Sin(dlam) * math.Cos(phi2)
+	x := math.Cos(phi1)*math.Sin(phi2) - math.Sin(phi1)*math.Cos(phi2)*math.Cos(dlam)
+	return normalizeBearing(degrees(math.Atan2(y, x)))
+}
+
+// Destination returns the point at the distance in meters from p along the initial bearing in degrees
+func Destination(p Point, bearing, dist float64) Point {
+	phi1, lam1 := radians(p.Lat), radians(p.Lon)
+	theta := radians(bearing)
+	delta := dist / EarthRadius
+	phi2 := math.Asin(math.Sin(phi1)*math.Cos(delta) + math.Cos(phi1)*math.Sin(delta)*math.Cos(theta))
+	lam2 := lam1 + math.Atan2(math.Sin(theta)*math.Sin(delta)*math.Cos(phi1), math.Cos(delta)-math.Sin(phi1)*math.Sin(phi2))
+	return Point{
+		Lat: degrees(phi2),
+		Lon: math.Mod(degrees(lam2)+540, 360) - 180,
+	}
+}
+
+// CrossTrack returns the distance in meters from p to the great circle path from start to end.
+// It is positive if p is on the right of the path, and negative on the left.
+func CrossTrack(p, start, end Point) float64 {
+	d13 := Distance(start, p) / EarthRadius
+	theta13 := radians(Bearing(start, p))
+	theta12 := radians(Bearing(start, end))
+	return math.Asin(math.Sin(d13)*math.Sin(theta13-theta12)) * EarthRadius
+}
+
+// AlongTrack returns the distance in meters from start to the closest point on the path to p
+func AlongTrack(p, start, end Point) float64 {
+	d13 := Distance(start, p) / EarthRadius
+	dxt := CrossTrack(p, start, end) / EarthRadius
+	theta13 := radians(Bearing(start, p))
+	theta12 := radians(Bearing(start, end))
+	dat := math.Acos(math.Max(-1, math.Min(1, math.Cos(d13)/math.Cos(dxt))))
+	if math.Cos(theta12-theta13) < 0 {
+		dat = -dat
+	}
+	return dat * EarthRadius
+}
@@ -0,0 +1,238 @@
+package geo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Distance(t *testing.T) {
+	london := Point{Lat: 51.5007, Lon: -0.1246}
+	newYork := Point{Lat: 40.6892, Lon: -74.0445}
+	assert.InDelta(t, 5574840, Distance(london, newYork), 1000)
+	assert.Equal(t, 0.0, Distance(london, london))
+
+	// the classic example of Vincenty
+	flindersPeak := Point{Lat: -37.95103341666667, Lon: 144.42486788888888}
+	buninyong := Point{Lat: -37.65282113888889, Lon: 143.92649552777777}
+	d, err := VincentyDistance(flindersPeak, buninyong)
+	assert.NoError(t, err)
+	assert.InDelta(t, 54972.271, d, 0.001)
+
+	d, err = VincentyDistance(london, london)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, d)
+
+	_, err = VincentyDistance(Point{Lat: 0, Lon: 0}, Point{Lat: 0.5, Lon: 179.7})
+	assert.ErrorIs(t, err, ErrNotConverged)
+}
+
+func Test_BearingAndDestination(t *testing.T) {
+	origin := Point{}
+	assert.InDelta(t, 90, Bearing(origin, Point{Lat: 0, Lon: 1}), 1e-9)
+	assert.InDelta(t, 0, Bearing(origin, Point{Lat: 1, Lon: 0}), 1e-9)
+	assert.InDelta(t, 270, Bearing(origin, Point{Lat: 0, Lon: -1}), 1e-9)
+
+	p := Point{Lat: 39.958134, Lon: 116.436234}
+	for _, b := range []float64{0, 45, 135, 270} {
+		dest := Destination(p, b, 1000)
+		assert.InDelta(t, 1000, Distance(p, dest), 1e-6)
+		assert.InDelta(t, b, Bearing(p, dest), 1e-6)
+	}
+	dest := Destination(Point{Lat: 0, Lon: 179.9}, 90, 50000)
+	assert.Less(t, dest.Lon, -179.0)
+}
+
+func Test_CrossTrack(t *testing.T) {
+	start, end := Point{Lat: 0, Lon: 0}, Point{Lat: 0, Lon: 10}
+	oneDegree := EarthRadius * 3.141592653589793 / 180
+	assert.InDelta(t, -oneDegree, CrossTrack(Point{Lat: 1, Lon: 5}, start, end), 1)
+	assert.InDelta(t, oneDegree, CrossTrack(Point{Lat: -1, Lon: 5}, start, end), 1)
+	assert.InDelta(t, 5*oneDegree, AlongTrack(Point{Lat: 1, Lon: 5}, start, end), 100)
+	assert.Less(t, AlongTrack(Point{Lat: 0, Lon: -1}, start, end), 0.0)
+}
+
+func Test_DMS(t *testing.T) {
+	dms := ToDMS(-116.436234)
+	assert.Equal(t, DMS{Negative: true, Degrees: 116, Minutes: 26, Seconds: 10.4424}, roundDMS(dms))
+	assert.InDelta(t, -116.436234, dms.Decimal(), 1e-9)
+	assert.Equal(t, DMS{Degrees: 1}, roundDMS(ToDMS(0.99999999999)))
+
+	assert.Equal(t, `39°57'29.28"N 116°26'10.44"W`, FormatDMS(Point{Lat: 39.958134, Lon: -116.436234}))
+
+	for s, want := range map[string]float64{
+		`39°57'29.28"N`:   39.958133,
+		`39 57 29.28 S`:   -39.958133,
+		`-39°57.488'`:     -39.958133,
+		`116°26'10.44" E`: 116.436233,
+		`39.958134`:       39.958134,
+		`116.436234W`:     -116.436234,
+	} {
+		got, err := ParseDMS(s)
+		assert.NoError(t, err, s)
+		assert.InDelta(t, want, got, 1e-6, s)
+	}
+	for _, s := range []string{"", "abc", `39°60'N`, `-39°57'29"S`, `39.5°57'`, "1 2 3 4"} {
+		_, err := ParseDMS(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func roundDMS(d DMS) DMS {
+	d.Seconds = float64(int(d.Seconds*10000+0.5)) / 10000
+	return d
+}
+
+func Test_NMEA(t *testing.T) {
+	lat, ns := ToNMEALat(39.958134)
+	assert.Equal(t, "3957.4880", lat)
+	assert.Equal(t, "N", ns)
+	lon, ew := ToNMEALon(-6.50562)
+	assert.Equal(t, "00630.3372", lon)
+	assert.Equal(t, "W", ew)
+
+	v, err := FromNMEA("11626.17404", "E")
+	assert.NoError(t, err)
+	assert.InDelta(t, 116.436234, v, 1e-6)
+	v, err = FromNMEA("5321.6802", "S")
+	assert.NoError(t, err)
+	assert.InDelta(t, -53.361337, v, 1e-6)
+
+	_, err = FromNMEA("5361.6802", "N")
+	assert.Error(t, err)
+	_, err = FromNMEA("5321.6802", "X")
+	assert.Error(t, err)
+}
+
+func Test_UTM(t *testing.T) {
+	eiffelTower := Point{Lat: 48.85826, Lon: 2.29450}
+	u, err := ToUTM(eiffelTower)
+	assert.NoError(t, err)
+	assert.Equal(t, 31, u.Zone)
+	assert.Equal(t, byte('U'), u.Band)
+	assert.True(t, u.North)
+	assert.InDelta(t, 448251.8, u.Easting, 1)
+	assert.InDelta(t, 5411939.3, u.Northing, 1)
+	assert.Equal(t, "31U 448251.9 5411939.3", u.String())
+
+	u, err = ToUTM(Point{Lat: 39.958134, Lon: 116.436234})
+	assert.NoError(t, err)
+	assert.Equal(t, "50S 451847.6 4423262.7", u.String())
+
+	for _, p := range []Point{
+		{Lat: 39.958134, Lon: 116.436234},
+		{Lat: -33.856784, Lon: 151.215297},
+		{Lat: 60.5, Lon: 5.3},
+		{Lat: 78.2, Lon: 15.6},
+		{Lat: -0.0001, Lon: -179.9999},
+	} {
+		u, err := ToUTM(p)
+		assert.NoError(t, err)
+		got, err := FromUTM(u)
+		assert.NoError(t, err)
+		assert.InDelta(t, 0, Distance(p, got), 0.01, u.String())
+	}
+	u, _ = ToUTM(Point{Lat: 60.5, Lon: 5.3})
+	assert.Equal(t, 32, u.Zone)
+
+	_, err = ToUTM(Point{Lat: 85, Lon: 0})
+	assert.Error(t, err)
+	_, err = FromUTM(UTM{Zone: 61})
+	assert.Error(t, err)
+}
+
+func Test_Fences(t *testing.T) {
+	home := Circle{Center: Point{Lat: 39.958134, Lon: 116.436234}, Radius: 100}
+	assert.True(t, home.Contains(Destination(home.Center, 30, 99)))
+	assert.False(t, home.Contains(Destination(home.Center, 30, 101)))
+
+	park := Polygon{
+		{Lat: 39.96, Lon: 116.43},
+		{Lat: 39.96, Lon: 116.44},
+		{Lat: 39.97, Lon: 116.44},
+		{Lat: 39.965, Lon: 116.435}, // concave
+		{Lat: 39.97, Lon: 116.43},
+	}
+	assert.True(t, park.Contains(Point{Lat: 39.962, Lon: 116.435}))
+	assert.False(t, park.Contains(Point{Lat: 39.969, Lon: 116.435}))
+	assert.False(t, park.Contains(Point{Lat: 39.95, Lon: 116.435}))
+
+	m := NewFenceMonitor(Fence{Name: "home", Area: home}, Fence{Name: "park", Area: park})
+	now := time.Now()
+	evs := m.Update(home.Center, now)
+	assert.Equal(t, []FenceEvent{{Fence: "home", Type: Enter, Point: home.Center, Time: now}}, evs)
+	assert.Empty(t, m.Update(home.Center, now))
+	assert.Equal(t, []string{"home"}, m.Inside())
+
+	p := Point{Lat: 39.962, Lon: 116.435}
+	evs = m.Update(p, now)
+	assert.Len(t, evs, 2)
+	assert.Equal(t, "home", evs[0].Fence)
+	assert.Equal(t, Exit, evs[0].Type)
+	assert.Equal(t, "park", evs[1].Fence)
+	assert.Equal(t, Enter, evs[1].Type)
+	assert.Equal(t, "exit", Exit.String())
+}
+
+type fakeLocator struct {
+	points []Point
+	i      int
+}
+
+func (l *fakeLocator) Loc() (lat, lon float64, err error) {
+	if l.i >= len(l.points) {
+		return 0, 0, errors.New("no fix")
+	}
+	p := l.points[l.i]
+	l.i++
+	return p.Lat, p.Lon, nil
+}
+
+func Test_WatchFences(t *testing.T) {
+	home := Circle{Center: Point{Lat: 39.958134, Lon: 116.436234}, Radius: 100}
+	loc := &fakeLocator{points: []Point{
+		Destination(home.Center, 0, 300),
+		Destination(home.Center, 0, 50),
+		home.Center,
+		Destination(home.Center, 180, 200),
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	events := WatchFences(ctx, loc, time.Millisecond, Fence{Name: "home", Area: home})
+
+	ev := <-events
+	assert.Equal(t, Enter, ev.Type)
+	assert.Equal(t, loc.points[1], ev.Point)
+	ev = <-events
+	assert.Equal(t, Exit, ev.Type)
+	assert.Equal(t, loc.points[3], ev.Point)
+
+	cancel()
+	for range events {
+	}
+}
+
+func Test_WatchFencesDefaultInterval(t *testing.T) {
+	home := Circle{Center: Point{Lat: 39.958134, Lon: 116.436234}, Radius: 100}
+	loc := &fakeLocator{points: []Point{
+		home.Center,
+		Destination(home.Center, 0, 300),
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	events := WatchFences(ctx, loc, 0, Fence{Name: "home", Area: home})
+
+	ev := <-events
+	assert.Equal(t, Enter, ev.Type)
+	// the next location is read after the default interval of 1s
+	select {
+	case ev := <-events:
+		t.Fatalf("unexpected event %v", ev.Type)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	cancel()
+	for range events {
+	}
+}
@@ -0,0 +1,220 @@
+package nmea
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Time is the UTC time of day in sentences, hhmmss.sss
+type Time struct {
+	Valid       bool
+	Hour        int
+	Minute      int
+	Second      int
+	Millisecond int
+}
+
+// Date is the UTC date in sentences, ddmmyy
+type Date struct {
+	Valid bool
+	Day   int
+	Month int
+	Year  int
+}
+
+// String ...
+func (t Time) String() string {
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", t.Hour, t.Minute, t.Second, t.Millisecond)
+}
+
+// String ...
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+// DateTime combines the date and time into a UTC time.
+// It returns a zero time if either of them is invalid.
+func DateTime(d Date, t Time) time.Time {
+	if !d.Valid || !t.Valid {
+		return time.Time{}
+	}
+	return time.Date(d.Year, time.Month(d.Month), d.Day, t.Hour, t.Minute, t.Second, t.Millisecond*int(time.Millisecond), time.UTC)
+}
+
+// fieldParser parses the fields of a sentence and keeps the first error.
+// Empty fields are allowed and parsed as zero values since modules leave them blank without a fix.
+type fieldParser struct {
+	b   BaseSentence
+	err error
+}
+
+func (p *fieldParser) field(i int) string {
+	if i >= len(p.b.Fields) {
+		return ""
+	}
+	return p.b.Fields[i]
+}
+
+func (p *fieldParser) fail(i int, what string) {
+	if p.err == nil {
+		p.err = fmt.Errorf("nmea: %v: invalid %v %q at field %v", p.b.Prefix(), what, p.field(i), i+1)
+	}
+}
+
+func (p *fieldParser) requireFields(n int) {
+	if len(p.b.Fields) < n && p.err == nil {
+		p.err = fmt.Errorf("nmea: %v: expected at least %v fields but got %v", p.b.Prefix(), n, len(p.b.Fields))
+	}
+}
+
+func (p *fieldParser) string(i int) string {
+	return p.field(i)
+}
+
+// parseFloat is strconv.ParseFloat without NaN and Inf
+func parseFloat(s string) (float64, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return v, nil
+}
+
+func (p *fieldParser) float(i int) float64 {
+	s := p.field(i)
+	if s == "" {
+		return 0
+	}
+	v, err := parseFloat(s)
+	if err != nil {
+		p.fail(i, "number")
+		return 0
+	}
+	return v
+}
+
+func (p *fieldParser) int(i int) int {
+	s := p.field(i)
+	if s == "" {
+		return 0
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		p.fail(i, "integer")
+		return 0
+	}
+	return v
+}
+
+// latlon parses ddmm.mmmm/dddmm.mmmm in field i with the hemisphere N/S/E/W in field i+1
+func (p *fieldParser) latlon(i int, pos, neg string, limit float64) float64 {
+	s, h := p.field(i), p.field(i+1)
+	if s == "" {
+		return 0
+	}
+	v, err := parseFloat(s)
+	if err != nil || v < 0 {
+		p.fail(i, "coordinate")
+		return 0
+	}
+	deg := float64(int(v / 100))
+	min := v - deg*100
+	if min >= 60 {
+		p.fail(i, "coordinate")
+		return 0
+	}
+	v = deg + min/60
+	if v > limit {
+		p.fail(i, "coordinate")
+		return 0
+	}
+	switch h {
+	case pos:
+	case neg:
+		v = -v
+	default:
+		p.fail(i+1, "hemisphere")
+		return 0
+	}
+	return v
+}
+
+func (p *fieldParser) lat(i int) float64 {
+	return p.latlon(i, "N", "S", 90)
+}
+
+func (p *fieldParser) lon(i int) float64 {
+	return p.latlon(i, "E", "W", 180)
+}
+
+func (p *fieldParser) time(i int) Time {
+	s := p.field(i)
+	if s == "" {
+		return Time{}
+	}
+	if len(s) < 6 {
+		p.fail(i, "time")
+		return Time{}
+	}
+	h, err1 := strconv.Atoi(s[0:2])
+	m, err2 := strconv.Atoi(s[2:4])
+	sec, err3 := parseFloat(s[4:])
+	if err1 != nil || err2 != nil || err3 != nil || h > 23 || m > 59 || sec < 0 || sec >= 61 {
+		p.fail(i, "time")
+		return Time{}
+	}
+	ms := int((sec-float64(int(sec)))*1000 + 0.5)
+	if ms > 999 {
+		ms = 999
+	}
+	return Time{
+		Valid:       true,
+		Hour:        h,
+		Minute:      m,
+		Second:      int(sec),
+		Millisecond: ms,
+	}
+}
+
+func (p *fieldParser) date(i int) Date {
+	s := p.field(i)
+	if s == "" {
+		return Date{}
+	}
+	if len(s) != 6 {
+		p.fail(i, "date")
+		return Date{}
+	}
+	d, err1 := strconv.Atoi(s[0:2])
+	m, err2 := strconv.Atoi(s[2:4])
+	y, err3 := strconv.Atoi(s[4:6])
+	if err1 != nil || err2 != nil || err3 != nil || d < 1 || d > 31 || m < 1 || m > 12 {
+		p.fail(i, "date")
+		return Date{}
+	}
+	return Date{
+		Valid: true,
+		Day:   d,
+		Month: m,
+		Year:  2000 + y,
+	}
+}
+
+// enum parses a single character field which must be one of the options, or empty
+func (p *fieldParser) enum(i int, options string) string {
+	s := p.field(i)
+	if s == "" {
+		return ""
+	}
+	if len(s) != 1 || !strings.Contains(options, s) {
+		p.fail(i, "value")
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,187 @@
+/*
+Package nmea parses NMEA 0183 sentences output by GPS modules like NEO-6M and HT1818.
+
+Supported sentences:
+  - RMC: recommended minimum specific GNSS data
+  - GGA: global positioning system fix data
+  - GSA: GNSS DOP and active satellites
+  - GSV: GNSS satellites in view
+  - VTG: course over ground and ground speed
+  - GLL: geographic position, latitude/longitude
+  - ZDA: time and date
+
+Supported talkers: GP (GPS), GN (GNSS), BD/GB (Beidou), GL (GLONASS), GA (Galileo).
+
+Usage:
+
+	s, err := nmea.Parse("$GNRMC,083245.00,A,3957.48804,N,11626.17404,E,0.1,,041119,,,A*67")
+	if err != nil {
+		...
+	}
+	if rmc, ok := s.(*nmea.RMC); ok {
+		fmt.Println(rmc.Lat, rmc.Lon)
+	}
+*/
+package nmea
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	sentenceStart   = '$'
+	checksumSep     = '*'
+	fieldSep        = ","
+	maxSentenceSize = 120 // 82 by the spec, but some modules output longer sentences
+)
+
+// Talkers
+const (
+	TalkerGPS     = "GP"
+	TalkerGNSS    = "GN"
+	TalkerBeidou  = "BD"
+	TalkerBeidou2 = "GB"
+	TalkerGLONASS = "GL"
+	TalkerGalileo = "GA"
+)
+
+// Sentence types
+const (
+	TypeRMC = "RMC"
+	TypeGGA = "GGA"
+	TypeGSA = "GSA"
+	TypeGSV = "GSV"
+	TypeVTG = "VTG"
+	TypeGLL = "GLL"
+	TypeZDA = "ZDA"
+)
+
+var (
+	// ErrChecksum is returned if the checksum of the sentence mismatches
+	ErrChecksum = errors.New("nmea: checksum mismatch")
+	// ErrUnsupported is returned if the sentence type isn't supported
+	ErrUnsupported = errors.New("nmea: unsupported sentence")
+)
+
+var talkers = map[string]bool{
+	TalkerGPS:     true,
+	TalkerGNSS:    true,
+	TalkerBeidou:  true,
+	TalkerBeidou2: true,
+	TalkerGLONASS: true,
+	TalkerGalileo: true,
+}
+
+var parsers = map[string]func(b BaseSentence) (Sentence, error){
+	TypeRMC: parseRMC,
+	TypeGGA: parseGGA,
+	TypeGSA: parseGSA,
+	TypeGSV: parseGSV,
+	TypeVTG: parseVTG,
+	TypeGLL: parseGLL,
+	TypeZDA: parseZDA,
+}
+
+// Sentence is a parsed NMEA sentence
+type Sentence interface {
+	// Prefix returns the talker and type, e.g. GNRMC
+	Prefix() string
+	// DataType returns the type, e.g. RMC
+	DataType() string
+}
+
+// BaseSentence is the common part of all sentences
+type BaseSentence struct {
+	// Talker is the talker id, e.g. GP
+	Talker string
+	// Type is the sentence type, e.g. RMC
+	Type string
+	// Fields are the data fields between the prefix and checksum
+	Fields []string
+	// Raw is the raw sentence
+	Raw string
+}
+
+// Prefix ...
+func (b BaseSentence) Prefix() string {
+	return b.Talker + b.Type
+}
+
+// DataType ...
+func (b BaseSentence) DataType() string {
+	return b.Type
+}
+
+// Parse validates the checksum of the sentence and parses it.
+// It returns ErrUnsupported with the BaseSentence for unsupported sentence types.
+func Parse(raw string) (Sentence, error) {
+	b, err := ParseBase(raw)
+	if err != nil {
+		return nil, err
+	}
+	parse, ok := parsers[b.Type]
+	if !ok {
+		return b, ErrUnsupported
+	}
+	return parse(b)
+}
+
+// ParseBase validates the checksum of the sentence, and splits it into fields without parsing them
+func ParseBase(raw string) (BaseSentence, error) {
+	raw = strings.TrimSpace(raw)
+	if len(raw) > maxSentenceSize {
+		return BaseSentence{}, fmt.Errorf("nmea: sentence is too long: %v bytes", len(raw))
+	}
+	start := strings.IndexByte(raw, sentenceStart)
+	if start < 0 {
+		return BaseSentence{}, errors.New("nmea: sentence doesn't start with '$'")
+	}
+	raw = raw[start:]
+
+	sep := strings.LastIndexByte(raw, checksumSep)
+	if sep < 0 {
+		return BaseSentence{}, errors.New("nmea: missing checksum")
+	}
+	data, sum := raw[1:sep], raw[sep+1:]
+	want, err := strconv.ParseUint(sum, 16, 8)
+	if err != nil || len(sum) != 2 {
+		return BaseSentence{}, fmt.Errorf("nmea: invalid checksum %q", sum)
+	}
+	if Checksum(data) != byte(want) {
+		return BaseSentence{}, ErrChecksum
+	}
+
+	fields := strings.Split(data, fieldSep)
+	prefix := fields[0]
+	if len(prefix) != 5 {
+		return BaseSentence{}, fmt.Errorf("nmea: invalid prefix %q", prefix)
+	}
+	talker, typ := prefix[:2], prefix[2:]
+	if !talkers[talker] {
+		return BaseSentence{}, fmt.Errorf("nmea: unsupported talker %q", talker)
+	}
+	return BaseSentence{
+		Talker: talker,
+		Type:   typ,
+		Fields: fields[1:],
+		Raw:    raw,
+	}, nil
+}
+
+// Checksum calculates the checksum of data between '$' and '*'
+func Checksum(data string) byte {
+	var sum byte
+	for i := 0; i < len(data); i++ {
+		sum ^= data[i]
+	}
+	return sum
+}
+
+// Sentencef builds a sentence with checksum from the fields, e.g. Sentencef("GPGLL", "3953.88", "N", ...)
+func Sentencef(prefix string, fields ...string) string {
+	data := strings.Join(append([]string{prefix}, fields...), fieldSep)
+	return fmt.Sprintf("%c%s%c%02X", sentenceStart, data, checksumSep, Checksum(data))
+}
@@ -0,0 +1,242 @@
+package nmea
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sampleCSV = "../dev/test/gps.csv"
+
+var samples = []string{
+	"$GNRMC,083245.00,A,3957.48804,N,11626.17404,E,0.012,,041119,,,A*65",
+	"$GPGGA,092750.000,5321.6802,N,00630.3372,W,1,8,1.03,61.7,M,55.2,M,,*76",
+	"$GPGSA,A,3,10,07,05,02,29,04,08,13,,,,,1.72,1.03,1.38*0A",
+	"$GPGSV,3,1,11,10,63,137,17,07,61,098,15,05,59,290,20,08,54,157,30*70",
+	"$GPVTG,77.52,T,,M,0.004,N,0.008,K,A*06",
+	"$GPGLL,3953.88008971,N,10506.75318910,W,034138.00,A,D*7A",
+	"$GPZDA,172809.456,12,07,1996,00,00*57",
+}
+
+type csvPoint struct {
+	t   time.Time
+	lat float64
+	lon float64
+}
+
+func loadCSV(t testing.TB) []csvPoint {
+	f, err := os.Open(sampleCSV)
+	if err != nil {
+		t.Fatalf("failed to open %v: %v", sampleCSV, err)
+	}
+	defer f.Close()
+
+	var points []csvPoint
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		items := strings.Split(strings.TrimSpace(scanner.Text()), ",")
+		if len(items) != 3 {
+			continue
+		}
+		tm, err1 := time.Parse("2006-01-02T15:04:05", items[0])
+		lat, err2 := strconv.ParseFloat(items[1], 64)
+		lon, err3 := strconv.ParseFloat(items[2], 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+		points = append(points, csvPoint{tm, lat, lon})
+	}
+	return points
+}
+
+// toNMEA formats degrees to ddmm.mmmmm
+func toNMEA(deg float64, width int, pos, neg string) (string, string) {
+	h := pos
+	if deg < 0 {
+		h, deg = neg, -deg
+	}
+	d := math.Floor(deg)
+	return fmt.Sprintf("%0*.0f%08.5f", width, d, (deg-d)*60), h
+}
+
+func pointToRMC(p csvPoint) string {
+	lat, ns := toNMEA(p.lat, 2, "N", "S")
+	lon, ew := toNMEA(p.lon, 3, "E", "W")
+	return Sentencef("GNRMC", p.t.Format("150405.00"), "A", lat, ns, lon, ew, "0.012", "", p.t.Format("020106"), "", "", "A")
+}
+
+func pointToGGA(p csvPoint) string {
+	lat, ns := toNMEA(p.lat, 2, "N", "S")
+	lon, ew := toNMEA(p.lon, 3, "E", "W")
+	return Sentencef("GPGGA", p.t.Format("150405.00"), lat, ns, lon, ew, "1", "08", "1.01", "52.3", "M", "-8.1", "M", "", "")
+}
+
+func Test_Checksum(t *testing.T) {
+	_, err := Parse("$GPVTG,77.52,T,,M,0.004,N,0.008,K,A*07")
+	assert.ErrorIs(t, err, ErrChecksum)
+
+	_, err = Parse("$GPVTG,77.52,T,,M,0.004,N,0.008,K,A")
+	assert.Error(t, err)
+
+	_, err = Parse("$GPVTG,77.52,T,,M,0.004,N,0.008,K,A*0")
+	assert.Error(t, err)
+
+	s, err := Parse("garbage$GPVTG,77.52,T,,M,0.004,N,0.008,K,A*06\r\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "GPVTG", s.Prefix())
+
+	_, err = Parse(Sentencef("GPTXT", "01", "01", "02", "ANTSTATUS=OK"))
+	assert.ErrorIs(t, err, ErrUnsupported)
+
+	_, err = Parse(Sentencef("XXRMC", "083245.00", "A"))
+	assert.Error(t, err)
+
+	_, err = Parse(Sentencef("GPGLL", "NaN", "N", "10506.75318910", "W", "034138.00", "A", "D"))
+	assert.Error(t, err)
+}
+
+func Test_ParseRMC(t *testing.T) {
+	s, err := Parse(samples[0])
+	assert.NoError(t, err)
+	rmc, ok := s.(*RMC)
+	assert.True(t, ok)
+	assert.Equal(t, TalkerGNSS, rmc.Talker)
+	assert.True(t, rmc.Valid())
+	assert.InDelta(t, 39.958134, rmc.Lat, 1e-6)
+	assert.InDelta(t, 116.436234, rmc.Lon, 1e-6)
+	assert.InDelta(t, 0.012, rmc.Speed, 1e-9)
+	assert.Equal(t, time.Date(2019, 11, 4, 8, 32, 45, 0, time.UTC), DateTime(rmc.Date, rmc.Time))
+
+	s, err = Parse(Sentencef("BDRMC", "", "V", "", "", "", "", "", "", "", "", "", "N"))
+	assert.NoError(t, err)
+	rmc = s.(*RMC)
+	assert.Equal(t, TalkerBeidou, rmc.Talker)
+	assert.False(t, rmc.Valid())
+	assert.True(t, DateTime(rmc.Date, rmc.Time).IsZero())
+
+	_, err = Parse(Sentencef("GPRMC", "083245.00", "A", "3957.48804", "X", "11626.17404", "E", "", "", "041119", "", ""))
+	assert.Error(t, err)
+}
+
+func Test_ParseGGA(t *testing.T) {
+	s, err := Parse(samples[1])
+	assert.NoError(t, err)
+	gga := s.(*GGA)
+	assert.True(t, gga.Valid())
+	assert.InDelta(t, 53.361337, gga.Lat, 1e-6)
+	assert.InDelta(t, -6.50562, gga.Lon, 1e-6)
+	assert.Equal(t, FixGPS, gga.FixQuality)
+	assert.Equal(t, 8, gga.NumSatellites)
+	assert.Equal(t, 1.03, gga.HDOP)
+	assert.Equal(t, 61.7, gga.Altitude)
+	assert.Equal(t, 55.2, gga.Separation)
+	assert.Equal(t, Time{Valid: true, Hour: 9, Minute: 27, Second: 50}, gga.Time)
+}
+
+func Test_ParseGSA(t *testing.T) {
+	s, err := Parse(samples[2])
+	assert.NoError(t, err)
+	gsa := s.(*GSA)
+	assert.Equal(t, "A", gsa.Mode)
+	assert.Equal(t, FixType3D, gsa.FixType)
+	assert.Equal(t, []int{10, 7, 5, 2, 29, 4, 8, 13}, gsa.SVs)
+	assert.Equal(t, 1.72, gsa.PDOP)
+	assert.Equal(t, 1.03, gsa.HDOP)
+	assert.Equal(t, 1.38, gsa.VDOP)
+}
+
+func Test_ParseGSV(t *testing.T) {
+	s, err := Parse(samples[3])
+	assert.NoError(t, err)
+	gsv := s.(*GSV)
+	assert.Equal(t, 3, gsv.TotalMessages)
+	assert.Equal(t, 1, gsv.MessageNumber)
+	assert.Equal(t, 11, gsv.NumInView)
+	assert.Len(t, gsv.Info, 4)
+	assert.Equal(t, GSVInfo{PRN: 8, Elevation: 54, Azimuth: 157, SNR: 30}, gsv.Info[3])
+
+	s, err = Parse(Sentencef("GLGSV", "3", "3", "09", "88", "12", "300", ""))
+	assert.NoError(t, err)
+	assert.Equal(t, []GSVInfo{{PRN: 88, Elevation: 12, Azimuth: 300}}, s.(*GSV).Info)
+}
+
+func Test_ParseVTG(t *testing.T) {
+	s, err := Parse(samples[4])
+	assert.NoError(t, err)
+	vtg := s.(*VTG)
+	assert.Equal(t, 77.52, vtg.TrueCourse)
+	assert.Equal(t, 0.004, vtg.SpeedKnots)
+	assert.Equal(t, 0.008, vtg.SpeedKmh)
+	assert.Equal(t, "A", vtg.FAAMode)
+}
+
+func Test_ParseGLL(t *testing.T) {
+	s, err := Parse(samples[5])
+	assert.NoError(t, err)
+	gll := s.(*GLL)
+	assert.True(t, gll.Valid())
+	assert.InDelta(t, 39.898001, gll.Lat, 1e-6)
+	assert.InDelta(t, -105.112553, gll.Lon, 1e-6)
+	assert.Equal(t, Time{Valid: true, Hour: 3, Minute: 41, Second: 38}, gll.Time)
+}
+
+func Test_ParseZDA(t *testing.T) {
+	s, err := Parse(samples[6])
+	assert.NoError(t, err)
+	zda := s.(*ZDA)
+	assert.Equal(t, time.Date(1996, 7, 12, 17, 28, 9, 456*int(time.Millisecond), time.UTC), DateTime(zda.Date, zda.Time))
+}
+
+func Test_ParseSampleTrack(t *testing.T) {
+	points := loadCSV(t)
+	assert.NotEmpty(t, points)
+	for _, p := range points {
+		s, err := Parse(pointToRMC(p))
+		assert.NoError(t, err)
+		rmc := s.(*RMC)
+		assert.InDelta(t, p.lat, rmc.Lat, 1e-6)
+		assert.InDelta(t, p.lon, rmc.Lon, 1e-6)
+		assert.Equal(t, p.t, DateTime(rmc.Date, rmc.Time))
+
+		s, err = Parse(pointToGGA(p))
+		assert.NoError(t, err)
+		gga := s.(*GGA)
+		assert.InDelta(t, p.lat, gga.Lat, 1e-6)
+		assert.InDelta(t, p.lon, gga.Lon, 1e-6)
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, s := range samples {
+		f.Add(s)
+	}
+	for _, p := range loadCSV(f) {
+		f.Add(pointToRMC(p))
+		f.Add(pointToGGA(p))
+	}
+	f.Fuzz(func(t *testing.T, raw string) {
+		s, err := Parse(raw)
+		if err != nil {
+			return
+		}
+		var lat, lon float64
+		switch v := s.(type) {
+		case *RMC:
+			lat, lon = v.Lat, v.Lon
+		case *GGA:
+			lat, lon = v.Lat, v.Lon
+		case *GLL:
+			lat, lon = v.Lat, v.Lon
+		}
+		if math.IsNaN(lat) || math.Abs(lat) > 90 || math.IsNaN(lon) || math.Abs(lon) > 180 {
+			t.Errorf("invalid coordinate (%v, %v) from %q", lat, lon, raw)
+		}
+	})
+}
@@ -0,0 +1,293 @@
+package nmea
+
+// Fix qualities in GGA
+const (
+	FixInvalid   = 0
+	FixGPS       = 1
+	FixDGPS      = 2
+	FixPPS       = 3
+	FixRTK       = 4
+	FixFloatRTK  = 5
+	FixEstimated = 6
+	FixManual    = 7
+	FixSimulated = 8
+)
+
+// Fix types in GSA
+const (
+	FixTypeNone = 1
+	FixType2D   = 2
+	FixType3D   = 3
+)
+
+// Status in RMC and GLL
+const (
+	StatusValid   = "A"
+	StatusInvalid = "V"
+)
+
+const knotToKmh = 1.852
+
+// RMC is the recommended minimum specific GNSS data, e.g.
+// $GNRMC,083245.00,A,3957.48804,N,11626.17404,E,0.012,,041119,,,A*65
+type RMC struct {
+	BaseSentence
+	Time    Time
+	Status  string  // A: valid, V: invalid
+	Lat     float64 // degrees, negative for south
+	Lon     float64 // degrees, negative for west
+	Speed   float64 // knots
+	Course  float64 // degrees from true north
+	Date    Date
+	MagVar  float64 // degrees, negative for west
+	FAAMode string  // A: autonomous, D: differential, E: estimated, N: not valid
+}
+
+// Valid returns true if the RMC has a valid fix
+func (s *RMC) Valid() bool {
+	return s.Status == StatusValid && s.FAAMode != "N"
+}
+
+// SpeedKmh returns the speed in km/h
+func (s *RMC) SpeedKmh() float64 {
+	return s.Speed * knotToKmh
+}
+
+func parseRMC(b BaseSentence) (Sentence, error) {
+	p := &fieldParser{b: b}
+	p.requireFields(9)
+	s := &RMC{
+		BaseSentence: b,
+		Time:         p.time(0),
+		Status:       p.enum(1, StatusValid+StatusInvalid),
+		Lat:          p.lat(2),
+		Lon:          p.lon(4),
+		Speed:        p.float(6),
+		Course:       p.float(7),
+		Date:         p.date(8),
+		MagVar:       p.float(9),
+		FAAMode:      p.string(11),
+	}
+	if p.enum(10, "EW") == "W" {
+		s.MagVar = -s.MagVar
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return s, nil
+}
+
+// GGA is the global positioning system fix data, e.g.
+// $GPGGA,092750.000,5321.6802,N,00630.3372,W,1,8,1.03,61.7,M,55.2,M,,*76
+type GGA struct {
+	BaseSentence
+	Time          Time
+	Lat           float64
+	Lon           float64
+	FixQuality    int
+	NumSatellites int
+	HDOP          float64
+	Altitude      float64 // meters above mean sea level
+	Separation    float64 // geoidal separation in meters
+	DGPSAge       float64
+	DGPSStationID string
+}
+
+// Valid returns true if the GGA has a fix
+func (s *GGA) Valid() bool {
+	return s.FixQuality != FixInvalid
+}
+
+func parseGGA(b BaseSentence) (Sentence, error) {
+	p := &fieldParser{b: b}
+	p.requireFields(9)
+	s := &GGA{
+		BaseSentence:  b,
+		Time:          p.time(0),
+		Lat:           p.lat(1),
+		Lon:           p.lon(3),
+		FixQuality:    p.int(5),
+		NumSatellites: p.int(6),
+		HDOP:          p.float(7),
+		Altitude:      p.float(8),
+		Separation:    p.float(10),
+		DGPSAge:       p.float(12),
+		DGPSStationID: p.string(13),
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return s, nil
+}
+
+// GSA is the GNSS DOP and active satellites, e.g.
+// $GPGSA,A,3,10,07,05,02,29,04,08,13,,,,,1.72,1.03,1.38*0A
+type GSA struct {
+	BaseSentence
+	Mode     string // A: automatic, M: manual
+	FixType  int    // 1: no fix, 2: 2D, 3: 3D
+	SVs      []int  // PRNs of satellites used for the fix
+	PDOP     float64
+	HDOP     float64
+	VDOP     float64
+	SystemID int // NMEA 4.1+ only
+}
+
+func parseGSA(b BaseSentence) (Sentence, error) {
+	p := &fieldParser{b: b}
+	p.requireFields(17)
+	s := &GSA{
+		BaseSentence: b,
+		Mode:         p.enum(0, "AM"),
+		FixType:      p.int(1),
+	}
+	for i := 2; i < 14; i++ {
+		if p.field(i) != "" {
+			s.SVs = append(s.SVs, p.int(i))
+		}
+	}
+	s.PDOP = p.float(14)
+	s.HDOP = p.float(15)
+	s.VDOP = p.float(16)
+	s.SystemID = p.int(17)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return s, nil
+}
+
+// GSVInfo is the info of a satellite in view
+type GSVInfo struct {
+	PRN       int
+	Elevation int // degrees
+	Azimuth   int // degrees
+	SNR       int // dB, 0 if not tracking
+}
+
+// GSV is the GNSS satellites in view, e.g.
+// $GPGSV,3,1,11,10,63,137,17,07,61,098,15,05,59,290,20,08,54,157,30*70
+type GSV struct {
+	BaseSentence
+	TotalMessages int
+	MessageNumber int
+	NumInView     int
+	Info          []GSVInfo
+}
+
+func parseGSV(b BaseSentence) (Sentence, error) {
+	p := &fieldParser{b: b}
+	p.requireFields(3)
+	s := &GSV{
+		BaseSentence:  b,
+		TotalMessages: p.int(0),
+		MessageNumber: p.int(1),
+		NumInView:     p.int(2),
+	}
+	// up to 4 satellites with 4 fields each, NMEA 4.1+ appends a signal id
+	for i := 3; i+3 < len(b.Fields); i += 4 {
+		s.Info = append(s.Info, GSVInfo{
+			PRN:       p.int(i),
+			Elevation: p.int(i + 1),
+			Azimuth:   p.int(i + 2),
+			SNR:       p.int(i + 3),
+		})
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return s, nil
+}
+
+// VTG is the course over ground and ground speed, e.g.
+// $GPVTG,77.52,T,,M,0.004,N,0.008,K,A*06
+type VTG struct {
+	BaseSentence
+	TrueCourse     float64 // degrees
+	MagneticCourse float64 // degrees
+	SpeedKnots     float64
+	SpeedKmh       float64
+	FAAMode        string
+}
+
+func parseVTG(b BaseSentence) (Sentence, error) {
+	p := &fieldParser{b: b}
+	p.requireFields(8)
+	s := &VTG{
+		BaseSentence:   b,
+		TrueCourse:     p.float(0),
+		MagneticCourse: p.float(2),
+		SpeedKnots:     p.float(4),
+		SpeedKmh:       p.float(6),
+		FAAMode:        p.string(8),
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return s, nil
+}
+
+// GLL is the geographic position, e.g.
+// $GPGLL,3953.88008971,N,10506.75318910,W,034138.00,A,D*7A
+type GLL struct {
+	BaseSentence
+	Lat     float64
+	Lon     float64
+	Time    Time
+	Status  string
+	FAAMode string
+}
+
+// Valid returns true if the GLL has a valid fix
+func (s *GLL) Valid() bool {
+	return s.Status == StatusValid && s.FAAMode != "N"
+}
+
+func parseGLL(b BaseSentence) (Sentence, error) {
+	p := &fieldParser{b: b}
+	p.requireFields(6)
+	s := &GLL{
+		BaseSentence: b,
+		Lat:          p.lat(0),
+		Lon:          p.lon(2),
+		Time:         p.time(4),
+		Status:       p.enum(5, StatusValid+StatusInvalid),
+		FAAMode:      p.string(6),
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return s, nil
+}
+
+// ZDA is the time and date, e.g.
+// $GPZDA,172809.456,12,07,1996,00,00*57
+type ZDA struct {
+	BaseSentence
+	Time          Time
+	Date          Date
+	OffsetHours   int // local zone offset
+	OffsetMinutes int
+}
+
+func parseZDA(b BaseSentence) (Sentence, error) {
+	p := &fieldParser{b: b}
+	p.requireFields(6)
+	s := &ZDA{
+		BaseSentence:  b,
+		Time:          p.time(0),
+		OffsetHours:   p.int(4),
+		OffsetMinutes: p.int(5),
+	}
+	day, month, year := p.int(1), p.int(2), p.int(3)
+	if p.field(1) != "" && p.field(2) != "" && p.field(3) != "" {
+		if day < 1 || day > 31 || month < 1 || month > 12 || year < 1 {
+			p.fail(1, "date")
+		} else {
+			s.Date = Date{Valid: true, Day: day, Month: month, Year: year}
+		}
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return s, nil
+}